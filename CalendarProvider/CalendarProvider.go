@@ -0,0 +1,86 @@
+package CalendarProvider
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"strings"
+	"time"
+)
+
+type CalendarProvider interface {
+	Initialise(logger logger.Logger, conf config.CalendarConf, programDirectory string) error
+	IsWorkingTime(team string, t time.Time) bool
+	NextWorkingTime(team string, t time.Time) time.Time
+	OnDutyChats(team string) []int64
+}
+
+var weekdayList = map[string]time.Weekday{
+	"Sun": time.Sunday,
+	"Mon": time.Monday,
+	"Tue": time.Tuesday,
+	"Wed": time.Wednesday,
+	"Thu": time.Thursday,
+	"Fri": time.Friday,
+	"Sat": time.Saturday,
+}
+
+// Parse short weekday name, e.g. "Mon".
+func ParseWeekday(value string) (time.Weekday, error) {
+	weekday, ok := weekdayList[value]
+	if !ok {
+		return 0, fmt.Errorf("%w '%v'", myErrors.ErrInvalidWorkingDay, value)
+	}
+	return weekday, nil
+}
+
+// Parse "15:04" into offset since midnight. "24:00" allowed as end of day.
+func ParseClock(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// Parse daily range "23:00-07:00" into offsets since midnight. Range with end before start cross midnight.
+func ParseDailyRange(value string) (time.Duration, time.Duration, error) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("%w '%v'", myErrors.ErrInvalidTimeRange, value)
+	}
+	start, err := ParseClock(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w '%v'", myErrors.ErrInvalidTimeRange, value)
+	}
+	end, err := ParseClock(parts[1])
+	if err != nil || start == end {
+		return 0, 0, fmt.Errorf("%w '%v'", myErrors.ErrInvalidTimeRange, value)
+	}
+	return start, end, nil
+}
+
+// Return end of daily range containing t in t location. Return false if t outside range.
+func DailyRangeEnd(t time.Time, start, end time.Duration) (time.Time, bool) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	switch {
+	case start < end && clock >= start && clock < end:
+		return AddClock(day, end), true
+	case start > end && clock >= start:
+		return AddClock(day.AddDate(0, 0, 1), end), true
+	case start > end && clock < end:
+		return AddClock(day, end), true
+	}
+	return time.Time{}, false
+}
+
+// Add wall clock offset to midnight. Correct on daylight saving time changes.
+func AddClock(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, int(clock/time.Minute), 0, 0, day.Location())
+}
@@ -0,0 +1,179 @@
+package basicCalendar
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/CalendarProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"path/filepath"
+	"time"
+)
+
+const (
+	ModuleName   string = "Calendar Provider"
+	DateLayout   string = "2006-01-02"
+	searchPeriod int    = 366 // Days to search for next working time.
+)
+
+// Implement CalendarProvider interface.
+type BasicCalendar struct {
+	Location *time.Location
+	Default  *TeamCalendar
+	Teams    map[string]*TeamCalendar
+	Log      logger.Logger
+}
+
+// Working hours and holidays for one team.
+type TeamCalendar struct {
+	Start          time.Duration // Working hours start since midnight.
+	End            time.Duration // Working hours end since midnight.
+	WorkingDays    map[time.Weekday]bool
+	Holidays       map[string]bool // Dates in DateLayout.
+	YearlyHolidays map[string]bool // Dates in "01-02" format repeated every year.
+	OnDuty         []int64
+}
+
+func (bc *BasicCalendar) Initialise(logger logger.Logger, conf config.CalendarConf, programDirectory string) error {
+	bc.Log = logger.SetModuleName(ModuleName)
+
+	bc.Location = time.UTC
+	if conf.Timezone != "" {
+		location, err := time.LoadLocation(conf.Timezone)
+		if err != nil {
+			return err
+		}
+		bc.Location = location
+	}
+
+	var err error
+	bc.Default, err = newTeamCalendar(conf.Default, programDirectory, bc.Log)
+	if err != nil {
+		bc.Log.Error(fmt.Sprintf("Invalid default calendar - '%v'", err))
+		return err
+	}
+	bc.Teams = make(map[string]*TeamCalendar, len(conf.Teams))
+	for team, teamConf := range conf.Teams {
+		bc.Teams[team], err = newTeamCalendar(teamConf, programDirectory, bc.Log)
+		if err != nil {
+			bc.Log.Error(fmt.Sprintf("Invalid calendar for team '%v' - '%v'", team, err))
+			return err
+		}
+	}
+
+	bc.Log.Debug(fmt.Sprintf("Initialisation complete. Timezone '%v', team calendars %v", bc.Location, len(bc.Teams)))
+	return nil
+}
+
+// Check that time is within working hours of working day and not a holiday.
+func (bc *BasicCalendar) IsWorkingTime(team string, t time.Time) bool {
+	return bc.NextWorkingTime(team, t).Equal(t)
+}
+
+// Return provided time if it is working time, otherwise start of next working hours.
+// If no working time found in a year return provided time.
+func (bc *BasicCalendar) NextWorkingTime(team string, t time.Time) time.Time {
+	calendar := bc.calendar(team)
+	local := t.In(bc.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, bc.Location)
+
+	for i := 0; i < searchPeriod; i++ {
+		if calendar.isWorkingDay(day) {
+			start := CalendarProvider.AddClock(day, calendar.Start)
+			end := CalendarProvider.AddClock(day, calendar.End)
+			switch {
+			case t.Before(start):
+				return start
+			case t.Before(end):
+				return t
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	bc.Log.Warning(fmt.Sprintf("No working time for team '%v' in %v days after '%v'", team, searchPeriod, t))
+	return t
+}
+
+// Telegram chats for notifications outside working hours.
+func (bc *BasicCalendar) OnDutyChats(team string) []int64 {
+	return bc.calendar(team).OnDuty
+}
+
+// Return team calendar or default one.
+func (bc *BasicCalendar) calendar(team string) *TeamCalendar {
+	if calendar, ok := bc.Teams[team]; ok {
+		return calendar
+	}
+	return bc.Default
+}
+
+func (tc *TeamCalendar) isWorkingDay(day time.Time) bool {
+	if len(tc.WorkingDays) > 0 && !tc.WorkingDays[day.Weekday()] {
+		return false
+	}
+	return !tc.Holidays[day.Format(DateLayout)] && !tc.YearlyHolidays[day.Format("01-02")]
+}
+
+// Parse team calendar options and read holiday files.
+func newTeamCalendar(conf config.TeamCalendarConf, programDirectory string, logger logger.Logger) (*TeamCalendar, error) {
+	calendar := &TeamCalendar{
+		End:            24 * time.Hour,
+		WorkingDays:    make(map[time.Weekday]bool),
+		Holidays:       make(map[string]bool),
+		YearlyHolidays: make(map[string]bool),
+		OnDuty:         conf.OnDutyChats,
+	}
+
+	if conf.WorkingHours != "" {
+		start, end, err := parseWorkingHours(conf.WorkingHours)
+		if err != nil {
+			return nil, err
+		}
+		calendar.Start, calendar.End = start, end
+	}
+
+	for _, day := range conf.WorkingDays {
+		weekday, err := CalendarProvider.ParseWeekday(day)
+		if err != nil {
+			return nil, err
+		}
+		calendar.WorkingDays[weekday] = true
+	}
+
+	for _, holiday := range conf.Holidays {
+		_, err := time.Parse(DateLayout, holiday)
+		if err != nil {
+			return nil, fmt.Errorf("%w '%v'", myErrors.ErrInvalidHoliday, holiday)
+		}
+		calendar.Holidays[holiday] = true
+	}
+
+	for _, file := range conf.HolidayFiles {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(programDirectory, file)
+		}
+		dates, yearly, err := readICalendarHolidays(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, date := range dates {
+			calendar.Holidays[date] = true
+		}
+		for _, date := range yearly {
+			calendar.YearlyHolidays[date] = true
+		}
+		logger.Debug(fmt.Sprintf("Imported %v holidays and %v yearly holidays from '%v'", len(dates), len(yearly), file))
+	}
+
+	return calendar, nil
+}
+
+// Parse "09:00-18:00" into offsets since midnight. End must be after start.
+func parseWorkingHours(value string) (time.Duration, time.Duration, error) {
+	start, end, err := CalendarProvider.ParseDailyRange(value)
+	if err != nil || end <= start {
+		return 0, 0, fmt.Errorf("%w '%v'", myErrors.ErrInvalidWorkingHours, value)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,115 @@
+package basicCalendar
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"os"
+	"strings"
+	"time"
+)
+
+// Read holidays from iCalendar file. Every day covered by VEVENT is a holiday.
+// Return dates in DateLayout and dates repeated every year (RRULE:FREQ=YEARLY) in "01-02" format.
+// Other recurrence rules not supported, such events used as single ones.
+func readICalendarHolidays(path string) ([]string, []string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	lines, err := unfoldLines(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dates := make([]string, 0, 32)
+	yearly := make([]string, 0, 32)
+	var start, end time.Time
+	var isYearly, inEvent bool
+	for _, line := range lines {
+		name, value := splitProperty(line)
+		switch {
+		case line == "BEGIN:VEVENT":
+			inEvent = true
+			start, end, isYearly = time.Time{}, time.Time{}, false
+		case line == "END:VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				return nil, nil, fmt.Errorf("%w - event without DTSTART in '%v'", myErrors.ErrInvalidHoliday, path)
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				if isYearly {
+					yearly = append(yearly, day.Format("01-02"))
+				} else {
+					dates = append(dates, day.Format(DateLayout))
+				}
+			}
+		case !inEvent:
+		case name == "DTSTART":
+			start, err = parseICalendarDate(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w '%v' in '%v'", myErrors.ErrInvalidHoliday, value, path)
+			}
+		case name == "DTEND":
+			end, err = parseICalendarEnd(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w '%v' in '%v'", myErrors.ErrInvalidHoliday, value, path)
+			}
+		case name == "RRULE":
+			isYearly = strings.Contains(value, "FREQ=YEARLY")
+		}
+	}
+
+	return dates, yearly, nil
+}
+
+// Join folded lines. Continuation lines start with space or tab.
+func unfoldLines(file *os.File) ([]string, error) {
+	lines := make([]string, 0, 256)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// Split "DTSTART;VALUE=DATE:20260101" into property name without parameters and value.
+func splitProperty(line string) (string, string) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) < 2 {
+		return line, ""
+	}
+	name := strings.SplitN(parts[0], ";", 2)[0]
+	return strings.ToUpper(name), parts[1]
+}
+
+// Parse date or date-time value. Only date part used.
+func parseICalendarDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, myErrors.ErrInvalidHoliday
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+// Parse DTEND value. Date value and midnight exclude end date, later end time includes it.
+func parseICalendarEnd(value string) (time.Time, error) {
+	end, err := parseICalendarDate(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	clock := strings.TrimSuffix(value[8:], "Z")
+	if strings.HasPrefix(clock, "T") && strings.Trim(clock[1:], "0") != "" {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, nil
+}
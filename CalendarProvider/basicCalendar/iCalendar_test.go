@@ -0,0 +1,71 @@
+package basicCalendar
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+)
+
+func TestReadICalendarHolidays(t *testing.T) {
+	dates, yearly, err := readICalendarHolidays(filepath.Join("testdata", "holidays.ics"))
+	if err != nil {
+		t.Fatalf("read holidays - %v", err)
+	}
+
+	wantDates := []string{
+		// VALUE=DATE range, DTEND exclusive.
+		"2026-01-01", "2026-01-02", "2026-01-03", "2026-01-04", "2026-01-05", "2026-01-06", "2026-01-07", "2026-01-08",
+		// Single day without DTEND.
+		"2026-05-09",
+		// Folded DTSTART and DTEND.
+		"2026-06-12",
+		// Timed DTEND includes its date.
+		"2026-11-03", "2026-11-04",
+		// DTEND at midnight excludes its date.
+		"2026-12-30",
+	}
+	if strings.Join(dates, " ") != strings.Join(wantDates, " ") {
+		t.Errorf("dates %v, want %v", dates, wantDates)
+	}
+	if strings.Join(yearly, " ") != "03-08" {
+		t.Errorf("yearly dates %v, want [03-08]", yearly)
+	}
+}
+
+func TestReadICalendarHolidaysInvalid(t *testing.T) {
+	for _, file := range []string{"no-dtstart.ics", "invalid-date.ics"} {
+		t.Run(file, func(t *testing.T) {
+			_, _, err := readICalendarHolidays(filepath.Join("testdata", file))
+			if !errors.Is(err, myErrors.ErrInvalidHoliday) {
+				t.Errorf("error '%v', want '%v'", err, myErrors.ErrInvalidHoliday)
+			}
+		})
+	}
+}
+
+func TestParseICalendarEnd(t *testing.T) {
+	cases := []struct {
+		value string
+		want  string
+	}{
+		{"20260102", "2026-01-02"},
+		{"20260102T000000", "2026-01-02"},
+		{"20260102T000000Z", "2026-01-02"},
+		{"20260102T000001", "2026-01-03"},
+		{"20260102T180000Z", "2026-01-03"},
+	}
+	for _, c := range cases {
+		t.Run(c.value, func(t *testing.T) {
+			end, err := parseICalendarEnd(c.value)
+			if err != nil {
+				t.Fatalf("parse - %v", err)
+			}
+			if end.Format(DateLayout) != c.want {
+				t.Errorf("end '%v', want '%v'", end.Format(DateLayout), c.want)
+			}
+		})
+	}
+}
@@ -2,21 +2,63 @@ package DBProvider
 
 import "github.com/Sarraksh/otrs-echo-bot/common/logger"
 
+// User notification modes for reminders.
+const (
+	NotificationModeIndividual string = "individual" // Individual reminder for every ticket. Used if not chosen.
+	NotificationModeDigest     string = "digest"     // Periodic team digest only.
+	NotificationModeBoth       string = "both"       // Individual reminders and team digest.
+)
+
+// Kinds of data removed after retention period.
+const (
+	RetentionEvents     string = "events"     // Ended events with history.
+	RetentionMessages   string = "messages"   // Delivered messages.
+	RetentionDeliveries string = "deliveries" // Idempotency keys of ticket notifications.
+)
+
+// Kinds of event history entries.
+const (
+	HistoryStatus       string = "status"       // Event status changed. Details contain new status, actor is event channel on creation.
+	HistoryChange       string = "change"       // Ticket field changed in OTRS. Details contain field with old and new value.
+	HistoryNotification string = "notification" // Notification sent or deferred. Actor is chat ID or webhook endpoint.
+	HistoryAction       string = "action"       // Ticket taken or closed in OTRS. Actor is ticket owner.
+)
+
 type DBProvider interface {
 	Initialise(logger logger.Logger, directory string) error
 
-	OTRSEventCreateNew(Channel, Type string, TicketID int64) error
-	OTRSEventGetActive() (int64, string, error)
+	OTRSEventCreateNew(Channel, Type, Instance string, TicketID int64) error
+	OTRSEventCreateOnce(Channel, Type, Instance string, TicketID int64, IdempotencyKey string) (int64, bool, error)
+	OTRSEventClaim(owner string, leaseUntil, dueBefore int64) (int64, string, string, error)
+	OTRSEventExtendLease(id int64, owner string, leaseUntil int64) error
+	OTRSEventRelease(id int64, owner string) error
 	OTRSEventGetEarliestActivationTimestamp() (int64, error)
 	OTRSEventProcessing(id int64) error
-	OTRSEventSuspend(id int64) error
+	OTRSEventSuspend(id int64, nextActivation int64) error
+	OTRSEventPostpone(id int64, nextActivation int64) error
 	OTRSEventEnded(id int64) error
-	OTRSEventIsExistsWithTicketIDAndType(ticketID int64, eventType string) (bool, error)
+	OTRSEventIsExistsWithTicketIDAndType(instance string, ticketID int64, eventType string) (bool, error)
 	OTRSEventGetStatus(DBID int64) (string, error)
+	OTRSEventGetAllActive() ([]OTRSEvent, error)
+	OTRSEventGetByTicket(instance, ticket string) ([]OTRSEvent, error)
+	OTRSEventSetTicketNumber(instance string, ticketID int64, ticketNumber string) error
+	OTRSEventSetDefaultInstance(instance string) error
+	OTRSEventGetByID(id int64) (OTRSEvent, error)
+	OTRSEventSwapSnapshot(eventID int64, snapshot TicketSnapshot) (TicketSnapshot, bool, error)
 
-	BotUserAdd(tgID int64) error
+	EventHistoryAdd(eventID int64, kind, actor, details string) error
+	EventHistoryGetByEvent(eventID int64) ([]EventHistory, error)
+	EventHistoryGetReactions(eventType string, from, to int64) ([]EventReaction, error)
+
+	BotUserAdd(tgID int64, chatType, language string) error
 	BotUserUpdateFirstName(tgID int64, firstName string) error
 	BotUserUpdateLastName(tgID int64, lastName string) error
+	BotUserUpdateLanguage(tgID int64, language string) error
+	BotUserUpdateTimezone(tgID int64, timezone string) error
+	BotUserUpdateQuietHours(tgID int64, quietHours string) error
+	BotUserUpdateDNDUntil(tgID int64, until int64) error
+	BotUserUpdateNotificationMode(tgID int64, mode string) error
+	BotUserGetSettings(tgID int64) (BotUserSettings, error)
 	BotUserGetByTelegramID(tgID int64) (int64, error)
 	BotUserGetTelegramIDByID(ID int64) (int64, error)
 
@@ -30,9 +72,142 @@ type DBProvider interface {
 	ClientTeamBoundClientUpdate(client, team string) error
 	ClientTeamBoundGetTeamByClient(client string) (string, error)
 
-	MessageListNewMessage(sm string, chatID int64, text string) (int64, error)
+	MessageListNewMessage(sm string, chatID int64, text, instance string) (int64, error)
+	MessageListNewDeferredMessage(sm string, chatID int64, text, instance string, deliverAfter int64) (int64, error)
+	MessageListNewWebhookMessage(endpoint, body, instance string) (int64, error)
 	MessageListMarkDelivered(ID int64) error
 	MessageListGetAllUndeliveredBySM(sm string) ([]int64, error)
 	MessageListGetMessageText(ID int64) (string, error)
 	MessageListGetMessageChatID(ID int64) (string, error)
+	MessageListClaimDeferred(sm, owner string, leaseUntil, before int64) ([]DeferredMessage, error)
+
+	MessageTemplateGetAll() ([]MessageTemplate, error)
+
+	PollerStateGetWatermark(instance string) (int64, error)
+	PollerStateSetWatermark(instance string, watermark int64) error
+
+	ScheduledJobClaim(job, owner string, run, lastRunNotAfter int64) (bool, error)
+
+	RetentionPurge(kind string, before int64, batchSize int, archive Archiver) (int64, error)
+	Optimise() (int64, int64, error)
+
+	Backup(destination string) error
+	Restore(source string) error
+	Export() (Dump, error)
+	Import(dump Dump) error
+}
+
+// Receive rows before removal by retention. Row values by column name.
+type Archiver interface {
+	Archive(table string, rows []map[string]interface{}) error
+}
+
+// Version of portable dump format.
+const DumpVersion int = 1
+
+// Portable copy of users, subscriptions to teams and client bindings to teams, independent of DB backend.
+// Users identified by Telegram ID, so internal IDs may differ after import.
+type Dump struct {
+	Version       int                `json:"version"`
+	Created       int64              `json:"created"` // Unix timestamp of export.
+	Users         []DumpUser         `json:"users"`
+	Subscriptions []DumpSubscription `json:"subscriptions"`
+	Clients       []DumpClient       `json:"clients"`
+}
+
+// Bot user in portable dump.
+type DumpUser struct {
+	TelegramID       int64  `json:"telegramID"`
+	Token            string `json:"token,omitempty"`
+	Active           bool   `json:"active"`
+	FirstName        string `json:"firstName,omitempty"`
+	LastName         string `json:"lastName,omitempty"`
+	Phone            int64  `json:"phone,omitempty"`
+	Email            string `json:"email,omitempty"`
+	Created          int64  `json:"created"`
+	ChatType         string `json:"chatType,omitempty"`
+	Language         string `json:"language,omitempty"`
+	Timezone         string `json:"timezone,omitempty"`
+	QuietHours       string `json:"quietHours,omitempty"`
+	DNDUntil         int64  `json:"dndUntil,omitempty"`
+	NotificationMode string `json:"notificationMode,omitempty"`
+}
+
+// User subscription to team in portable dump.
+type DumpSubscription struct {
+	TelegramID   int64  `json:"telegramID"`
+	Subscription string `json:"subscription"`
+	Active       bool   `json:"active"`
+	Created      int64  `json:"created"`
+	Finished     int64  `json:"finished,omitempty"` // Zero for active subscription.
+}
+
+// Client bound to team in portable dump.
+type DumpClient struct {
+	Client string `json:"client"`
+	Team   string `json:"team"`
+}
+
+// Personal settings of bot user. Empty if not chosen.
+type BotUserSettings struct {
+	Language   string // ISO 639-1 language code.
+	Timezone   string // IANA timezone name for absolute time in messages and quiet hours.
+	QuietHours string // Daily range without non-critical notifications, e.g. "23:00-07:00".
+	DNDUntil   int64  // Unix timestamp till which non-critical notifications not delivered.
+
+	NotificationMode string // Individual reminders, digest or both. Individual if empty.
+}
+
+// OTRS event stored in DB.
+type OTRSEvent struct {
+	ID           int64
+	Status       string
+	Type         string
+	TicketID     int64
+	TicketNumber string // Empty until event processed.
+	Instance     string // Name of OTRS instance. Empty for single unnamed instance.
+	Created      int64  // Unix timestamp of event creation.
+	Finished     int64  // Unix timestamp of event end. Zero for active event.
+}
+
+// Append-only history entry of OTRS event.
+type EventHistory struct {
+	ID      int64
+	EventID int64
+	Created int64  // Unix timestamp of entry.
+	Kind    string // Status, change, notification or action.
+	Actor   string // Who caused entry. Empty for bot.
+	Details string
+}
+
+// Ticket fields tracked in event history.
+type TicketSnapshot struct {
+	Lock       string
+	State      string
+	Owner      string
+	Priority   string
+	CustomerID string // Used for reports, changes not tracked.
+}
+
+// Event with reaction times for response reports.
+type EventReaction struct {
+	OTRSEvent
+	CustomerID   string // From last ticket snapshot. Empty if event never processed.
+	Priority     string // From last ticket snapshot. Empty if event never processed.
+	Acknowledged int64  // Unix timestamp of first owner action, ticket taken or closed. Zero if not observed.
+	Closed       int64  // Unix timestamp of ticket close or merge. Zero if not observed.
+}
+
+// Message waiting for delivery till end of quiet hours.
+type DeferredMessage struct {
+	ID     int64
+	ChatID int64
+	Text   string
+}
+
+// Message template stored in DB.
+type MessageTemplate struct {
+	Kind    string // Message kind, e.g. "new" or "reminder".
+	Channel string // Template channel, e.g. "telegram" or "plain".
+	Body    string // Go text/template source.
 }
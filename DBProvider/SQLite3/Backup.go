@@ -0,0 +1,384 @@
+package SQLite3
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"github.com/mattn/go-sqlite3"
+	"os"
+	"time"
+)
+
+const (
+	backupStepPages int           = 256                   // Pages copied by one backup step.
+	backupStepPause time.Duration = 10 * time.Millisecond // Pause between steps so writers not blocked.
+)
+
+// Copy database into destination file using SQLite online backup API. Bot may keep working during backup.
+// Backup written into temporary file and renamed after integrity check, so destination never incomplete.
+func (db *DB) Backup(destination string) error {
+	db.Log.Debug(fmt.Sprintf("Backup database into '%v'", destination))
+
+	temporary := destination + ".tmp"
+	err := os.Remove(temporary)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	target, err := sql.Open("sqlite3", temporary)
+	if err != nil {
+		return err
+	}
+	err = onlineCopy(target, db.Reader)
+	if err == nil {
+		err = checkIntegrity(target)
+	}
+	closeErr := target.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temporary)
+		return err
+	}
+	err = os.Rename(temporary, destination)
+	if err != nil {
+		os.Remove(temporary)
+		return err
+	}
+
+	db.Log.Info(fmt.Sprintf("Database backed up into '%v'", destination))
+	return nil
+}
+
+// Replace database content with backup from source file and bring schema to current version.
+// Database locked exclusively during restore, so restore fails if database used by running bot.
+func (db *DB) Restore(source string) error {
+	db.Log.Info(fmt.Sprintf("Restore database from '%v'", source))
+
+	_, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	backup, err := sql.Open("sqlite3", fmt.Sprintf("%v?_query_only=true", source))
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+	err = checkIntegrity(backup)
+	if err != nil {
+		return fmt.Errorf("%w '%v'", err, source)
+	}
+
+	// Release own connections so only other processes can hold database.
+	db.close()
+	err = restoreExclusive(db.FileFullPath, backup)
+	openErr := db.open()
+	if err != nil {
+		return err
+	}
+	if openErr != nil {
+		return openErr
+	}
+	db.Log.Info(fmt.Sprintf("Database restored from '%v'", source))
+	return nil
+}
+
+// Copy backup into database under exclusive lock. WAL with its index removed before copy,
+// so stale WAL frames never applied over restored pages.
+func restoreExclusive(fileFullPath string, backup *sql.DB) error {
+	target, err := sql.Open("sqlite3", fmt.Sprintf("%v?_locking_mode=EXCLUSIVE&_busy_timeout=0", fileFullPath))
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	target.SetMaxOpenConns(1)
+
+	// Every connection of running bot holds shared lock on database file.
+	err = target.Ping()
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrBusy {
+		return fmt.Errorf("%w '%v'", myErrors.ErrDatabaseInUse, fileFullPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Checkpoint and delete WAL while lock held. Files left by crashed process removed too.
+	_, err = target.Exec(`PRAGMA journal_mode=DELETE;`)
+	if err != nil {
+		return err
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		err = os.Remove(fileFullPath + suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return onlineCopy(target, backup)
+}
+
+// Copy main database of source into destination page by page.
+// Copy restarted by SQLite if source changed by other connection.
+func onlineCopy(destination, source *sql.DB) error {
+	ctx := context.Background()
+	destinationConn, err := destination.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destinationConn.Close()
+	sourceConn, err := source.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sourceConn.Close()
+
+	return destinationConn.Raw(func(destinationDriver interface{}) error {
+		return sourceConn.Raw(func(sourceDriver interface{}) error {
+			destinationSQLite, ok := destinationDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection '%T'", destinationDriver)
+			}
+			sourceSQLite, ok := sourceDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection '%T'", sourceDriver)
+			}
+
+			backup, err := destinationSQLite.Backup("main", sourceSQLite, "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := backup.Step(backupStepPages)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+				if done {
+					break
+				}
+				time.Sleep(backupStepPause)
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// Return error if database integrity check not passed.
+func checkIntegrity(database *sql.DB) error {
+	var result string
+	err := database.QueryRow(`PRAGMA integrity_check;`).Scan(&result)
+	if err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w - '%v'", myErrors.ErrBackupCorrupted, result)
+	}
+	return nil
+}
+
+// Export users with Telegram ID, their subscriptions and client bindings.
+func (db *DB) Export() (DBProvider.Dump, error) {
+	db.Log.Debug("Export users, subscriptions and clients")
+	dump := DBProvider.Dump{
+		Version: DBProvider.DumpVersion,
+		Created: time.Now().Unix(),
+	}
+
+	// Read all tables in one transaction for consistent dump.
+	transaction, err := db.Reader.Begin()
+	if err != nil {
+		return DBProvider.Dump{}, err
+	}
+	defer transaction.Rollback()
+
+	dump.Users, err = exportUsers(transaction)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't export users - '%v'", err))
+		return DBProvider.Dump{}, err
+	}
+	dump.Subscriptions, err = exportSubscriptions(transaction)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't export subscriptions - '%v'", err))
+		return DBProvider.Dump{}, err
+	}
+	dump.Clients, err = exportClients(transaction)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't export clients - '%v'", err))
+		return DBProvider.Dump{}, err
+	}
+
+	db.Log.Info(fmt.Sprintf("Exported '%v' users, '%v' subscriptions and '%v' clients",
+		len(dump.Users), len(dump.Subscriptions), len(dump.Clients)))
+	return dump, nil
+}
+
+func exportUsers(transaction *sql.Tx) ([]DBProvider.DumpUser, error) {
+	rows, err := transaction.Query(`
+SELECT TelegramID, Token, Active, COALESCE(FirstName, ''), COALESCE(LastName, ''), COALESCE(Phone, 0),
+	COALESCE(Email, ''), Created, COALESCE(ChatType, ''), COALESCE(Language, ''), COALESCE(Timezone, ''),
+	COALESCE(QuietHours, ''), COALESCE(DNDUntil, 0), COALESCE(NotificationMode, '')
+FROM BotUserList WHERE TelegramID IS NOT NULL ORDER BY ID;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userList := make([]DBProvider.DumpUser, 0, 64)
+	for rows.Next() {
+		var user DBProvider.DumpUser
+		err = rows.Scan(&user.TelegramID, &user.Token, &user.Active, &user.FirstName, &user.LastName, &user.Phone,
+			&user.Email, &user.Created, &user.ChatType, &user.Language, &user.Timezone,
+			&user.QuietHours, &user.DNDUntil, &user.NotificationMode)
+		if err != nil {
+			return nil, err
+		}
+		userList = append(userList, user)
+	}
+	return userList, rows.Err()
+}
+
+func exportSubscriptions(transaction *sql.Tx) ([]DBProvider.DumpSubscription, error) {
+	rows, err := transaction.Query(`
+SELECT BotUserList.TelegramID, SubscriptionList.Subscription, SubscriptionList.Active,
+	SubscriptionList.Created, COALESCE(SubscriptionList.Finished, 0)
+FROM SubscriptionList JOIN BotUserList ON BotUserList.ID = SubscriptionList.UserID
+WHERE BotUserList.TelegramID IS NOT NULL ORDER BY BotUserList.ID, SubscriptionList.Subscription;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptionList := make([]DBProvider.DumpSubscription, 0, 64)
+	for rows.Next() {
+		var subscription DBProvider.DumpSubscription
+		err = rows.Scan(&subscription.TelegramID, &subscription.Subscription, &subscription.Active,
+			&subscription.Created, &subscription.Finished)
+		if err != nil {
+			return nil, err
+		}
+		subscriptionList = append(subscriptionList, subscription)
+	}
+	return subscriptionList, rows.Err()
+}
+
+func exportClients(transaction *sql.Tx) ([]DBProvider.DumpClient, error) {
+	rows, err := transaction.Query(`SELECT Client, Team FROM ClientTeamBound ORDER BY Client;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clientList := make([]DBProvider.DumpClient, 0, 64)
+	for rows.Next() {
+		var client DBProvider.DumpClient
+		err = rows.Scan(&client.Client, &client.Team)
+		if err != nil {
+			return nil, err
+		}
+		clientList = append(clientList, client)
+	}
+	return clientList, rows.Err()
+}
+
+// Import dump in one transaction. Existing users matched by Telegram ID and updated,
+// subscriptions and client bindings from dump replace existing ones with same key.
+// Data absent in dump kept unchanged.
+func (db *DB) Import(dump DBProvider.Dump) error {
+	db.Log.Info(fmt.Sprintf("Import '%v' users, '%v' subscriptions and '%v' clients",
+		len(dump.Users), len(dump.Subscriptions), len(dump.Clients)))
+	if dump.Version != DBProvider.DumpVersion {
+		return fmt.Errorf("%w '%v'", myErrors.ErrUnsupportedDumpVersion, dump.Version)
+	}
+
+	// Create new sql transaction.
+	transaction, err := db.Instance.Begin()
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	// Internal user ID by Telegram ID for subscriptions.
+	userIDList := make(map[int64]int64, len(dump.Users))
+	for _, user := range dump.Users {
+		userIDList[user.TelegramID], err = importUser(transaction, user)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't import user '%v' - '%v'", user.TelegramID, err))
+			return err
+		}
+	}
+
+	for _, subscription := range dump.Subscriptions {
+		userID, ok := userIDList[subscription.TelegramID]
+		if !ok {
+			err = transaction.QueryRow(`SELECT ID FROM BotUserList WHERE TelegramID = ?;`, subscription.TelegramID).Scan(&userID)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("%w for subscription '%v' of '%v'", myErrors.ErrNoUsersFound, subscription.Subscription, subscription.TelegramID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		_, err = transaction.Exec(
+			`INSERT OR REPLACE INTO SubscriptionList(Active, Subscription, UserID, Created, Finished) VALUES(?, ?, ?, ?, NULLIF(?, 0));`,
+			subscription.Active, subscription.Subscription, userID, subscription.Created, subscription.Finished,
+		)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't import subscription '%v' of '%v' - '%v'", subscription.Subscription, subscription.TelegramID, err))
+			return err
+		}
+	}
+
+	for _, client := range dump.Clients {
+		_, err = transaction.Exec(`INSERT OR REPLACE INTO ClientTeamBound(Client, Team) VALUES(?, ?);`, client.Client, client.Team)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't import client '%v' - '%v'", client.Client, err))
+			return err
+		}
+	}
+
+	// Close transaction.
+	err = transaction.Commit()
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("While commit for import - '%v'", err))
+		return err
+	}
+
+	db.Log.Info("Import complete")
+	return nil
+}
+
+// Update user with Telegram ID or insert new one. Return internal user ID.
+func importUser(transaction *sql.Tx, user DBProvider.DumpUser) (int64, error) {
+	var userID int64
+	err := transaction.QueryRow(`SELECT ID FROM BotUserList WHERE TelegramID = ? ORDER BY ID LIMIT 1;`, user.TelegramID).Scan(&userID)
+	switch {
+	case err == sql.ErrNoRows:
+		result, err := transaction.Exec(`
+INSERT INTO BotUserList(Token, Active, FirstName, LastName, Phone, Email, Created, TelegramID, ChatType,
+	Language, Timezone, QuietHours, DNDUntil, NotificationMode)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''));`,
+			user.Token, user.Active, user.FirstName, user.LastName, user.Phone, user.Email, user.Created, user.TelegramID, user.ChatType,
+			user.Language, user.Timezone, user.QuietHours, user.DNDUntil, user.NotificationMode,
+		)
+		if err != nil {
+			return 0, err
+		}
+		return result.LastInsertId()
+	case err != nil:
+		return 0, err
+	}
+
+	_, err = transaction.Exec(`
+UPDATE BotUserList SET Token = ?, Active = ?, FirstName = ?, LastName = ?, Phone = ?, Email = ?, Created = ?, ChatType = ?,
+	Language = NULLIF(?, ''), Timezone = NULLIF(?, ''), QuietHours = NULLIF(?, ''), DNDUntil = NULLIF(?, 0), NotificationMode = NULLIF(?, '')
+WHERE ID = ?;`,
+		user.Token, user.Active, user.FirstName, user.LastName, user.Phone, user.Email, user.Created, user.ChatType,
+		user.Language, user.Timezone, user.QuietHours, user.DNDUntil, user.NotificationMode,
+		userID,
+	)
+	return userID, err
+}
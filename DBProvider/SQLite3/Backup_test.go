@@ -0,0 +1,58 @@
+package SQLite3
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+)
+
+func TestRestore(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+	insertEvent(t, db, "main", 1)
+	backupFile := filepath.Join(t.TempDir(), "backup.db")
+	err := db.Backup(backupFile)
+	if err != nil {
+		t.Fatalf("backup - %v", err)
+	}
+	insertEvent(t, db, "main", 2)
+
+	err = db.Restore(backupFile)
+	if err != nil {
+		t.Fatalf("restore - %v", err)
+	}
+
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventList;`); count != 1 {
+		t.Errorf("%v events after restore, want 1", count)
+	}
+	// Cached statements of closed connections not reused.
+	_, created, err := db.OTRSEventCreateOnce("newticket", "NewTicket", "main", 3, "")
+	if err != nil || !created {
+		t.Errorf("event not created after restore - %v", err)
+	}
+}
+
+func TestRestoreFailsWhileDatabaseUsed(t *testing.T) {
+	directory := t.TempDir()
+	running := openTestDB(t, directory)
+	insertEvent(t, running, "main", 1)
+	backupFile := filepath.Join(t.TempDir(), "backup.db")
+	err := running.Backup(backupFile)
+	if err != nil {
+		t.Fatalf("backup - %v", err)
+	}
+	insertEvent(t, running, "main", 2)
+
+	db := openTestDB(t, directory)
+	err = db.Restore(backupFile)
+	if !errors.Is(err, myErrors.ErrDatabaseInUse) {
+		t.Fatalf("restore with running instance returned '%v', want database in use", err)
+	}
+
+	for _, instance := range []*DB{db, running} {
+		if count := countRows(t, instance, `SELECT count(*) FROM OTRSEventList;`); count != 2 {
+			t.Errorf("%v events after failed restore, want 2", count)
+		}
+	}
+}
@@ -1,14 +1,17 @@
 package SQLite3
 
 import (
+	"database/sql"
 	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
 	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
 	"time"
 )
 
-//
-func (db *DB) BotUserAdd(tgID int64) error {
-	db.Log.Info(fmt.Sprintf("Write new user with telegram ID '%+v'", tgID))
+// Add new user. Telegram ID is chat ID so user can be private chat, group or channel.
+// Chat type is Telegram chat type - "private", "group", "supergroup" or "channel".
+func (db *DB) BotUserAdd(tgID int64, chatType, language string) error {
+	db.Log.Info(fmt.Sprintf("Write new user with telegram ID '%+v', chat type '%+v' and language '%+v'", tgID, chatType, language))
 
 	// Check if user already exists
 	_, err := db.BotUserGetByTelegramID(tgID)
@@ -28,22 +31,10 @@ func (db *DB) BotUserAdd(tgID int64) error {
 	Email := ""
 	Created := time.Now().Unix()
 
-	// Create new sql transaction.
-	sqlTransaction, err := db.Instance.Begin()
-	if err != nil {
-		return err
-	}
-
-	// Prepare and execute transaction for insert row.
-	sqlStatement, err := sqlTransaction.Prepare(
-		`insert into BotUserList(Token, Active, FirstName, LastName, Phone, Email, Created, TelegramID)
-values(?, ?, ?, ?, ?, ?, ?, ?)`,
-	)
-	if err != nil {
-		return err
-	}
-	defer sqlStatement.Close()
-	_, err = sqlStatement.Exec(
+	// Execute statement for insert row.
+	_, err = db.exec(
+		`insert into BotUserList(Token, Active, FirstName, LastName, Phone, Email, Created, TelegramID, ChatType, Language)
+values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		Token,
 		Active,
 		FirstName,
@@ -52,17 +43,13 @@ values(?, ?, ?, ?, ?, ?, ?, ?)`,
 		Email,
 		Created,
 		tgID,
+		chatType,
+		language,
 	)
 	if err != nil {
 		return err
 	}
 
-	// Close transaction.
-	err = sqlTransaction.Commit()
-	if err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -74,28 +61,8 @@ func (db *DB) BotUserUpdateFirstName(tgID int64, firstName string) error {
 		return err
 	}
 
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		return err
-	}
-	defer transaction.Rollback()
-
-	// Prepare and execute transaction for update row.
-	statement, err := transaction.Prepare(`UPDATE BotUserList SET FirstName = ? WHERE ID = ?;`)
-	if err != nil {
-		return err
-	}
-	defer statement.Close()
-
 	// Update data into DB.
-	_, err = statement.Exec(firstName, userID)
-	if err != nil {
-		return err
-	}
-
-	// Close transaction.
-	err = transaction.Commit()
+	_, err = db.exec(`UPDATE BotUserList SET FirstName = ? WHERE ID = ?;`, firstName, userID)
 	if err != nil {
 		return err
 	}
@@ -111,28 +78,8 @@ func (db *DB) BotUserUpdateLastName(tgID int64, lastName string) error {
 		return err
 	}
 
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		return err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for update row.
-	statement, err := transaction.Prepare(`UPDATE BotUserList SET LastName = ? WHERE ID = ?;`)
-	if err != nil {
-		return err
-	}
-	defer statement.Close()
-
 	// Update data into DB.
-	_, err = statement.Exec(lastName, userID)
-	if err != nil {
-		return err
-	}
-
-	// Close transaction.
-	err = transaction.Commit()
+	_, err = db.exec(`UPDATE BotUserList SET LastName = ? WHERE ID = ?;`, lastName, userID)
 	if err != nil {
 		return err
 	}
@@ -142,9 +89,7 @@ func (db *DB) BotUserUpdateLastName(tgID int64, lastName string) error {
 
 func (db *DB) BotUserGetByTelegramID(tgID int64) (int64, error) {
 	// Query table BotUser for user ID.
-	queryString := fmt.Sprintf("SELECT ID FROM BotUserList WHERE Status TelegramID = %d;", tgID)
-	db.Log.Debug(fmt.Sprintf("Query string '%v'", queryString))
-	rows, err := db.Instance.Query(queryString)
+	rows, err := db.query(`SELECT ID FROM BotUserList WHERE TelegramID = ?;`, tgID)
 	if err != nil {
 		db.Log.Error(fmt.Sprintf("Can't get bot user by telegram ID - '%+v'", err))
 		return 0, err
@@ -182,23 +127,8 @@ func (db *DB) BotUserGetByTelegramID(tgID int64) (int64, error) {
 func (db *DB) BotUserGetTelegramIDByID(ID int64) (int64, error) {
 	db.Log.Debug(fmt.Sprintf("Get TelegramID for user with ID '%+v'", ID))
 
-	// Create new sql transaction.
-	sqlTransaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for get TelegramID by user with ID '%v' - '%v'", ID, err))
-		return 0, err
-	}
-
-	// Prepare and execute transaction for get TelegramID.
-	statement, err := sqlTransaction.Prepare(`Select TelegramID from BotUserList where ID = ?`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for get TelegramID by user with ID '%v' - '%v'", ID, err))
-		return 0, err
-	}
-	defer statement.Close()
-
 	// Query TelegramID.
-	rows, err := statement.Query(ID)
+	rows, err := db.query(`Select TelegramID from BotUserList where ID = ?`, ID)
 	if err != nil {
 		db.Log.Error(fmt.Sprintf("Can't query for get TelegramID by user with ID '%v' - '%v'", ID, err))
 		return 0, err
@@ -230,11 +160,107 @@ func (db *DB) BotUserGetTelegramIDByID(ID int64) (int64, error) {
 		return 0, myErrors.ErrNoUsersFound
 	}
 
-	// Close transaction.
-	err = sqlTransaction.Commit()
+	return telegramID, nil
+}
+
+// Change user language. Find user by telegram ID.
+func (db *DB) BotUserUpdateLanguage(tgID int64, language string) error {
+	// Search for user ID.
+	userID, err := db.BotUserGetByTelegramID(tgID)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	return telegramID, nil
+	// Update data into DB.
+	_, err = db.exec(`UPDATE BotUserList SET Language = ? WHERE ID = ?;`, language, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Change user display timezone. Find user by telegram ID.
+func (db *DB) BotUserUpdateTimezone(tgID int64, timezone string) error {
+	return db.botUserUpdate(tgID, "Timezone", timezone)
+}
+
+// Change user quiet hours, e.g. "23:00-07:00". Empty string disable quiet hours. Find user by telegram ID.
+func (db *DB) BotUserUpdateQuietHours(tgID int64, quietHours string) error {
+	return db.botUserUpdate(tgID, "QuietHours", quietHours)
+}
+
+// Set do-not-disturb mode till unix timestamp. Zero disable mode. Find user by telegram ID.
+func (db *DB) BotUserUpdateDNDUntil(tgID int64, until int64) error {
+	return db.botUserUpdate(tgID, "DNDUntil", until)
+}
+
+// Change user notification mode for reminders. Find user by telegram ID.
+func (db *DB) BotUserUpdateNotificationMode(tgID int64, mode string) error {
+	return db.botUserUpdate(tgID, "NotificationMode", mode)
+}
+
+// Change one settings column for user. Column name must be constant.
+func (db *DB) botUserUpdate(tgID int64, column string, value interface{}) error {
+	// Search for user ID.
+	userID, err := db.BotUserGetByTelegramID(tgID)
+	if err != nil {
+		return err
+	}
+
+	// Update data into DB.
+	_, err = db.exec(fmt.Sprintf(`UPDATE BotUserList SET %v = ? WHERE ID = ?;`, column), value, userID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Return user settings by telegram ID. Not chosen settings are empty strings.
+func (db *DB) BotUserGetSettings(tgID int64) (DBProvider.BotUserSettings, error) {
+	db.Log.Debug(fmt.Sprintf("Get settings for user with telegram ID '%+v'", tgID))
+
+	rows, err := db.query(`SELECT Language, Timezone, QuietHours, DNDUntil, NotificationMode FROM BotUserList WHERE TelegramID = ?;`, tgID)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't query settings for user with telegram ID '%v' - '%v'", tgID, err))
+		return DBProvider.BotUserSettings{}, err
+	}
+	defer rows.Close()
+
+	// Check query result.
+	var language, timezone, quietHours, notificationMode sql.NullString
+	var dndUntil sql.NullInt64
+	rowNumber := 0
+	for rows.Next() {
+		err = rows.Scan(&language, &timezone, &quietHours, &dndUntil, &notificationMode)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't scan settings for user with telegram ID '%v' - '%v'", tgID, err))
+			return DBProvider.BotUserSettings{}, err
+		}
+		rowNumber++
+	}
+	err = rows.Err()
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("While iteration for get settings by user with telegram ID '%v' - '%v'", tgID, err))
+		return DBProvider.BotUserSettings{}, err
+	}
+	settings := DBProvider.BotUserSettings{
+		Language:   language.String,
+		Timezone:   timezone.String,
+		QuietHours: quietHours.String,
+		DNDUntil:   dndUntil.Int64,
+
+		NotificationMode: notificationMode.String,
+	}
+
+	// Check if more than one row or no raws received.
+	switch {
+	case rowNumber > 1:
+		return settings, myErrors.ErrMoreThanOneUser
+	case rowNumber == 0:
+		return DBProvider.BotUserSettings{}, myErrors.ErrNoUsersFound
+	}
+
+	return settings, nil
 }
@@ -15,32 +15,10 @@ func (db *DB) ClientTeamBoundClientAdd(client, team string) error {
 		return err
 	}
 
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
+	// Insert into table.
+	_, err = db.exec(`INSERT INTO ClientTeamBound(Client, Team) VALUES(?, ?);`, client, team)
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for add new client '%v' with team '%v' - '%v'", client, team, err))
-		return err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for insert into table.
-	statement, err := transaction.Prepare(`INSERT INTO ClientTeamBound(Client, Team) VALUES(?, ?);`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for add new client '%v' with team '%v' - '%v'", client, team, err))
-		return err
-	}
-	defer statement.Close()
-
-	_, err = statement.Exec(client, team)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't execute transaction for add new client '%v' with team '%v' - '%v'", client, team, err))
-		return err
-	}
-
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for add new client '%v' with team '%v' - '%v'", client, team, err))
+		db.Log.Error(fmt.Sprintf("Can't add new client '%v' with team '%v' - '%v'", client, team, err))
 		return err
 	}
 
@@ -58,32 +36,10 @@ func (db *DB) ClientTeamBoundClientUpdate(client, team string) error {
 		return err
 	}
 
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for update client '%v' with team '%v' - '%v'", client, team, err))
-		return err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for update table.
-	statement, err := transaction.Prepare(`UPDATE ClientTeamBound SET Team = ? WHERE Client = ?`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for update client '%v' with team '%v' - '%v'", client, team, err))
-		return err
-	}
-	defer statement.Close()
-
-	_, err = statement.Exec(team, client)
+	// Update table.
+	_, err = db.exec(`UPDATE ClientTeamBound SET Team = ? WHERE Client = ?`, team, client)
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't execute transaction for update client '%v' with team '%v' - '%v'", client, team, err))
-		return err
-	}
-
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for update client '%v' with team '%v' - '%v'", client, team, err))
+		db.Log.Error(fmt.Sprintf("Can't update client '%v' with team '%v' - '%v'", client, team, err))
 		return err
 	}
 
@@ -97,24 +53,8 @@ func (db *DB) ClientTeamBoundClientUpdate(client, team string) error {
 // If more then one team fined return ErrMoreThenOneTeamBounded.
 func (db *DB) ClientTeamBoundGetTeamByClient(client string) (string, error) {
 	db.Log.Debug(fmt.Sprintf("Get team for client '%+v'", client))
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for get team for client '%v' - '%v'", client, err))
-		return "", err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for select from table.
-	statement, err := transaction.Prepare(`SELECT Team FROM ClientTeamBound WHERE Client = ?;`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for get team for client '%v' - '%v'", client, err))
-		return "", err
-	}
-	defer statement.Close()
-
 	// Query team.
-	rows, err := statement.Query(client)
+	rows, err := db.query(`SELECT Team FROM ClientTeamBound WHERE Client = ?;`, client)
 	if err != nil {
 		db.Log.Error(fmt.Sprintf("Can't query for get team for client '%v' - '%v'", client, err))
 		return "", err
@@ -153,13 +93,6 @@ func (db *DB) ClientTeamBoundGetTeamByClient(client string) (string, error) {
 		return "", myErrors.ErrMoreThenOneTeamBounded
 	}
 
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for get team for client '%v' - '%v'", client, err))
-		return "", err
-	}
-
 	db.Log.Debug(fmt.Sprintf("Client '%+v' bound to team '%v'", client, team))
 	return team, nil
 }
@@ -0,0 +1,191 @@
+package SQLite3
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"time"
+)
+
+// Statements run inside transactions. Prepared with database, see transactionStatements.
+const (
+	sqlAddHistory       string = `INSERT INTO EventHistory(EventID, Created, Kind, Actor, Details) VALUES(?, ?, ?, ?, ?);`
+	sqlReadSnapshot     string = `SELECT Snapshot FROM OTRSEventList WHERE ID = ?;`
+	sqlWriteSnapshot    string = `UPDATE OTRSEventList SET Snapshot = ? WHERE ID = ?;`
+	sqlAddStatusHistory string = `INSERT INTO EventHistory(EventID, Created, Kind, Actor, Details)
+SELECT ID, ?, ?, '', ? FROM OTRSEventList WHERE ID = ? AND Status != ?;`
+)
+
+// Append entry into history of OTRS event.
+func (db *DB) EventHistoryAdd(eventID int64, kind, actor, details string) error {
+	_, err := db.exec(sqlAddHistory, eventID, time.Now().Unix(), kind, actor, details)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't add '%v' history entry for event '%v' - '%v'", kind, eventID, err))
+		return err
+	}
+
+	return nil
+}
+
+// Return history of OTRS event in order of occurrence.
+func (db *DB) EventHistoryGetByEvent(eventID int64) ([]DBProvider.EventHistory, error) {
+	// Query rows.
+	rows, err := db.query(`SELECT ID, EventID, Created, Kind, Actor, Details FROM EventHistory WHERE EventID = ? ORDER BY ID;`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Check query result.
+	historyList := make([]DBProvider.EventHistory, 0, 16)
+	for rows.Next() {
+		var entry DBProvider.EventHistory
+		var actor, details sql.NullString
+		err = rows.Scan(&entry.ID, &entry.EventID, &entry.Created, &entry.Kind, &actor, &details)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't scan history of event '%v' - '%+v'", eventID, err))
+			return nil, err
+		}
+		entry.Actor = actor.String
+		entry.Details = details.String
+		historyList = append(historyList, entry)
+	}
+	err = rows.Err()
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("While iteration for history of event '%v' - '%v'", eventID, err))
+		return nil, err
+	}
+
+	return historyList, nil
+}
+
+// Return events of provided type created in [from, to) unix timestamps with times of owner actions.
+// Acknowledge time is first action, close time is first close or merge action.
+func (db *DB) EventHistoryGetReactions(eventType string, from, to int64) ([]DBProvider.EventReaction, error) {
+	// Query rows.
+	rows, err := db.query(`SELECT e.ID, e.Status, e.Type, e.TicketID, e.TicketNumber, e.Created, e.Finished, e.Instance, e.Snapshot,
+(SELECT MIN(h.Created) FROM EventHistory h WHERE h.EventID = e.ID AND h.Kind = ?),
+(SELECT MIN(h.Created) FROM EventHistory h WHERE h.EventID = e.ID AND h.Kind = ? AND h.Details IN ('closed', 'merged'))
+FROM OTRSEventList e WHERE e.Type = ? AND e.Created >= ? AND e.Created < ? ORDER BY e.ID;`,
+		DBProvider.HistoryAction, DBProvider.HistoryAction, eventType, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Check query result.
+	reactionList := make([]DBProvider.EventReaction, 0, 64)
+	for rows.Next() {
+		var reaction DBProvider.EventReaction
+		var ticketNumber, instance, snapshotData sql.NullString
+		var finished, acknowledged, closed sql.NullInt64
+		err = rows.Scan(&reaction.ID, &reaction.Status, &reaction.Type, &reaction.TicketID, &ticketNumber, &reaction.Created,
+			&finished, &instance, &snapshotData, &acknowledged, &closed)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't scan for event reaction - '%+v'", err))
+			return nil, err
+		}
+		reaction.TicketNumber = ticketNumber.String
+		reaction.Finished = finished.Int64
+		reaction.Instance = instance.String
+		reaction.Acknowledged = acknowledged.Int64
+		reaction.Closed = closed.Int64
+		if snapshotData.Valid {
+			var snapshot DBProvider.TicketSnapshot
+			if json.Unmarshal([]byte(snapshotData.String), &snapshot) == nil {
+				reaction.CustomerID = snapshot.CustomerID
+				reaction.Priority = snapshot.Priority
+			}
+		}
+		reactionList = append(reactionList, reaction)
+	}
+	err = rows.Err()
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("While iteration for event reactions '%v'", err))
+		return nil, err
+	}
+
+	return reactionList, nil
+}
+
+// Save last known OTRS ticket snapshot for event and return previous one.
+// Return false if event has no saved snapshot.
+func (db *DB) OTRSEventSwapSnapshot(eventID int64, snapshot DBProvider.TicketSnapshot) (DBProvider.TicketSnapshot, bool, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return DBProvider.TicketSnapshot{}, false, err
+	}
+
+	// Create new sql transaction.
+	transaction, err := db.Instance.Begin()
+	if err != nil {
+		return DBProvider.TicketSnapshot{}, false, err
+	}
+	defer transaction.Rollback()
+
+	// Read previous snapshot and replace it.
+	read, err := db.prepare(transaction, sqlReadSnapshot)
+	if err != nil {
+		return DBProvider.TicketSnapshot{}, false, err
+	}
+	defer read.Close()
+	var previousData sql.NullString
+	err = read.QueryRow(eventID).Scan(&previousData)
+	if err == sql.ErrNoRows {
+		return DBProvider.TicketSnapshot{}, false, myErrors.ErrEventNotFound
+	}
+	if err != nil {
+		return DBProvider.TicketSnapshot{}, false, err
+	}
+	write, err := db.prepare(transaction, sqlWriteSnapshot)
+	if err != nil {
+		return DBProvider.TicketSnapshot{}, false, err
+	}
+	defer write.Close()
+	_, err = write.Exec(string(data), eventID)
+	if err != nil {
+		return DBProvider.TicketSnapshot{}, false, err
+	}
+
+	// Close transaction.
+	err = transaction.Commit()
+	if err != nil {
+		return DBProvider.TicketSnapshot{}, false, err
+	}
+
+	if !previousData.Valid {
+		return DBProvider.TicketSnapshot{}, false, nil
+	}
+	var previous DBProvider.TicketSnapshot
+	err = json.Unmarshal([]byte(previousData.String), &previous)
+	if err != nil {
+		db.Log.Warning(fmt.Sprintf("Can't parse snapshot of event '%v' - '%v'", eventID, err))
+		return DBProvider.TicketSnapshot{}, false, nil
+	}
+	return previous, true, nil
+}
+
+// Append history entry inside provided transaction.
+func (db *DB) addHistory(transaction *sql.Tx, eventID int64, kind, actor, details string) error {
+	statement, err := db.prepare(transaction, sqlAddHistory)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+	_, err = statement.Exec(eventID, time.Now().Unix(), kind, actor, details)
+	return err
+}
+
+// Append status history entry inside provided transaction if event status differs from provided one.
+// Must be called before status update.
+func (db *DB) addStatusHistory(transaction *sql.Tx, eventID int64, status string) error {
+	statement, err := db.prepare(transaction, sqlAddStatusHistory)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+	_, err = statement.Exec(time.Now().Unix(), DBProvider.HistoryStatus, status, eventID, status)
+	return err
+}
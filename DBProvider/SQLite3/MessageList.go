@@ -1,37 +1,44 @@
 package SQLite3
 
 import (
+	"database/sql"
 	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"sort"
+	"strconv"
 	"time"
 )
 
 // Add new message. Return message ID in DB.
-func (db *DB) MessageListNewMessage(sm string, chatID int64, text string) (int64, error) {
+// Instance is name of OTRS instance message relates to. Empty if unnamed or not related to one instance.
+func (db *DB) MessageListNewMessage(sm string, chatID int64, text, instance string) (int64, error) {
+	return db.messageListInsert(sm, fmt.Sprint(chatID), text, instance, 0)
+}
+
+// Add new message which must be delivered not earlier than unix timestamp. Return message ID in DB.
+func (db *DB) MessageListNewDeferredMessage(sm string, chatID int64, text, instance string, deliverAfter int64) (int64, error) {
+	return db.messageListInsert(sm, fmt.Sprint(chatID), text, instance, deliverAfter)
+}
+
+// Add new message for outbound webhook endpoint. Endpoint name stored as chat ID. Return message ID in DB.
+func (db *DB) MessageListNewWebhookMessage(endpoint, body, instance string) (int64, error) {
+	return db.messageListInsert("Webhook", endpoint, body, instance, 0)
+}
+
+// Insert new message into MessageList. Zero deliverAfter means message not deferred. Return message ID in DB.
+func (db *DB) messageListInsert(sm, chatID, text, instance string, deliverAfter int64) (int64, error) {
 	db.Log.Debug(fmt.Sprintf("Add new message for chat '%v' in '%v'. Text - '%v'", chatID, sm, text))
 
 	// Prepare data for insert.
 	Created := time.Now().Unix()
-
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for add new message for caht '%v' in '%v' - '%v'", chatID, sm, err))
-		return 0, err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for insert into table.
-	statement, err := transaction.Prepare(`INSERT INTO MessageList(SocialMedia, ChatID, MessageText, Created) VALUES(?, ?, ?, ?);`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for add new message for caht '%v' in '%v' - '%v'", chatID, sm, err))
-		return 0, err
-	}
-	defer statement.Close()
+	DeliverAfter := sql.NullInt64{Int64: deliverAfter, Valid: deliverAfter != 0}
+	Instance := sql.NullString{String: instance, Valid: instance != ""}
 
 	// Execute statement.
-	result, err := statement.Exec(sm, chatID, text, Created)
+	result, err := db.exec(`INSERT INTO MessageList(SocialMedia, ChatID, MessageText, Created, DeliverAfter, Instance) VALUES(?, ?, ?, ?, ?, ?);`,
+		sm, chatID, text, Created, DeliverAfter, Instance)
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't execute transaction for add new message for caht '%v' in '%v' - '%v'", chatID, sm, err))
+		db.Log.Error(fmt.Sprintf("Can't execute statement for add new message for caht '%v' in '%v' - '%v'", chatID, sm, err))
 		return 0, err
 	}
 
@@ -42,13 +49,6 @@ func (db *DB) MessageListNewMessage(sm string, chatID int64, text string) (int64
 		return 0, err
 	}
 
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for add new message for caht '%v' in '%v' - '%v'", chatID, sm, err))
-		return 0, err
-	}
-
 	db.Log.Debug(fmt.Sprintf("Succesfully add new message for chat '%v' in '%v'. Text - '%v'", chatID, sm, text))
 	return lastInsertID, nil
 }
@@ -57,33 +57,10 @@ func (db *DB) MessageListNewMessage(sm string, chatID int64, text string) (int64
 func (db *DB) MessageListMarkDelivered(ID int64) error {
 	db.Log.Debug(fmt.Sprintf("Mark message ID '%v' as delivered", ID))
 
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for mark message ID '%v' as delivered", ID))
-		return err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for update table.
-	statement, err := transaction.Prepare(`UPDATE MessageList SET Sent = ? WHERE ID = ?`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for mark message ID '%v' as delivered", ID))
-		return err
-	}
-	defer statement.Close()
-
 	// Execute statement.
-	_, err = statement.Exec(ID)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't execute transaction for mark message ID '%v' as delivered", ID))
-		return err
-	}
-
-	// Close transaction.
-	err = transaction.Commit()
+	_, err := db.exec(`UPDATE MessageList SET Sent = ? WHERE ID = ?`, time.Now().Unix(), ID)
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for mark message ID '%v' as delivered", ID))
+		db.Log.Error(fmt.Sprintf("Can't execute statement for mark message ID '%v' as delivered - '%v'", ID, err))
 		return err
 	}
 
@@ -94,27 +71,11 @@ func (db *DB) MessageListMarkDelivered(ID int64) error {
 // Get all messages undelivered to social media API.
 func (db *DB) MessageListGetAllUndeliveredBySM(sm string) ([]int64, error) {
 	db.Log.Debug(fmt.Sprintf("Get all undelivered messages for '%+v'", sm))
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't get all undelivered messages for '%v' - '%v'", sm, err))
-		return nil, err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for select from table.
-	statement, err := transaction.Prepare(`SELECT ID FROM MessageList WHERE Sent < ?;`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for get all undelivered messages for '%v' - '%v'", sm, err))
-		return nil, err
-	}
-	defer statement.Close()
-
 	// Leave time gap for just send messages.
 	timeThreshold := time.Now().Unix() + 60
 
 	// Query message list.
-	rows, err := statement.Query(timeThreshold)
+	rows, err := db.query(`SELECT ID FROM MessageList WHERE Sent < ?;`, timeThreshold)
 	if err != nil {
 		db.Log.Error(fmt.Sprintf("Can't query for get all undelivered messages for '%v' - '%v'", sm, err))
 		return nil, err
@@ -138,13 +99,6 @@ func (db *DB) MessageListGetAllUndeliveredBySM(sm string) ([]int64, error) {
 		return nil, err
 	}
 
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for get all undelivered messages for '%v' - '%v'", sm, err))
-		return nil, err
-	}
-
 	db.Log.Debug(fmt.Sprintf("Sucessful all undelivered messages for '%+v'", sm))
 	return messageIDList, nil
 }
@@ -152,29 +106,10 @@ func (db *DB) MessageListGetAllUndeliveredBySM(sm string) ([]int64, error) {
 // Get message text by message ID.
 func (db *DB) MessageListGetMessageText(ID int64) (string, error) {
 	db.Log.Debug(fmt.Sprintf("Get message text by message ID '%+v'", ID))
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't get message text by message ID '%+v'", ID))
-		return "", err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for select from table.
-	statement, err := transaction.Prepare(`SELECT MessageText FROM MessageList WHERE ID = ?;`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for message text by message ID '%+v'", ID))
-		return "", err
-	}
-	defer statement.Close()
-
-	// Leave time gap for just send messages.
-	timeThreshold := time.Now().Unix() + 60
-
 	// Query message text.
-	rows, err := statement.Query(timeThreshold)
+	rows, err := db.query(`SELECT MessageText FROM MessageList WHERE ID = ?;`, ID)
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't query for get message text by message ID '%+v'", ID))
+		db.Log.Error(fmt.Sprintf("Can't query for get message text by message ID '%+v' - '%v'", ID, err))
 		return "", err
 	}
 	defer rows.Close()
@@ -194,13 +129,6 @@ func (db *DB) MessageListGetMessageText(ID int64) (string, error) {
 		return "", err
 	}
 
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for get message text by message ID '%+v'", ID))
-		return "", err
-	}
-
 	db.Log.Debug(fmt.Sprintf("Sucessful get message text by message ID '%+v'", ID))
 	return text, nil
 }
@@ -208,29 +136,10 @@ func (db *DB) MessageListGetMessageText(ID int64) (string, error) {
 // Get chat ID for message by message ID.
 func (db *DB) MessageListGetMessageChatID(ID int64) (string, error) {
 	db.Log.Debug(fmt.Sprintf("Get chat ID by message ID '%+v'", ID))
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't get chat ID by message ID '%+v'", ID))
-		return "", err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for select from table.
-	statement, err := transaction.Prepare(`SELECT ChatID FROM MessageList WHERE ID = ?;`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for chat ID by message ID '%+v'", ID))
-		return "", err
-	}
-	defer statement.Close()
-
-	// Leave time gap for just send messages.
-	timeThreshold := time.Now().Unix() + 60
-
 	// Query chat ID.
-	rows, err := statement.Query(timeThreshold)
+	rows, err := db.query(`SELECT ChatID FROM MessageList WHERE ID = ?;`, ID)
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't query for get chat ID by message ID '%+v'", ID))
+		db.Log.Error(fmt.Sprintf("Can't query for get chat ID by message ID '%+v' - '%v'", ID, err))
 		return "", err
 	}
 	defer rows.Close()
@@ -250,13 +159,59 @@ func (db *DB) MessageListGetMessageChatID(ID int64) (string, error) {
 		return "", err
 	}
 
-	// Close transaction.
-	err = transaction.Commit()
+	db.Log.Debug(fmt.Sprintf("Sucessful get chat ID by message ID '%+v'", ID))
+	return chatID, nil
+}
+
+// Claim undelivered deferred messages for social media with delivery time before unix timestamp
+// for owner till lease expires. Selection and claim done by one statement so message claimed by one owner.
+func (db *DB) MessageListClaimDeferred(sm, owner string, leaseUntil, before int64) ([]DBProvider.DeferredMessage, error) {
+	db.Log.Debug(fmt.Sprintf("Claim deferred messages for '%v' before '%v' by '%v'", sm, before, owner))
+
+	statement, err := db.writeStatements.get(db.Instance, `UPDATE MessageList SET Owner = ?, LeaseUntil = ?
+WHERE SocialMedia = ? AND Sent IS NULL AND DeliverAfter <= ? AND (Owner IS NULL OR LeaseUntil < ?)
+RETURNING ID, ChatID, MessageText;`)
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for get chat ID by message ID '%+v'", ID))
-		return "", err
+		return nil, err
+	}
+	rows, err := statement.Query(
+		owner,
+		leaseUntil,
+		sm,
+		before,
+		time.Now().Unix(),
+	)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't claim deferred messages for '%v' - '%v'", sm, err))
+		return nil, err
 	}
+	defer rows.Close()
 
-	db.Log.Debug(fmt.Sprintf("Sucessful get chat ID by message ID '%+v'", ID))
-	return chatID, nil
+	// Collect query result.
+	messageList := make([]DBProvider.DeferredMessage, 0, 16)
+	for rows.Next() {
+		var message DBProvider.DeferredMessage
+		var chatID string
+		err = rows.Scan(&message.ID, &chatID, &message.Text)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't scan deferred message for '%v' - '%v'", sm, err))
+			return nil, err
+		}
+		message.ChatID, err = strconv.ParseInt(chatID, 10, 64)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Invalid chat ID '%v' in deferred message '%v' - '%v'", chatID, message.ID, err))
+			continue
+		}
+		messageList = append(messageList, message)
+	}
+	err = rows.Err()
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("While iteration for deferred messages for '%v' - '%v'", sm, err))
+		return nil, err
+	}
+
+	// Returned rows not ordered.
+	sort.Slice(messageList, func(i, j int) bool { return messageList[i].ID < messageList[j].ID })
+
+	return messageList, nil
 }
@@ -0,0 +1,39 @@
+package SQLite3
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+)
+
+// Return all message templates stored in DB.
+func (db *DB) MessageTemplateGetAll() ([]DBProvider.MessageTemplate, error) {
+	db.Log.Debug("Get all message templates")
+
+	// Query templates.
+	rows, err := db.query(`SELECT Kind, Channel, Body FROM MessageTemplate;`)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't query for get all message templates - '%v'", err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Check query result.
+	templateList := make([]DBProvider.MessageTemplate, 0, 16)
+	for rows.Next() {
+		var messageTemplate DBProvider.MessageTemplate
+		err = rows.Scan(&messageTemplate.Kind, &messageTemplate.Channel, &messageTemplate.Body)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't scan message template - '%v'", err))
+			return nil, err
+		}
+		templateList = append(templateList, messageTemplate)
+	}
+	err = rows.Err()
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("While iteration for get all message templates - '%v'", err))
+		return nil, err
+	}
+
+	db.Log.Debug(fmt.Sprintf("Got '%v' message templates", len(templateList)))
+	return templateList, nil
+}
@@ -1,15 +1,38 @@
 package SQLite3
 
 import (
+	"database/sql"
 	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
 	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"strconv"
 	"time"
 )
 
+// Statements run inside transactions. Prepared with database, see transactionStatements.
+const (
+	sqlInsertEvent string = `INSERT INTO OTRSEventList(Status, Channel, Type, TicketID, Created, ActivationInterval, NextActivation, Instance)
+values(?, ?, ?, ?, ?, ?, ?, ?)`
+	sqlRegisterDelivery string = `INSERT INTO OTRSEventDelivery(Key, Created) VALUES(?, ?) ON CONFLICT(Key) DO NOTHING;`
+	sqlReplayDelivery   string = `SELECT EventID FROM OTRSEventDelivery WHERE Key = ?;`
+	sqlInsertEventOnce  string = `INSERT INTO OTRSEventList(Status, Channel, Type, TicketID, Created, ActivationInterval, NextActivation, Instance)
+SELECT ?, ?, ?, ?, ?, ?, ?, ?
+WHERE NOT EXISTS (SELECT ID FROM OTRSEventList WHERE Type = ? AND Instance = ? AND TicketID = ?)
+	AND NOT EXISTS (SELECT TicketID FROM OTRSEventTombstone WHERE Type = ? AND Instance = ? AND TicketID = ?)
+ON CONFLICT DO NOTHING
+RETURNING ID;`
+	sqlSelectExistingEvent string = `SELECT ID FROM OTRSEventList WHERE Type = ? AND Instance = ? AND TicketID = ? ORDER BY ID LIMIT 1;`
+	sqlBindDelivery        string = `UPDATE OTRSEventDelivery SET EventID = ? WHERE Key = ? AND EventID IS NULL;`
+	sqlSetProcessing       string = `UPDATE OTRSEventList SET Status = 'Processing' WHERE ID = ?;`
+	sqlSetSuspended        string = `UPDATE OTRSEventList SET Status = 'Suspended', NextActivation = ? WHERE ID = ?;`
+	sqlSetEnded            string = `UPDATE OTRSEventList SET Status = 'Ended', Finished = ? WHERE ID = ?;`
+)
+
 // Create new OTRS event in database.
 // Increment LastID.OTRSEventList even if error occurred,
-func (db *DB) OTRSEventCreateNew(Channel, Type string, TicketID int64) error {
-	db.Log.Info(fmt.Sprintf("Write new OTRS event with type '%+v' and ticket ID '%+v'", Type, TicketID))
+func (db *DB) OTRSEventCreateNew(Channel, Type, Instance string, TicketID int64) error {
+	db.Log.Info(fmt.Sprintf("Write new OTRS event with type '%+v' and ticket ID '%+v' from instance '%v'", Type, TicketID, Instance))
 
 	// Prepare data for insert.
 	Status := "New"
@@ -24,16 +47,14 @@ func (db *DB) OTRSEventCreateNew(Channel, Type string, TicketID int64) error {
 	defer transaction.Rollback()
 
 	// Prepare and execute transaction for update row.
-	statement, err := transaction.Prepare(`INSERT INTO OTRSEventList(Status, Channel, Type, TicketID, Created, ActivationInterval, NextActivation)
-values(?, ?, ?, ?, ?, ?, ?)`,
-	)
+	statement, err := db.prepare(transaction, sqlInsertEvent)
 	if err != nil {
 		return err
 	}
 	defer statement.Close()
 
 	// Update data into DB.
-	_, err = statement.Exec(
+	result, err := statement.Exec(
 		Status,
 		Channel,
 		Type,
@@ -41,10 +62,19 @@ values(?, ?, ?, ?, ?, ?, ?)`,
 		Created,
 		DefaultActivationInterval,
 		NextActivation,
+		Instance,
 	)
 	if err != nil {
 		return err
 	}
+	ID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	err = db.addHistory(transaction, ID, DBProvider.HistoryStatus, Channel, Status)
+	if err != nil {
+		return err
+	}
 
 	// Close transaction.
 	err = transaction.Commit()
@@ -55,72 +85,187 @@ values(?, ?, ?, ?, ?, ?, ?)`,
 	return nil
 }
 
-// Return OTRS event ticket ID with status "New", 'Processing' or 'Suspended' and it's DB ID.
-// If there is no event that meets the conditions, return ErrNoActiveEvents error.
-func (db *DB) OTRSEventGetActive() (int64, string, error) {
-	// Query provided table for last ID.
-	currentTimestamp := time.Now().Unix()
+// Create OTRS event unless event with same type already exists for ticket. Check and insert are atomic.
+// Idempotency key identifies inbound delivery, repeated delivery with same key returns event of first one.
+// Empty key not stored. Return DB ID of created or existing event and true if event created.
+// Zero ID returned if event for ticket already removed by retention.
+func (db *DB) OTRSEventCreateOnce(Channel, Type, Instance string, TicketID int64, IdempotencyKey string) (int64, bool, error) {
+	db.Log.Debug(fmt.Sprintf("Create OTRS event with type '%+v' and ticket ID '%+v' from instance '%v' once with key '%v'", Type, TicketID, Instance, IdempotencyKey))
+
+	// Prepare data for insert.
+	Status := "New"
+	Created := time.Now().Unix()
+	NextActivation := Created + DefaultActivationInterval
 
 	// Create new sql transaction.
 	transaction, err := db.Instance.Begin()
 	if err != nil {
-		return 0, "", err
+		return 0, false, err
 	}
 	defer transaction.Rollback()
 
-	// Prepare and execute transaction for update row.
-	statement, err := transaction.Prepare(
-		`SELECT ID, TicketID FROM OTRSEventList where status in ('New', 'Processing', 'Suspended') and NextActivation < ? LIMIT 1;`,
-	)
-	if err != nil {
-		return 0, "", err
+	// Register delivery. Write first so transaction holds write lock till commit.
+	if IdempotencyKey != "" {
+		register, err := db.prepare(transaction, sqlRegisterDelivery)
+		if err != nil {
+			return 0, false, err
+		}
+		defer register.Close()
+		result, err := register.Exec(IdempotencyKey, Created)
+		if err != nil {
+			return 0, false, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return 0, false, err
+		}
+		if count == 0 {
+			replay, err := db.prepare(transaction, sqlReplayDelivery)
+			if err != nil {
+				return 0, false, err
+			}
+			defer replay.Close()
+			var ID sql.NullInt64
+			err = replay.QueryRow(IdempotencyKey).Scan(&ID)
+			if err != nil {
+				return 0, false, err
+			}
+			if ID.Valid {
+				db.Log.Info(fmt.Sprintf("Delivery with key '%v' already processed as event '%v'", IdempotencyKey, ID.Int64))
+				return ID.Int64, false, nil
+			}
+		}
 	}
-	defer statement.Close()
 
-	// Query provided table for last ID.
-	rows, err := statement.Query(currentTimestamp)
+	// Insert event if event with same type not exists for ticket from any channel and not removed by retention.
+	// Unique index guards against duplicates from same channel.
+	insert, err := db.prepare(transaction, sqlInsertEventOnce)
+	if err != nil {
+		return 0, false, err
+	}
+	defer insert.Close()
+	var ID int64
+	created := true
+	err = insert.QueryRow(
+		Status, Channel, Type, TicketID, Created, DefaultActivationInterval, NextActivation, Instance,
+		Type, Instance, TicketID,
+		Type, Instance, TicketID,
+	).Scan(&ID)
+	if err == sql.ErrNoRows {
+		created = false
+		var existing *sql.Stmt
+		existing, err = db.prepare(transaction, sqlSelectExistingEvent)
+		if err != nil {
+			return 0, false, err
+		}
+		defer existing.Close()
+		err = existing.QueryRow(Type, Instance, TicketID).Scan(&ID)
+		if err == sql.ErrNoRows {
+			// Event removed by retention. Nothing to bind delivery to.
+			db.Log.Info(fmt.Sprintf("Event with type '%v' for ticket '%v' from instance '%v' already ended and removed", Type, TicketID, Instance))
+			return 0, false, transaction.Commit()
+		}
+	}
 	if err != nil {
-		return 0, "", err
+		return 0, false, err
 	}
 
-	// Check query result.
-	var ID int64 = 0
-	var ticketIDint int64
-	for rows.Next() {
-		err = rows.Scan(&ID, &ticketIDint)
+	if created {
+		err = db.addHistory(transaction, ID, DBProvider.HistoryStatus, Channel, Status)
 		if err != nil {
-			db.Log.Error(fmt.Sprintf("Can't scan for active event - '%+v'", err))
-			return 0, "", err
+			return 0, false, err
 		}
 	}
-	err = rows.Err()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While iteration for active event '%v'", err))
-		return 0, "", err
+
+	// Bind delivery to event.
+	if IdempotencyKey != "" {
+		bind, err := db.prepare(transaction, sqlBindDelivery)
+		if err != nil {
+			return 0, false, err
+		}
+		defer bind.Close()
+		_, err = bind.Exec(ID, IdempotencyKey)
+		if err != nil {
+			return 0, false, err
+		}
 	}
-	defer rows.Close()
 
 	// Close transaction.
 	err = transaction.Commit()
 	if err != nil {
-		return 0, "", err
+		return 0, false, err
 	}
 
-	// Check if no one row received.
-	if ID == 0 {
-		return 0, "", myErrors.ErrNoActiveEvents
+	if created {
+		db.Log.Info(fmt.Sprintf("Write new OTRS event '%v' with type '%+v' and ticket ID '%+v' from instance '%v'", ID, Type, TicketID, Instance))
+	}
+	return ID, created, nil
+}
+
+// Claim due event for owner till lease expires and return its ID, instance and ticket ID.
+// Event claimable if it activated before dueBefore and not leased or lease expired.
+// Selection and claim done by one statement so event never claimed by two owners.
+func (db *DB) OTRSEventClaim(owner string, leaseUntil, dueBefore int64) (int64, string, string, error) {
+	statement, err := db.writeStatements.get(db.Instance, `UPDATE OTRSEventList SET Owner = ?, LeaseUntil = ?
+WHERE ID = (
+	SELECT ID FROM OTRSEventList
+	WHERE Status IN ('New', 'Processing', 'Suspended') AND NextActivation < ?
+		AND (Owner IS NULL OR LeaseUntil < ?)
+	ORDER BY NextActivation LIMIT 1
+)
+RETURNING ID, TicketID, Instance;`)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	var ID int64
+	var ticketIDint int64
+	var instance sql.NullString
+	err = statement.QueryRow(owner, leaseUntil, dueBefore, time.Now().Unix()).Scan(&ID, &ticketIDint, &instance)
+	if err == sql.ErrNoRows {
+		return 0, "", "", myErrors.ErrNoActiveEvents
+	}
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't claim active event for '%v' - '%+v'", owner, err))
+		return 0, "", "", err
 	}
 
 	ticketID := fmt.Sprint(ticketIDint)
-	return ID, ticketID, nil
+	return ID, instance.String, ticketID, nil
+}
+
+// Move lease of claimed event till provided unix timestamp.
+// Return ErrLeaseLost if event claimed by other owner or lease already released.
+func (db *DB) OTRSEventExtendLease(id int64, owner string, leaseUntil int64) error {
+	result, err := db.exec(`UPDATE OTRSEventList SET LeaseUntil = ? WHERE ID = ? AND Owner = ?;`, leaseUntil, id, owner)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return myErrors.ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Release event claimed by owner so any owner can claim it on next activation.
+func (db *DB) OTRSEventRelease(id int64, owner string) error {
+	_, err := db.exec(`UPDATE OTRSEventList SET Owner = NULL, LeaseUntil = NULL WHERE ID = ? AND Owner = ?;`, id, owner)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // Return earliest event activation timestamp for active events.
 func (db *DB) OTRSEventGetEarliestActivationTimestamp() (int64, error) {
 	// Query provided table for last ID.
-	queryString := "SELECT Created FROM OTRSEventList where Status in ('New', 'Processing', 'Suspended') ORDER BY NextActivation LIMIT 1;"
-	db.Log.Debug(fmt.Sprintf("Query string '%v'", queryString))
-	rows, err := db.Instance.Query(queryString)
+	rows, err := db.query("SELECT Created FROM OTRSEventList where Status in ('New', 'Processing', 'Suspended') ORDER BY NextActivation LIMIT 1;")
 	if err != nil {
 		db.Log.Error(fmt.Sprintf("Can't get earliest activation timestamp - '%+v'", err))
 		return 0, err
@@ -159,8 +304,14 @@ func (db *DB) OTRSEventProcessing(id int64) error {
 	}
 	defer transaction.Rollback()
 
+	// Record status change before update.
+	err = db.addStatusHistory(transaction, id, "Processing")
+	if err != nil {
+		return err
+	}
+
 	// Prepare and execute transaction for update row.
-	statement, err := transaction.Prepare(`UPDATE OTRSEventList SET Status = 'Processing' WHERE ID = ?;`)
+	statement, err := db.prepare(transaction, sqlSetProcessing)
 	if err != nil {
 		return err
 	}
@@ -181,10 +332,8 @@ func (db *DB) OTRSEventProcessing(id int64) error {
 	return nil
 }
 
-// Mark event as "Suspended" and renew "NextActivation" time.
-func (db *DB) OTRSEventSuspend(id int64) error {
-	nextActivation := time.Now().Unix() + DefaultActivationInterval
-
+// Mark event as "Suspended" and set "NextActivation" time as unix timestamp.
+func (db *DB) OTRSEventSuspend(id int64, nextActivation int64) error {
 	// Create new sql transaction.
 	transaction, err := db.Instance.Begin()
 	if err != nil {
@@ -192,8 +341,14 @@ func (db *DB) OTRSEventSuspend(id int64) error {
 	}
 	defer transaction.Rollback()
 
+	// Record status change before update.
+	err = db.addStatusHistory(transaction, id, "Suspended")
+	if err != nil {
+		return err
+	}
+
 	// Prepare and execute transaction for update row.
-	statement, err := transaction.Prepare(`UPDATE OTRSEventList SET Status = 'Suspended', NextActivation = ? WHERE ID = ?;`)
+	statement, err := db.prepare(transaction, sqlSetSuspended)
 	if err != nil {
 		return err
 	}
@@ -214,6 +369,17 @@ func (db *DB) OTRSEventSuspend(id int64) error {
 	return nil
 }
 
+// Move event activation to provided unix timestamp without status change.
+func (db *DB) OTRSEventPostpone(id int64, nextActivation int64) error {
+	// Update data into DB.
+	_, err := db.exec(`UPDATE OTRSEventList SET NextActivation = ? WHERE ID = ?;`, nextActivation, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // Mark event as "Ended" and add current timestamp into "Finished" column.
 func (db *DB) OTRSEventEnded(id int64) error {
 	Finished := time.Now().Unix()
@@ -225,8 +391,14 @@ func (db *DB) OTRSEventEnded(id int64) error {
 	}
 	defer transaction.Rollback()
 
+	// Record status change before update.
+	err = db.addStatusHistory(transaction, id, "Ended")
+	if err != nil {
+		return err
+	}
+
 	// Prepare and execute transaction for update row.
-	statement, err := transaction.Prepare(`UPDATE OTRSEventList SET Status = 'Ended', Finished = ? WHERE ID = ?;`)
+	statement, err := db.prepare(transaction, sqlSetEnded)
 	if err != nil {
 		return err
 	}
@@ -247,29 +419,16 @@ func (db *DB) OTRSEventEnded(id int64) error {
 	return nil
 }
 
-// Return true if event with given ticket ID and type exists.
-func (db *DB) OTRSEventIsExistsWithTicketIDAndType(ticketID int64, eventType string) (bool, error) {
+// Return true if event with given OTRS instance, ticket ID and type exists.
+func (db *DB) OTRSEventIsExistsWithTicketIDAndType(instance string, ticketID int64, eventType string) (bool, error) {
 	db.Log.Error(fmt.Sprintf("Check existense for event with type '%+v' and OTRS ID '%+v'", eventType, ticketID))
 
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		return false, err
-	}
-	defer transaction.Rollback()
-
-	// Prepare and execute transaction for update row.
-	statement, err := transaction.Prepare(`SELECT ID from OTRSEventList WHERE TicketID = ? and Type = ?;`)
-	if err != nil {
-		return false, err
-	}
-	defer statement.Close()
-
 	// Query provided table for last ID.
-	rows, err := statement.Query(ticketID, eventType)
+	rows, err := db.query(`SELECT ID from OTRSEventList WHERE TicketID = ? and Type = ? and Instance = ?;`, ticketID, eventType, instance)
 	if err != nil {
 		return false, err
 	}
+	defer rows.Close()
 
 	// Check query result.
 	var ID int64 = 0
@@ -285,13 +444,6 @@ func (db *DB) OTRSEventIsExistsWithTicketIDAndType(ticketID int64, eventType str
 		db.Log.Error(fmt.Sprintf("While iteration for check existense event with type '%+v' and OTRS ID '%+v'", eventType, ticketID))
 		return false, err
 	}
-	defer rows.Close()
-
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		return false, err
-	}
 
 	// Check if no one row received.
 	if ID == 0 {
@@ -305,25 +457,12 @@ func (db *DB) OTRSEventIsExistsWithTicketIDAndType(ticketID int64, eventType str
 func (db *DB) OTRSEventGetStatus(DBID int64) (string, error) {
 	db.Log.Error(fmt.Sprintf("Check status for event with with DBID '%+v'", DBID))
 
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		return "", err
-	}
-	defer transaction.Rollback()
-
-	// Prepare and execute transaction for update row.
-	statement, err := transaction.Prepare(`SELECT status from OTRSEventList WHERE ID = ?;`)
-	if err != nil {
-		return "", err
-	}
-	defer statement.Close()
-
 	// Query provided table event status.
-	rows, err := statement.Query(DBID)
+	rows, err := db.query(`SELECT status from OTRSEventList WHERE ID = ?;`, DBID)
 	if err != nil {
 		return "", err
 	}
+	defer rows.Close()
 
 	// Check query result.
 	var status string = ""
@@ -339,13 +478,6 @@ func (db *DB) OTRSEventGetStatus(DBID int64) (string, error) {
 		db.Log.Error(fmt.Sprintf("While iteration for check tatus for event with with DBID '%+v'", DBID))
 		return "", err
 	}
-	defer rows.Close()
-
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		return "", err
-	}
 
 	// Check if no one row received.
 	if status == "" {
@@ -354,3 +486,182 @@ func (db *DB) OTRSEventGetStatus(DBID int64) (string, error) {
 
 	return status, nil
 }
+
+// Return event by DB ID. Return ErrEventNotFound if event not exists.
+func (db *DB) OTRSEventGetByID(id int64) (DBProvider.OTRSEvent, error) {
+	eventList, err := db.otrsEventQuery(`where ID = ?`, id)
+	if err != nil {
+		return DBProvider.OTRSEvent{}, err
+	}
+	if len(eventList) == 0 {
+		return DBProvider.OTRSEvent{}, myErrors.ErrEventNotFound
+	}
+	return eventList[0], nil
+}
+
+// Return all events with status "New", 'Processing' or 'Suspended' regardless of activation time.
+func (db *DB) OTRSEventGetAllActive() ([]DBProvider.OTRSEvent, error) {
+	return db.otrsEventQuery(`where Status in ('New', 'Processing', 'Suspended') ORDER BY ID`)
+}
+
+// Return all events of OTRS instance for ticket in order of creation. Ticket found by OTRS ticket number,
+// ticket ID checked only if no ticket with such number, so ID of one ticket not matched with number of other.
+func (db *DB) OTRSEventGetByTicket(instance, ticket string) ([]DBProvider.OTRSEvent, error) {
+	eventList, err := db.otrsEventQuery(`where Instance = ? AND TicketNumber = ? ORDER BY ID`, instance, ticket)
+	if err != nil || len(eventList) > 0 {
+		return eventList, err
+	}
+	ticketID, err := strconv.ParseInt(ticket, 10, 64)
+	if err != nil {
+		return eventList, nil
+	}
+	return db.otrsEventQuery(`where Instance = ? AND TicketID = ? ORDER BY ID`, instance, ticketID)
+}
+
+// Save OTRS ticket number for all events of ticket where it not saved yet.
+func (db *DB) OTRSEventSetTicketNumber(instance string, ticketID int64, ticketNumber string) error {
+	// Update data into DB.
+	_, err := db.exec(`UPDATE OTRSEventList SET TicketNumber = ? WHERE TicketID = ? AND Instance = ? AND TicketNumber IS NULL;`, ticketNumber, ticketID, instance)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Assign events created before OTRS instances introduced to default instance.
+// Event without instance duplicating event of default instance merged into earlier one.
+func (db *DB) OTRSEventSetDefaultInstance(instance string) error {
+	// Create new sql transaction.
+	transaction, err := db.Instance.Begin()
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	err = mergeDuplicateEvents(transaction, db.Log, sqlSelectDefaultInstanceDuplicates, instance, instance)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't merge events without instance into OTRS instance '%v' - '%v'", instance, err))
+		return err
+	}
+	result, err := transaction.Exec(`UPDATE OTRSEventList SET Instance = ? WHERE Instance IS NULL;`, instance)
+	if err != nil {
+		return err
+	}
+	err = transaction.Commit()
+	if err != nil {
+		return err
+	}
+	if count, err := result.RowsAffected(); err == nil && count > 0 {
+		db.Log.Info(fmt.Sprintf("'%v' events assigned to OTRS instance '%v'", count, instance))
+	}
+
+	return nil
+}
+
+// Select pairs of duplicate event ID and ID of first event with same key.
+const (
+	sqlSelectDuplicateEvents string = `
+SELECT e.ID, k.KeptID, e.Channel, e.Type, e.Instance, e.TicketID FROM OTRSEventList e
+JOIN (SELECT Channel, Type, Instance, TicketID, MIN(ID) AS KeptID FROM OTRSEventList WHERE Instance IS NOT NULL
+GROUP BY Channel, Type, Instance, TicketID HAVING count(*) > 1) k USING (Channel, Type, Instance, TicketID)
+WHERE e.ID != k.KeptID ORDER BY e.ID;`
+	// Events without instance and events of default instance provided twice.
+	sqlSelectDefaultInstanceDuplicates string = `
+SELECT e.ID, k.KeptID, e.Channel, e.Type, ifnull(e.Instance, ''), e.TicketID FROM OTRSEventList e
+JOIN (SELECT Channel, Type, TicketID, MIN(ID) AS KeptID FROM OTRSEventList WHERE Instance IS NULL OR Instance = ?
+GROUP BY Channel, Type, TicketID HAVING count(*) > 1) k USING (Channel, Type, TicketID)
+WHERE (e.Instance IS NULL OR e.Instance = ?) AND e.ID != k.KeptID ORDER BY e.ID;`
+)
+
+// Remove duplicate events selected by query. History and delivery keys of removed event moved to kept event.
+func mergeDuplicateEvents(transaction *sql.Tx, Log logger.Logger, query string, args ...interface{}) error {
+	type duplicate struct {
+		ID, KeptID    int64
+		Channel, Type string
+		Instance      string
+		TicketID      int64
+	}
+
+	// Read all pairs before changes.
+	rows, err := transaction.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	duplicateList := make([]duplicate, 0, 16)
+	for rows.Next() {
+		var d duplicate
+		err = rows.Scan(&d.ID, &d.KeptID, &d.Channel, &d.Type, &d.Instance, &d.TicketID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		duplicateList = append(duplicateList, d)
+	}
+	rows.Close()
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range duplicateList {
+		Log.Warning(fmt.Sprintf("Event '%v' duplicates event '%v' (channel '%v', type '%v', instance '%v', ticket '%v'). Merge it into first event",
+			d.ID, d.KeptID, d.Channel, d.Type, d.Instance, d.TicketID))
+		for _, statement := range []string{
+			`UPDATE EventHistory SET EventID = ? WHERE EventID = ?;`,
+			`UPDATE OTRSEventDelivery SET EventID = ? WHERE EventID = ?;`,
+		} {
+			_, err = transaction.Exec(statement, d.KeptID, d.ID)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = transaction.Exec(`DELETE FROM OTRSEventList WHERE ID = ?;`, d.ID)
+		if err != nil {
+			return err
+		}
+	}
+	if len(duplicateList) > 0 {
+		Log.Info(fmt.Sprintf("'%v' duplicate events merged", len(duplicateList)))
+	}
+
+	return nil
+}
+
+// Return events selected by provided condition. Condition must be constant.
+func (db *DB) otrsEventQuery(condition string, args ...interface{}) ([]DBProvider.OTRSEvent, error) {
+	// Query rows.
+	rows, err := db.query(
+		fmt.Sprint(`SELECT ID, Status, Type, TicketID, TicketNumber, Created, Finished, Instance FROM OTRSEventList `, condition, `;`),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Check query result.
+	eventList := make([]DBProvider.OTRSEvent, 0, 16)
+	for rows.Next() {
+		var event DBProvider.OTRSEvent
+		var ticketNumber sql.NullString
+		var finished sql.NullInt64
+		var instance sql.NullString
+		err = rows.Scan(&event.ID, &event.Status, &event.Type, &event.TicketID, &ticketNumber, &event.Created, &finished, &instance)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't scan for event - '%+v'", err))
+			return nil, err
+		}
+		event.TicketNumber = ticketNumber.String
+		event.Finished = finished.Int64
+		event.Instance = instance.String
+		eventList = append(eventList, event)
+	}
+	err = rows.Err()
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("While iteration for events '%v'", err))
+		return nil, err
+	}
+
+	return eventList, nil
+}
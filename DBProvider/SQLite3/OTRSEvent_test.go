@@ -0,0 +1,170 @@
+package SQLite3
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+)
+
+// Drop all messages.
+type quietLogger struct{}
+
+func (ql quietLogger) SetModuleName(name string) logger.Logger { return ql }
+func (quietLogger) Error(message string)                       {}
+func (quietLogger) Warning(message string)                     {}
+func (quietLogger) Info(message string)                        {}
+func (quietLogger) Debug(message string)                       {}
+
+// Return database in directory, closed after test.
+func openTestDB(t testing.TB, directory string) *DB {
+	t.Helper()
+	db := &DB{}
+	err := db.Initialise(quietLogger{}, directory)
+	if err != nil {
+		t.Fatalf("initialise database - %v", err)
+	}
+	t.Cleanup(func() {
+		db.Reader.Close()
+		db.Instance.Close()
+	})
+	return db
+}
+
+func mustExec(t testing.TB, db *DB, query string, args ...interface{}) {
+	t.Helper()
+	_, err := db.Instance.Exec(query, args...)
+	if err != nil {
+		t.Fatalf("exec '%v' - %v", query, err)
+	}
+}
+
+// Insert event and return its ID.
+func insertEvent(t *testing.T, db *DB, instance interface{}, ticketID int64) int64 {
+	t.Helper()
+	result, err := db.Instance.Exec(`INSERT INTO OTRSEventList(Status, Channel, Type, TicketID, Created, Instance)
+VALUES('Ended', 'Webhook', 'NewTicket', ?, 1, ?);`, ticketID, instance)
+	if err != nil {
+		t.Fatalf("insert event - %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+func countRows(t *testing.T, db *DB, query string, args ...interface{}) int {
+	t.Helper()
+	var count int
+	err := db.Instance.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		t.Fatalf("count '%v' - %v", query, err)
+	}
+	return count
+}
+
+func TestUniqueIndexMergesDuplicates(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+	mustExec(t, db, `DROP INDEX OTRSEventListUnique;`)
+	first := insertEvent(t, db, "main", 1)
+	second := insertEvent(t, db, "main", 1)
+	other := insertEvent(t, db, "second", 1)
+	mustExec(t, db, `INSERT INTO EventHistory(EventID, Created, Kind) VALUES(?, 1, 'Created'), (?, 2, 'Alerted');`, first, second)
+	mustExec(t, db, `INSERT INTO OTRSEventDelivery(Key, EventID, Created) VALUES('key', ?, 1);`, second)
+
+	err := createAllIndexesIfNotExist(db.Instance, quietLogger{})
+	if err != nil {
+		t.Fatalf("create indexes - %v", err)
+	}
+
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventList WHERE ID IN (?, ?);`, first, other); count != 2 {
+		t.Errorf("%v of first and other instance events kept, want 2", count)
+	}
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventList WHERE ID = ?;`, second); count != 0 {
+		t.Errorf("duplicate event not removed")
+	}
+	if count := countRows(t, db, `SELECT count(*) FROM EventHistory WHERE EventID = ?;`, first); count != 2 {
+		t.Errorf("%v history entries of first event, want 2", count)
+	}
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventDelivery WHERE EventID = ?;`, first); count != 1 {
+		t.Errorf("delivery key not moved to first event")
+	}
+
+	// Migration runs once, duplicates can't appear after index created.
+	err = createAllIndexesIfNotExist(db.Instance, quietLogger{})
+	if err != nil {
+		t.Fatalf("create indexes again - %v", err)
+	}
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventList;`); count != 2 {
+		t.Errorf("%v events after second run, want 2", count)
+	}
+}
+
+func TestSetDefaultInstanceMergesClashes(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+	legacy := insertEvent(t, db, nil, 1)
+	legacyDuplicate := insertEvent(t, db, nil, 1)
+	current := insertEvent(t, db, "main", 1)
+	single := insertEvent(t, db, nil, 2)
+	mustExec(t, db, `INSERT INTO EventHistory(EventID, Created, Kind) VALUES(?, 1, 'Created'), (?, 2, 'Alerted');`, legacyDuplicate, current)
+
+	err := db.OTRSEventSetDefaultInstance("main")
+	if err != nil {
+		t.Fatalf("set default instance - %v", err)
+	}
+
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventList WHERE Instance IS NULL;`); count != 0 {
+		t.Errorf("%v events left without instance", count)
+	}
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventList WHERE Instance = 'main' AND ID IN (?, ?);`, legacy, single); count != 2 {
+		t.Errorf("%v events assigned to default instance, want 2", count)
+	}
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventList;`); count != 2 {
+		t.Errorf("%v events after merge, want 2", count)
+	}
+	if count := countRows(t, db, `SELECT count(*) FROM EventHistory WHERE EventID = ?;`, legacy); count != 2 {
+		t.Errorf("%v history entries of kept event, want 2", count)
+	}
+}
+
+func TestGetByTicketScopedToInstance(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+	// Ticket 1 of "main" has number "42", ticket 42 of "main" has number "1000".
+	byNumber := insertEvent(t, db, "main", 1)
+	mustExec(t, db, `UPDATE OTRSEventList SET TicketNumber = '42' WHERE ID = ?;`, byNumber)
+	byID := insertEvent(t, db, "main", 42)
+	mustExec(t, db, `UPDATE OTRSEventList SET TicketNumber = '1000' WHERE ID = ?;`, byID)
+	// Same ticket ID in other instance.
+	other := insertEvent(t, db, "other", 42)
+
+	cases := []struct {
+		name     string
+		instance string
+		ticket   string
+		want     []int64
+	}{
+		{"number preferred over ID", "main", "42", []int64{byNumber}},
+		{"ID without number match", "main", "1", []int64{byNumber}},
+		{"number of second ticket", "main", "1000", []int64{byID}},
+		{"other instance by ID", "other", "42", []int64{other}},
+		{"number of other instance", "other", "1000", nil},
+		{"unknown instance", "missing", "42", nil},
+		{"not a number", "main", "abc", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			eventList, err := db.OTRSEventGetByTicket(c.instance, c.ticket)
+			if err != nil {
+				t.Fatalf("get events - %v", err)
+			}
+			got := make([]int64, 0, len(eventList))
+			for _, event := range eventList {
+				if event.Instance != c.instance {
+					t.Errorf("event %v of instance '%v'", event.ID, event.Instance)
+				}
+				got = append(got, event.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(append([]int64{}, c.want...)) {
+				t.Errorf("events %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package SQLite3
+
+import (
+	"fmt"
+	"time"
+)
+
+// Return last poll watermark for OTRS instance as unix timestamp. Zero if instance never polled.
+func (db *DB) PollerStateGetWatermark(instance string) (int64, error) {
+	// Query watermark.
+	rows, err := db.query(`SELECT Watermark FROM PollerState WHERE Instance = ?;`, instance)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't query watermark of instance '%v' - '%v'", instance, err))
+		return 0, err
+	}
+	defer rows.Close()
+
+	// Zero if no row received.
+	var watermark int64
+	for rows.Next() {
+		err = rows.Scan(&watermark)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't scan watermark of instance '%v' - '%v'", instance, err))
+			return 0, err
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("While iteration for watermark of instance '%v' - '%v'", instance, err))
+		return 0, err
+	}
+
+	return watermark, nil
+}
+
+// Save poll watermark for OTRS instance as unix timestamp.
+func (db *DB) PollerStateSetWatermark(instance string, watermark int64) error {
+	// Insert or update row.
+	_, err := db.exec(`INSERT OR REPLACE INTO PollerState(Instance, Watermark, Updated) VALUES(?, ?, ?);`, instance, watermark, time.Now().Unix())
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't set watermark of instance '%v' - '%v'", instance, err))
+		return err
+	}
+
+	db.Log.Debug(fmt.Sprintf("Watermark of instance '%v' set to '%v'", instance, watermark))
+	return nil
+}
@@ -0,0 +1,193 @@
+package SQLite3
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"strings"
+)
+
+// Expired rows of retention kind. Child rows removed together with parent rows.
+type retentionRule struct {
+	Table     string
+	Condition string // Row expired if true. Single parameter is expiration unix timestamp.
+	Children  []retentionChild
+	Tombstone string // Keep keys of removed rows. Rows selected by "%s" placeholder of rowid list.
+}
+
+type retentionChild struct {
+	Table  string
+	Column string // References parent ID.
+}
+
+var retentionRuleList = map[string]retentionRule{
+	DBProvider.RetentionEvents: {
+		Table:     "OTRSEventList",
+		Condition: "Status = 'Ended' AND Finished < ?",
+		Children:  []retentionChild{{Table: "EventHistory", Column: "EventID"}},
+		// Removed event still blocks new event for same ticket, so repeated notification not alerted again.
+		Tombstone: `INSERT INTO OTRSEventTombstone(Type, Instance, TicketID, Removed)
+SELECT Type, ifnull(Instance, ''), TicketID, strftime('%%s', 'now') FROM OTRSEventList WHERE rowid IN (%s)
+ON CONFLICT DO NOTHING;`,
+	},
+	DBProvider.RetentionMessages: {
+		Table:     "MessageList",
+		Condition: "Sent IS NOT NULL AND Sent < ?",
+	},
+	DBProvider.RetentionDeliveries: {
+		Table:     "OTRSEventDelivery",
+		Condition: "Created < ?",
+	},
+}
+
+// Remove one batch of rows expired before provided unix timestamp. If archive provided, rows passed to it
+// before removal, on archive error nothing removed. Return number of removed rows without child rows.
+func (db *DB) RetentionPurge(kind string, before int64, batchSize int, archive DBProvider.Archiver) (int64, error) {
+	rule, ok := retentionRuleList[kind]
+	if !ok {
+		return 0, fmt.Errorf("%w '%v'", myErrors.ErrUnknownRetentionKind, kind)
+	}
+
+	// Create new sql transaction.
+	transaction, err := db.Instance.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer transaction.Rollback()
+
+	// Select expired rows. Condition and table names are constant.
+	rowIDList, rowList, err := selectRows(transaction,
+		fmt.Sprintf(`SELECT rowid, * FROM %s WHERE %s ORDER BY rowid LIMIT ?;`, rule.Table, rule.Condition), before, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(rowIDList) == 0 {
+		return 0, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(rowIDList)), ", ")
+
+	// Archive and delete child rows first.
+	for _, child := range rule.Children {
+		query := fmt.Sprintf(`SELECT rowid, * FROM %s WHERE %s IN (SELECT ID FROM %s WHERE rowid IN (%s));`,
+			child.Table, child.Column, rule.Table, placeholders)
+		_, childRowList, err := selectRows(transaction, query, rowIDList...)
+		if err != nil {
+			return 0, err
+		}
+		if archive != nil && len(childRowList) > 0 {
+			err = archive.Archive(child.Table, childRowList)
+			if err != nil {
+				return 0, err
+			}
+		}
+		_, err = transaction.Exec(fmt.Sprintf(`DELETE FROM %s WHERE %s IN (SELECT ID FROM %s WHERE rowid IN (%s));`,
+			child.Table, child.Column, rule.Table, placeholders), rowIDList...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if archive != nil {
+		err = archive.Archive(rule.Table, rowList)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if rule.Tombstone != "" {
+		_, err = transaction.Exec(fmt.Sprintf(rule.Tombstone, placeholders), rowIDList...)
+		if err != nil {
+			return 0, err
+		}
+	}
+	result, err := transaction.Exec(fmt.Sprintf(`DELETE FROM %s WHERE rowid IN (%s);`, rule.Table, placeholders), rowIDList...)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	// Close transaction.
+	err = transaction.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Rebuild database file to return free pages to file system and update query planner statistics.
+// Return database size in bytes before and after.
+func (db *DB) Optimise() (int64, int64, error) {
+	sizeBefore, err := db.size()
+	if err != nil {
+		return 0, 0, err
+	}
+	_, err = db.Instance.Exec(`VACUUM;`)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, err = db.Instance.Exec(`ANALYZE;`)
+	if err != nil {
+		return 0, 0, err
+	}
+	sizeAfter, err := db.size()
+	if err != nil {
+		return 0, 0, err
+	}
+	return sizeBefore, sizeAfter, nil
+}
+
+// Return database size in bytes.
+func (db *DB) size() (int64, error) {
+	var pageCount, pageSize int64
+	err := db.Reader.QueryRow(`PRAGMA page_count;`).Scan(&pageCount)
+	if err != nil {
+		return 0, err
+	}
+	err = db.Reader.QueryRow(`PRAGMA page_size;`).Scan(&pageSize)
+	if err != nil {
+		return 0, err
+	}
+	return pageCount * pageSize, nil
+}
+
+// Return rowid and row values by column name for query selecting rowid first.
+func selectRows(transaction *sql.Tx, query string, args ...interface{}) ([]interface{}, []map[string]interface{}, error) {
+	rows, err := transaction.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	rowIDList := make([]interface{}, 0, 64)
+	rowList := make([]map[string]interface{}, 0, 64)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, nil, err
+		}
+		row := make(map[string]interface{}, len(columns)-1)
+		for i, column := range columns[1:] {
+			value := values[i+1]
+			if data, ok := value.([]byte); ok {
+				value = string(data)
+			}
+			row[column] = value
+		}
+		rowIDList = append(rowIDList, values[0])
+		rowList = append(rowList, row)
+	}
+	return rowIDList, rowList, rows.Err()
+}
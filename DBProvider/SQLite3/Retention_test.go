@@ -0,0 +1,45 @@
+package SQLite3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+)
+
+func TestRetentionKeepsEventDedup(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+	eventID, created, err := db.OTRSEventCreateOnce("newticket", "NewTicket", "main", 42, "first")
+	if err != nil || !created {
+		t.Fatalf("create event - %v, %v", created, err)
+	}
+	mustExec(t, db, `UPDATE OTRSEventList SET Status = 'Ended', Finished = 1 WHERE ID = ?;`, eventID)
+
+	before := time.Now().Add(time.Minute).Unix()
+	for _, kind := range []string{DBProvider.RetentionEvents, DBProvider.RetentionDeliveries} {
+		count, err := db.RetentionPurge(kind, before, 100, nil)
+		if err != nil || count != 1 {
+			t.Fatalf("purge %v removed %v rows - %v", kind, count, err)
+		}
+	}
+
+	// Repeated notification with same and new delivery key.
+	for _, key := range []string{"first", "second", ""} {
+		_, created, err = db.OTRSEventCreateOnce("newticket", "NewTicket", "main", 42, key)
+		if err != nil {
+			t.Fatalf("create event with key '%v' - %v", key, err)
+		}
+		if created {
+			t.Errorf("event for removed ticket created again with key '%v'", key)
+		}
+	}
+	if count := countRows(t, db, `SELECT count(*) FROM OTRSEventList;`); count != 0 {
+		t.Errorf("%v events after repeated notification", count)
+	}
+
+	// Other tickets and instances not blocked.
+	_, created, err = db.OTRSEventCreateOnce("newticket", "NewTicket", "second", 42, "")
+	if err != nil || !created {
+		t.Errorf("event of other instance not created - %v", err)
+	}
+}
@@ -7,19 +7,50 @@ import (
 	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
 	_ "github.com/mattn/go-sqlite3"
 	"path/filepath"
+	"sync"
 )
 
 const (
 	ModuleName                string = "DB Provider SQLite3"
 	DBFileName                string = "sqlite3.bd"
-	DefaultActivationInterval int64  = 300 // In seconds
+	DefaultActivationInterval int64  = 300  // In seconds
+	ReadConnections           int    = 4    // Connections in read pool.
+	BusyTimeout               int    = 5000 // Milliseconds to wait for lock before "database is locked" error.
 )
 
 // Implement DBProvider interface
 type DB struct {
-	Instance     *sql.DB
+	Instance     *sql.DB // Single writer connection. Writers wait for it in pool instead of failing on lock.
+	Reader       *sql.DB // Pool of query only connections, in WAL mode work in parallel with writer.
 	FileFullPath string
 	Log          logger.Logger
+
+	writeStatements statementCache
+	readStatements  statementCache
+}
+
+// Statements prepared once for pool and reused by all calls.
+type statementCache struct {
+	mx   sync.Mutex
+	list map[string]*sql.Stmt
+}
+
+// Statements bound to transactions. Writer connection busy with transaction, so statements
+// prepared on writer pool with database and transactions only bind them.
+var transactionStatements = []string{
+	sqlInsertEvent,
+	sqlRegisterDelivery,
+	sqlReplayDelivery,
+	sqlInsertEventOnce,
+	sqlSelectExistingEvent,
+	sqlBindDelivery,
+	sqlSetProcessing,
+	sqlSetSuspended,
+	sqlSetEnded,
+	sqlReadSnapshot,
+	sqlWriteSnapshot,
+	sqlAddHistory,
+	sqlAddStatusHistory,
 }
 
 // Store last ID for each table with ID column.
@@ -38,16 +69,71 @@ func (db *DB) Initialise(logger logger.Logger, directory string) error {
 	db.FileFullPath = filepath.Join(directory, DBFileName)
 	db.Log.Debug(fmt.Sprintf("Use DB file '%v'", db.FileFullPath))
 
-	// Prepare DB engine.
-	dbInstance, err := sql.Open("sqlite3", db.FileFullPath)
+	return db.open()
+}
+
+// Open writer and read pool, bring schema to current version.
+func (db *DB) open() error {
+	// Prepare DB engine. Writes in immediate transactions on single connection, so lock taken
+	// at transaction start and concurrent writers queued in pool. WAL lets readers work during write.
+	dbInstance, err := sql.Open("sqlite3", fmt.Sprintf("%v?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=%v&_txlock=immediate",
+		db.FileFullPath, BusyTimeout))
 	if err != nil {
 		db.Log.Error(fmt.Sprintf("DB instance initialisation failed '%v'", err))
 		return err
 	}
+	dbInstance.SetMaxOpenConns(1)
 	db.Instance = dbInstance
 
+	// Create tables before readers opened.
+	err = db.prepareSchema()
+	if err != nil {
+		return err
+	}
+	for _, query := range transactionStatements {
+		_, err = db.writeStatements.get(db.Instance, query)
+		if err != nil {
+			db.Log.Error(fmt.Sprintf("Can't prepare statement '%v' - '%v'", query, err))
+			return err
+		}
+	}
+
+	readerInstance, err := sql.Open("sqlite3", fmt.Sprintf("%v?_busy_timeout=%v&_query_only=true", db.FileFullPath, BusyTimeout))
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("DB reader initialisation failed '%v'", err))
+		return err
+	}
+	readerInstance.SetMaxOpenConns(ReadConnections)
+	readerInstance.SetMaxIdleConns(ReadConnections)
+	db.Reader = readerInstance
+
+	return nil
+}
+
+// Close writer and read pool with cached statements.
+func (db *DB) close() {
+	db.writeStatements.close()
+	db.readStatements.close()
+	db.Reader.Close()
+	db.Instance.Close()
+}
+
+// Bring database schema to current version. Also used after restore from backup made by previous version.
+func (db *DB) prepareSchema() error {
 	// Create tables if not exists.
-	err = createAllTablesIfNotExist(db.Instance, db.Log)
+	err := createAllTablesIfNotExist(db.Instance, db.Log)
+	if err != nil {
+		return err
+	}
+
+	// Add columns missing in tables created by previous versions.
+	err = addMissingColumns(db.Instance, db.Log)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes after columns added.
+	err = createAllIndexesIfNotExist(db.Instance, db.Log)
 	if err != nil {
 		return err
 	}
@@ -86,3 +172,74 @@ func executeStatement(db *sql.DB, statement string) error {
 
 	return nil
 }
+
+// Execute single change statement on writer connection with cached statement.
+func (db *DB) exec(query string, args ...interface{}) (sql.Result, error) {
+	statement, err := db.writeStatements.get(db.Instance, query)
+	if err != nil {
+		return nil, err
+	}
+	return statement.Exec(args...)
+}
+
+// Run single query on read pool with cached statement.
+func (db *DB) query(query string, args ...interface{}) (*sql.Rows, error) {
+	statement, err := db.readStatements.get(db.Reader, query)
+	if err != nil {
+		return nil, err
+	}
+	return statement.Query(args...)
+}
+
+// Return cached write statement bound to transaction. Statement closed with transaction.
+func (db *DB) prepare(transaction *sql.Tx, query string) (*sql.Stmt, error) {
+	return db.writeStatements.bind(transaction, query)
+}
+
+// Return statement prepared on pool. Prepare it on first use.
+func (sc *statementCache) get(pool *sql.DB, query string) (*sql.Stmt, error) {
+	sc.mx.Lock()
+	statement, ok := sc.list[query]
+	sc.mx.Unlock()
+	if ok {
+		return statement, nil
+	}
+
+	statement, err := pool.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	sc.mx.Lock()
+	defer sc.mx.Unlock()
+	if existing, ok := sc.list[query]; ok {
+		statement.Close()
+		return existing, nil
+	}
+	if sc.list == nil {
+		sc.list = make(map[string]*sql.Stmt, 64)
+	}
+	sc.list[query] = statement
+	return statement, nil
+}
+
+// Close all cached statements.
+func (sc *statementCache) close() {
+	sc.mx.Lock()
+	defer sc.mx.Unlock()
+	for _, statement := range sc.list {
+		statement.Close()
+	}
+	sc.list = nil
+}
+
+// Return cached statement bound to transaction. Statement not cached prepared for this transaction only.
+func (sc *statementCache) bind(transaction *sql.Tx, query string) (*sql.Stmt, error) {
+	sc.mx.Lock()
+	statement, ok := sc.list[query]
+	sc.mx.Unlock()
+
+	if ok {
+		return transaction.Stmt(statement), nil
+	}
+	return transaction.Prepare(query)
+}
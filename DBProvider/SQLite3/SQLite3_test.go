@@ -0,0 +1,142 @@
+package SQLite3
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+)
+
+func TestTransactionStatementsPrepared(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+
+	for _, query := range transactionStatements {
+		if _, ok := db.writeStatements.list[query]; !ok {
+			t.Errorf("statement not prepared with database '%v'", query)
+		}
+	}
+}
+
+// Statements of transactions must be listed in transactionStatements, not written in place.
+func TestTransactionStatementsListed(t *testing.T) {
+	fileList, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inPlace := regexp.MustCompile("db\\.prepare\\([^,]+, `")
+	for _, fileName := range fileList {
+		if strings.HasSuffix(fileName, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inPlace.Match(data) {
+			t.Errorf("%v prepares statement in place", fileName)
+		}
+	}
+}
+
+func TestBindNotCached(t *testing.T) {
+	db := openTestDB(t, t.TempDir())
+	transaction, err := db.Instance.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transaction.Rollback()
+
+	query := `SELECT count(*) FROM PollerState;`
+	statement, err := db.prepare(transaction, query)
+	if err != nil {
+		t.Fatalf("prepare - %v", err)
+	}
+	var count int
+	err = statement.QueryRow().Scan(&count)
+	if err != nil {
+		t.Fatalf("query - %v", err)
+	}
+	if _, ok := db.writeStatements.list[query]; ok {
+		t.Errorf("statement of transaction cached")
+	}
+}
+
+// Ticket storm: every ticket processed in own goroutine like event processor does,
+// with notification of all team subscribers.
+func BenchmarkTicketStorm(b *testing.B) {
+	const (
+		teams    = 5
+		teamSize = 10 // Subscribers of each team.
+	)
+	db := openTestDB(b, b.TempDir())
+	for team := 0; team < teams; team++ {
+		for member := 0; member < teamSize; member++ {
+			tgID := int64(team*teamSize + member + 1)
+			err := db.BotUserAdd(tgID, "private", "en")
+			if err != nil {
+				b.Fatal(err)
+			}
+			userID, err := db.BotUserGetByTelegramID(tgID)
+			if err != nil {
+				b.Fatal(err)
+			}
+			err = db.SubscriptionListAdd(userID, fmt.Sprintf("Team%v", team))
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	var operations, failures int64
+	count := func(err error) {
+		atomic.AddInt64(&operations, 1)
+		if err != nil {
+			atomic.AddInt64(&failures, 1)
+			b.Error(err)
+		}
+	}
+
+	b.ResetTimer()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for ticket := 1; ticket <= b.N; ticket++ {
+		wg.Add(1)
+		go func(ticketID int64) {
+			defer wg.Done()
+			eventID, _, err := db.OTRSEventCreateOnce("benchmark", "newticket", "benchmark", ticketID, fmt.Sprint("benchmark-", ticketID))
+			count(err)
+			if err != nil {
+				return
+			}
+			count(db.OTRSEventProcessing(eventID))
+			_, _, err = db.OTRSEventSwapSnapshot(eventID, DBProvider.TicketSnapshot{State: "new", Lock: "unlock"})
+			count(err)
+			count(db.OTRSEventSetTicketNumber("benchmark", ticketID, fmt.Sprint(ticketID)))
+
+			userList, err := db.SubscriptionListGetActiveBySubscription(fmt.Sprintf("Team%v", ticketID%teams))
+			count(err)
+			for _, userID := range userList {
+				tgID, err := db.BotUserGetTelegramIDByID(userID)
+				count(err)
+				_, err = db.BotUserGetSettings(tgID)
+				count(err)
+				messageID, err := db.MessageListNewMessage("Telegram", tgID, "benchmark", "benchmark")
+				count(err)
+				count(db.MessageListMarkDelivered(messageID))
+				count(db.EventHistoryAdd(eventID, DBProvider.HistoryNotification, fmt.Sprint(tgID), ""))
+			}
+			count(db.OTRSEventSuspend(eventID, time.Now().Unix()))
+			count(db.OTRSEventEnded(eventID))
+		}(int64(ticket))
+	}
+	wg.Wait()
+	b.ReportMetric(float64(operations)/time.Since(start).Seconds(), "operations/s")
+	b.ReportMetric(float64(failures), "failures")
+}
@@ -0,0 +1,25 @@
+package SQLite3
+
+import (
+	"fmt"
+)
+
+// Record run of scheduled job at unix timestamp for owner if job never run or its last run not after provided time.
+// Check and record done by one statement, so job run by one of instances sharing database.
+// Return false if job already run by any instance.
+func (db *DB) ScheduledJobClaim(job, owner string, run, lastRunNotAfter int64) (bool, error) {
+	result, err := db.exec(`INSERT INTO ScheduledJob(Job, LastRun, Owner) VALUES(?, ?, ?)
+ON CONFLICT(Job) DO UPDATE SET LastRun = excluded.LastRun, Owner = excluded.Owner WHERE LastRun <= ?;`,
+		job, run, owner, lastRunNotAfter)
+	if err != nil {
+		db.Log.Error(fmt.Sprintf("Can't claim run of job '%v' for '%v' - '%v'", job, owner, err))
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	db.Log.Debug(fmt.Sprintf("Run of job '%v' at '%v' claimed by '%v' - '%v'", job, run, owner, count > 0))
+	return count > 0, nil
+}
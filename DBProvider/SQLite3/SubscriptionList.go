@@ -10,24 +10,8 @@ import (
 // If user have no subscriptions return empty slice.
 func (db *DB) SubscriptionListGetActiveByUser(userID int64) ([]string, error) {
 	db.Log.Debug(fmt.Sprintf("Collect subscription list by user '%+v'", userID))
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for scan subscriptions for user '%v' - '%v'", userID, err))
-		return nil, err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for select from table.
-	statement, err := transaction.Prepare(`SELECT Subscription FROM SubscriptionList WHERE UserID = ? AND Active = 1;`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for scan subscriptions for user '%v' - '%v'", userID, err))
-		return nil, err
-	}
-	defer statement.Close()
-
 	// Query active subscription list for user.
-	rows, err := statement.Query(userID)
+	rows, err := db.query(`SELECT Subscription FROM SubscriptionList WHERE UserID = ? AND Active = 1;`, userID)
 	if err != nil {
 		db.Log.Error(fmt.Sprintf("Can't query for scan subscriptions for user '%v' - '%v'", userID, err))
 		return nil, err
@@ -51,13 +35,6 @@ func (db *DB) SubscriptionListGetActiveByUser(userID int64) ([]string, error) {
 		return nil, err
 	}
 
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for subscriptions for user '%v' - '%v'", userID, err))
-		return nil, err
-	}
-
 	db.Log.Debug(fmt.Sprintf("Collected subscription list by user '%+v'", userID))
 	return subscriptionList, nil
 }
@@ -65,24 +42,8 @@ func (db *DB) SubscriptionListGetActiveByUser(userID int64) ([]string, error) {
 // Return list of users with active provided subscription name.
 func (db *DB) SubscriptionListGetActiveBySubscription(subscription string) ([]int64, error) {
 	db.Log.Debug(fmt.Sprintf("Collect users by subscription '%+v'", subscription))
-	// Create new sql transaction.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for scan users by subscription '%v' - '%v'", subscription, err))
-		return nil, err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for select from table.
-	statement, err := transaction.Prepare(`SELECT UserID FROM SubscriptionList WHERE Subscription = ? AND Active = 1;`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for scan users by subscription '%v' - '%v'", subscription, err))
-		return nil, err
-	}
-	defer statement.Close()
-
 	// Query active users list for subscription.
-	rows, err := statement.Query(subscription)
+	rows, err := db.query(`SELECT UserID FROM SubscriptionList WHERE Subscription = ? AND Active = 1;`, subscription)
 	if err != nil {
 		db.Log.Error(fmt.Sprintf("Can't query for scan users by subscription '%v' - '%v'", subscription, err))
 		return nil, err
@@ -106,13 +67,6 @@ func (db *DB) SubscriptionListGetActiveBySubscription(subscription string) ([]in
 		return nil, err
 	}
 
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("While commit for users by subscription '%v' - '%v'", subscription, err))
-		return nil, err
-	}
-
 	db.Log.Debug(fmt.Sprintf("Collected users '%+v'", userList))
 	return userList, nil
 }
@@ -163,36 +117,11 @@ func (db *DB) SubscriptionListAdd(userID int64, newSubscription string) error {
 		}
 	}
 
-	// Create new sql transaction for add new subscription.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for add subscription '%v' for user '%v' - '%v'",
-			newSubscription, userID, err))
-		return err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for add new subscription.
-	statement, err := transaction.Prepare(`INSERT INTO SubscriptionList(Active, Subscription, UserID, Created) VALUES(?, ?, ?, ?);`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for add subscription '%v' for user '%v' - '%v'",
-			newSubscription, userID, err))
-		return err
-	}
-	defer statement.Close()
-
-	// Execute prepared statement
-	_, err = statement.Exec(1, newSubscription, userID, time.Now().Unix())
+	// Execute statement.
+	_, err = db.exec(`INSERT INTO SubscriptionList(Active, Subscription, UserID, Created) VALUES(?, ?, ?, ?);`,
+		1, newSubscription, userID, time.Now().Unix())
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't execute statement for add subscription '%v' for user '%v' - '%v'",
-			newSubscription, userID, err))
-		return err
-	}
-
-	// Close transaction.
-	err = transaction.Commit()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Wile commit transaction for add subscription '%v' for user '%v' - '%v'",
+		db.Log.Error(fmt.Sprintf("Can't add subscription '%v' for user '%v' - '%v'",
 			newSubscription, userID, err))
 		return err
 	}
@@ -219,37 +148,12 @@ func (db *DB) SubscriptionListRemove(userID int64, removeSubscription string) er
 		return myErrors.ErrNotSubscribed
 	}
 
-	// Create new sql transaction for remove subscription.
-	transaction, err := db.Instance.Begin()
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't create transaction for remove subscription '%v' for user '%v' - '%v'",
-			removeSubscription, userID, err))
-		return err
-	}
-	defer transaction.Rollback()
-
-	// Prepare transaction for remove subscription.
-	statement, err := transaction.Prepare(`UPDATE SubscriptionList SET Active = 0, Finished = ?
-WHERE Active = 1 AND Subscription = ? AND UserID = ?;`)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't prepare transaction for remove subscription '%v' for user '%v' - '%v'",
-			removeSubscription, userID, err))
-		return err
-	}
-	defer statement.Close()
-
-	// Execute prepared statement
-	_, err = statement.Exec(time.Now().Unix(), removeSubscription, userID)
-	if err != nil {
-		db.Log.Error(fmt.Sprintf("Can't execute statement for remove subscription '%v' for user '%v' - '%v'",
-			removeSubscription, userID, err))
-		return err
-	}
-
-	// Close transaction.
-	err = transaction.Commit()
+	// Execute statement.
+	_, err = db.exec(`UPDATE SubscriptionList SET Active = 0, Finished = ?
+WHERE Active = 1 AND Subscription = ? AND UserID = ?;`,
+		time.Now().Unix(), removeSubscription, userID)
 	if err != nil {
-		db.Log.Error(fmt.Sprintf("Wile commit transaction for remove subscription '%v' for user '%v' - '%v'",
+		db.Log.Error(fmt.Sprintf("Can't remove subscription '%v' for user '%v' - '%v'",
 			removeSubscription, userID, err))
 		return err
 	}
@@ -17,7 +17,13 @@ create table BotUserList (
 	Phone integer,
 	Email text,
 	Created integer not null,
-	TelegramID integer
+	TelegramID integer,
+	ChatType text,
+	Language text,
+	Timezone text,
+	QuietHours text,
+	DNDUntil integer,
+	NotificationMode text
 );`
 	sqlCreateOTRSEventListTable = `
 create table OTRSEventList (
@@ -29,7 +35,12 @@ create table OTRSEventList (
 	Created integer not null,
 	ActivationInterval integer,
 	NextActivation integer,
-	Finished integer
+	Finished integer,
+	TicketNumber text,
+	Instance text,
+	Snapshot text,
+	Owner text,
+	LeaseUntil integer
 );`
 	sqlCreateSubscriptionListTable = `
 create table SubscriptionList (
@@ -56,12 +67,59 @@ create table MessageList (
 	ChatID text not null,
 	MessageText text not null,
 	Created integer not null,
-	Sent integer
+	Sent integer,
+	DeliverAfter integer,
+	Instance text,
+	Owner text,
+	LeaseUntil integer
 );`
 	sqlCreateClientTeamBoundTable = `
 create table ClientTeamBound (
 	Client text not null primary key,
 	Team text not null
+);`
+	sqlCreateMessageTemplateTable = `
+create table MessageTemplate (
+	Kind text not null,
+	Channel text not null,
+	Body text not null,
+	Updated integer not null,
+	PRIMARY KEY (Kind, Channel)
+);`
+	sqlCreateOTRSEventDeliveryTable = `
+create table OTRSEventDelivery (
+	Key text not null primary key,
+	EventID integer,
+	Created integer not null
+);`
+	sqlCreateEventHistoryTable = `
+create table EventHistory (
+	ID integer not null primary key,
+	EventID integer not null,
+	Created integer not null,
+	Kind text not null,
+	Actor text,
+	Details text
+);`
+	sqlCreateOTRSEventTombstoneTable = `
+create table OTRSEventTombstone (
+	Type text not null,
+	Instance text not null,
+	TicketID integer not null,
+	Removed integer not null,
+	PRIMARY KEY (Type, Instance, TicketID)
+);`
+	sqlCreateScheduledJobTable = `
+create table ScheduledJob (
+	Job text not null primary key,
+	LastRun integer not null,
+	Owner text not null
+);`
+	sqlCreatePollerStateTable = `
+create table PollerState (
+	Instance text not null primary key,
+	Watermark integer not null,
+	Updated integer not null
 );`
 )
 
@@ -76,6 +134,12 @@ func createAllTablesIfNotExist(db *sql.DB, Log logger.Logger) error {
 	tableCreateStatementList["SubscriptionScheduler"] = sqlCreateSubscriptionSchedulerTable
 	tableCreateStatementList["MessageList"] = sqlCreateMessageListTable
 	tableCreateStatementList["ClientTeamBound"] = sqlCreateClientTeamBoundTable
+	tableCreateStatementList["MessageTemplate"] = sqlCreateMessageTemplateTable
+	tableCreateStatementList["OTRSEventDelivery"] = sqlCreateOTRSEventDeliveryTable
+	tableCreateStatementList["PollerState"] = sqlCreatePollerStateTable
+	tableCreateStatementList["EventHistory"] = sqlCreateEventHistoryTable
+	tableCreateStatementList["OTRSEventTombstone"] = sqlCreateOTRSEventTombstoneTable
+	tableCreateStatementList["ScheduledJob"] = sqlCreateScheduledJobTable
 
 	for currentTable, statement := range tableCreateStatementList {
 		tableExist, err := isTableExists(db, Log, currentTable)
@@ -97,3 +161,128 @@ func createAllTablesIfNotExist(db *sql.DB, Log logger.Logger) error {
 	Log.Debug("Sequence createAllTablesIfNotExist successfully finished")
 	return nil
 }
+
+// Indexes created after tables and columns. Statement runs before index creation if index not exists.
+// Prepare and statement run in one transaction, so prepare runs once.
+var indexList = []struct {
+	Name      string
+	Prepare   func(transaction *sql.Tx, Log logger.Logger) error
+	Statement string
+}{
+	{
+		// Events duplicated before index introduced merged into first event.
+		Name: "OTRSEventListUnique",
+		Prepare: func(transaction *sql.Tx, Log logger.Logger) error {
+			return mergeDuplicateEvents(transaction, Log, sqlSelectDuplicateEvents)
+		},
+		Statement: `create unique index OTRSEventListUnique on OTRSEventList (Channel, Type, Instance, TicketID);`,
+	},
+	{
+		Name:      "OTRSEventListCreated",
+		Statement: `create index OTRSEventListCreated on OTRSEventList (Type, Created);`,
+	},
+	{
+		Name:      "EventHistoryEventID",
+		Statement: `create index EventHistoryEventID on EventHistory (EventID);`,
+	},
+}
+
+// If indexes don't exist create them.
+func createAllIndexesIfNotExist(db *sql.DB, Log logger.Logger) error {
+	Log.Debug("Start createAllIndexesIfNotExist sequence")
+
+	for _, index := range indexList {
+		var count int
+		err := db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = ?;`, index.Name).Scan(&count)
+		if err != nil {
+			Log.Error(fmt.Sprintf("Can't search index '%v' in master table - '%v'", index.Name, err))
+			return err
+		}
+		if count > 0 {
+			Log.Debug(fmt.Sprintf("Index '%v' exists", index.Name))
+			continue
+		}
+		Log.Info(fmt.Sprintf("Index '%v' not exists. Create it", index.Name))
+
+		err = createIndex(db, Log, index.Name, index.Prepare, index.Statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	Log.Debug("Sequence createAllIndexesIfNotExist successfully finished")
+	return nil
+}
+
+// Prepare data and create index in one transaction.
+func createIndex(db *sql.DB, Log logger.Logger, name string, prepare func(*sql.Tx, logger.Logger) error, statement string) error {
+	transaction, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+
+	if prepare != nil {
+		err = prepare(transaction, Log)
+		if err != nil {
+			Log.Error(fmt.Sprintf("Can't prepare data for index '%v' - '%v'", name, err))
+			return err
+		}
+	}
+	_, err = transaction.Exec(statement)
+	if err != nil {
+		Log.Error(fmt.Sprintf("Can't create index '%v' - '%v'", name, err))
+		return err
+	}
+
+	return transaction.Commit()
+}
+
+// Add columns introduced after table creation.
+// New columns always nullable so "alter table" can add them to filled tables.
+func addMissingColumns(db *sql.DB, Log logger.Logger) error {
+	Log.Debug("Start addMissingColumns sequence")
+
+	for tableName, tableInfo := range getValidTableInfo() {
+		existingColumns, err := getColumnNames(db, tableName)
+		if err != nil {
+			Log.Error(fmt.Sprintf("Can't get columns for table '%v' - '%v'", tableName, err))
+			return err
+		}
+		for _, column := range tableInfo {
+			if existingColumns[column.Name] {
+				continue
+			}
+			Log.Info(fmt.Sprintf("Column '%v' not exists in table '%v'. Add it", column.Name, tableName))
+			statement := fmt.Sprintf("alter table %s add column %s %s;", tableName, column.Name, column.Type)
+			err = executeStatement(db, statement)
+			if err != nil {
+				Log.Error(fmt.Sprintf("Can't add column '%v' into table '%v' - '%v'", column.Name, tableName, err))
+				return err
+			}
+		}
+	}
+
+	Log.Debug("Sequence addMissingColumns successfully finished")
+	return nil
+}
+
+// Return set of column names for provided table.
+func getColumnNames(db *sql.DB, tableName string) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("pragma table_info(%s);", tableName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnNames := make(map[string]bool, 16)
+	column := columnInfo{}
+	for rows.Next() {
+		err = rows.Scan(&column.CID, &column.Name, &column.Type, &column.NotNULL, &column.DefaultValue, &column.PrimaryKey)
+		if err != nil {
+			return nil, err
+		}
+		columnNames[column.Name] = true
+	}
+	return columnNames, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"strings"
 )
 
 // Used in table validation process.
@@ -118,6 +119,8 @@ func isTableValid(db *sql.DB, Log logger.Logger, tableName string, tableInfo []c
 			Log.Error(fmt.Sprintf("While iteration for '%s' table info - '%v'", tableName, err))
 			return false, err
 		}
+		// Depending on version SQLite may return declared type in upper case.
+		currentColumnInfo.Type = strings.ToLower(currentColumnInfo.Type)
 		if currentColumnInfo.CID >= int64(len(tableInfo)) {
 			Log.Error(fmt.Sprintf("More columns then expected while iteration for '%s' table info", tableName))
 			return false, nil
@@ -161,6 +164,12 @@ func getValidTableInfo() map[string][]columnInfo {
 		columnInfo{CID: 6, Name: "Email", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
 		columnInfo{CID: 7, Name: "Created", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
 		columnInfo{CID: 8, Name: "TelegramID", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 9, Name: "ChatType", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 10, Name: "Language", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 11, Name: "Timezone", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 12, Name: "QuietHours", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 13, Name: "DNDUntil", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 14, Name: "NotificationMode", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
 	)
 	result["BotUserList"] = tmpTableInfo
 
@@ -176,6 +185,11 @@ func getValidTableInfo() map[string][]columnInfo {
 		columnInfo{CID: 6, Name: "ActivationInterval", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
 		columnInfo{CID: 7, Name: "NextActivation", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
 		columnInfo{CID: 8, Name: "Finished", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 9, Name: "TicketNumber", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 10, Name: "Instance", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 11, Name: "Snapshot", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 12, Name: "Owner", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 13, Name: "LeaseUntil", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
 	)
 	result["OTRSEventList"] = tmpTableInfo
 
@@ -210,6 +224,10 @@ func getValidTableInfo() map[string][]columnInfo {
 		columnInfo{CID: 3, Name: "MessageText", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
 		columnInfo{CID: 4, Name: "Created", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
 		columnInfo{CID: 5, Name: "Sent", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 6, Name: "DeliverAfter", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 7, Name: "Instance", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 8, Name: "Owner", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 9, Name: "LeaseUntil", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
 	)
 	result["MessageList"] = tmpTableInfo
 
@@ -221,5 +239,64 @@ func getValidTableInfo() map[string][]columnInfo {
 	)
 	result["ClientTeamBound"] = tmpTableInfo
 
+	//MessageTemplate
+	tmpTableInfo = make([]columnInfo, 0, 16)
+	tmpTableInfo = append(tmpTableInfo,
+		columnInfo{CID: 0, Name: "Kind", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 1},
+		columnInfo{CID: 1, Name: "Channel", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 2},
+		columnInfo{CID: 2, Name: "Body", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 3, Name: "Updated", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+	)
+	result["MessageTemplate"] = tmpTableInfo
+
+	//OTRSEventDelivery
+	tmpTableInfo = make([]columnInfo, 0, 16)
+	tmpTableInfo = append(tmpTableInfo,
+		columnInfo{CID: 0, Name: "Key", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 1},
+		columnInfo{CID: 1, Name: "EventID", Type: "integer", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 2, Name: "Created", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+	)
+	result["OTRSEventDelivery"] = tmpTableInfo
+
+	//EventHistory
+	tmpTableInfo = make([]columnInfo, 0, 16)
+	tmpTableInfo = append(tmpTableInfo,
+		columnInfo{CID: 0, Name: "ID", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 1},
+		columnInfo{CID: 1, Name: "EventID", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 2, Name: "Created", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 3, Name: "Kind", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 4, Name: "Actor", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 5, Name: "Details", Type: "text", NotNULL: 0, DefaultValue: nil, PrimaryKey: 0},
+	)
+	result["EventHistory"] = tmpTableInfo
+
+	//PollerState
+	tmpTableInfo = make([]columnInfo, 0, 16)
+	tmpTableInfo = append(tmpTableInfo,
+		columnInfo{CID: 0, Name: "Instance", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 1},
+		columnInfo{CID: 1, Name: "Watermark", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 2, Name: "Updated", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+	)
+	result["PollerState"] = tmpTableInfo
+
+	//OTRSEventTombstone
+	tmpTableInfo = make([]columnInfo, 0, 16)
+	tmpTableInfo = append(tmpTableInfo,
+		columnInfo{CID: 0, Name: "Type", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 1},
+		columnInfo{CID: 1, Name: "Instance", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 2},
+		columnInfo{CID: 2, Name: "TicketID", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 3},
+		columnInfo{CID: 3, Name: "Removed", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+	)
+	result["OTRSEventTombstone"] = tmpTableInfo
+
+	//ScheduledJob
+	tmpTableInfo = make([]columnInfo, 0, 16)
+	tmpTableInfo = append(tmpTableInfo,
+		columnInfo{CID: 0, Name: "Job", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 1},
+		columnInfo{CID: 1, Name: "LastRun", Type: "integer", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+		columnInfo{CID: 2, Name: "Owner", Type: "text", NotNULL: 1, DefaultValue: nil, PrimaryKey: 0},
+	)
+	result["ScheduledJob"] = tmpTableInfo
+
 	return result
 }
@@ -3,63 +3,70 @@ package Formatter
 import (
 	"fmt"
 	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/locale"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
-	"log"
 	"time"
 )
 
-const OTRSLayout string = "2006-01-02 15:04:05 MST" // Time layout for parsing detailed info from OTRS.
-
-func EventNewPlainText(ticket OTRSProvider.TicketOTRS) string {
-	return fmt.Sprint( // Ticket information formatting
-		"NEW ",
-		ticket.CustomerID,
-		"   ",
-		ticket.Type,
-		"\n",
-		"Ticket ",
-		ticket.TicketNumber,
-		"\n",
-		ticket.Title,
-		"\n",
-		ticket.URL,
-	)
-}
+const (
+	ReminderInterval time.Duration = 5 * time.Minute        // Interval between reminders. Used for escalation level.
+	DisplayLayout    string        = "2006-01-02 15:04 MST" // Layout for absolute time in messages.
+)
 
-func EventReminderPlainText(ticket OTRSProvider.TicketOTRS, logger logger.Logger) string {
+// Prepare template data for ticket with computed age and escalation level in default language.
+func NewTemplateData(ticket OTRSProvider.TicketOTRS, logger logger.Logger) TemplateData {
 	logger = logger.SetModuleName("Message formatter")
-	age := ageCalculation(ticket.Created, logger)
-	return fmt.Sprint( // Ticket information formatting
-		"UP ",
+	data := TemplateData{TicketOTRS: ticket}
+	age, ok := ageCalculation(ticket.CreatedTime)
+	if ok {
+		data.ageKnown = true
+		data.AgeDuration = age
+		data.AgeMinutes = int64(age.Minutes())
+		data.EscalationLevel = int(age / ReminderInterval)
+	}
+	logger.Debug(fmt.Sprintf(
+		"Age calculation. String - '%+v'. Parsed time - '%+v'. Calculated duration - '%+v'.",
+		ticket.Created,
+		ticket.CreatedTime,
 		age,
-		" мин.   ",
-		ticket.CustomerID,
-		"   ",
-		ticket.Type,
-		"\n",
-		"Ticket ",
-		ticket.TicketNumber,
-		"\n",
-		ticket.Title,
-		"\n",
-		ticket.URL,
-	)
+	))
+	return data.Localise(locale.Default, nil)
 }
 
-// Return current ticket age in minutes.
-func ageCalculation(absoluteAge string, logger logger.Logger) string {
-	created, err := time.Parse(OTRSLayout, fmt.Sprint(absoluteAge, " MSK")) // Add timezone.
-	if err != nil {
-		log.Println("Can't parse age")
-		return "UNKNOWN"
+// Return copy of data with language and timezone dependent fields for recipient.
+// If location is nil OTRS server timezone used.
+func (data TemplateData) Localise(language string, location *time.Location) TemplateData {
+	if !locale.IsSupported(language) {
+		language = locale.Default
+	}
+	data.Language = language
+	data.Age = catalog.Text(language, "unknownAge")
+	data.CreatedLocal = data.Created
+	if data.ageKnown {
+		data.Age = locale.ShortDuration(language, data.AgeDuration)
 	}
+	if !data.CreatedTime.IsZero() {
+		created := data.CreatedTime
+		if location != nil {
+			created = created.In(location)
+		}
+		data.CreatedLocal = created.Format(DisplayLayout)
+	}
+	if data.Tickets != nil {
+		tickets := make([]TemplateData, len(data.Tickets))
+		for i, ticket := range data.Tickets {
+			tickets[i] = ticket.Localise(language, location)
+		}
+		data.Tickets = tickets
+	}
+	return data
+}
 
-	since := time.Since(created).Minutes()
-	logger.Debug(fmt.Sprintf(
-		"Age calculation. String - '%+v'. Parsed time - '%+v'. Calculated duration - '%+v'.",
-		absoluteAge,
-		created,
-		since,
-	))
-	return fmt.Sprint(uint64(since))
+// Return current ticket age.
+// Return false if ticket creation time not parsed by OTRS provider.
+func ageCalculation(created time.Time) (time.Duration, bool) {
+	if created.IsZero() {
+		return 0, false
+	}
+	return time.Since(created), true
 }
@@ -0,0 +1,23 @@
+package Formatter
+
+import "github.com/Sarraksh/otrs-echo-bot/common/locale"
+
+// Texts for built-in templates. Available in templates with "tr" function, e.g. {{tr .Language "ticket"}}.
+var catalog = locale.Catalog{
+	locale.Russian: {
+		"ticket":      "Заявка",
+		"openInOTRS":  "Открыть в OTRS",
+		"openTickets": "ОТКРЫТЫЕ ЗАЯВКИ",
+		"locked":      "ВЗЯТА",
+		"unknownAge":  "неизвестно",
+		"created":     "Создана",
+	},
+	locale.English: {
+		"ticket":      "Ticket",
+		"openInOTRS":  "Open in OTRS",
+		"openTickets": "OPEN TICKETS",
+		"locked":      "LOCKED",
+		"unknownAge":  "UNKNOWN",
+		"created":     "Created",
+	},
+}
@@ -0,0 +1,77 @@
+package Formatter
+
+// Built-in templates. Used if template for kind and channel not found in templates directory or DB.
+// Telegram templates use markup functions so same text works for any parse mode.
+var defaultTemplateList = map[string]string{
+	templateKey(KindNew, ChannelPlain): `NEW {{.CustomerID}}   {{.Type}}
+{{tr .Language "ticket"}} {{.TicketNumber}}{{if .Instance}} [{{.Instance}}]{{end}}
+{{.Title}}
+{{.URL}}`,
+	templateKey(KindNew, ChannelTelegram): `{{emoji .Priority}} {{bold "NEW"}} {{escape .CustomerID}}   {{escape .Type}}
+{{escape (tr .Language "ticket")}} {{code .TicketNumber}}{{if .Instance}} {{escape (printf "[%v]" .Instance)}}{{end}}
+{{escape .Title}}
+{{link .URL (tr .Language "openInOTRS")}}`,
+
+	templateKey(KindReminder, ChannelPlain): `UP {{.Age}}   {{.CustomerID}}   {{.Type}}
+{{tr .Language "ticket"}} {{.TicketNumber}}{{if .Instance}} [{{.Instance}}]{{end}}
+{{.Title}}
+{{tr .Language "created"}} {{.CreatedLocal}}
+{{.URL}}`,
+	templateKey(KindReminder, ChannelTelegram): `{{emoji .Priority}} {{bold (printf "UP %v" .Age)}}   {{escape .CustomerID}}   {{escape .Type}}
+{{escape (tr .Language "ticket")}} {{code .TicketNumber}}{{if .Instance}} {{escape (printf "[%v]" .Instance)}}{{end}}
+{{escape .Title}}
+{{escape (tr .Language "created")}} {{escape .CreatedLocal}}
+{{link .URL (tr .Language "openInOTRS")}}`,
+
+	templateKey(KindEscalation, ChannelPlain): `ESCALATION {{.EscalationLevel}}   UP {{.Age}}   {{.CustomerID}}   {{.Type}}
+{{tr .Language "ticket"}} {{.TicketNumber}}{{if .Instance}} [{{.Instance}}]{{end}}
+{{.Title}}
+{{tr .Language "created"}} {{.CreatedLocal}}
+{{.URL}}`,
+	templateKey(KindEscalation, ChannelTelegram): `{{emoji .Priority}} {{bold (printf "ESCALATION %v" .EscalationLevel)}} {{escape (printf "UP %v" .Age)}}   {{escape .CustomerID}}   {{escape .Type}}
+{{escape (tr .Language "ticket")}} {{code .TicketNumber}}{{if .Instance}} {{escape (printf "[%v]" .Instance)}}{{end}}
+{{escape .Title}}
+{{escape (tr .Language "created")}} {{escape .CreatedLocal}}
+{{link .URL (tr .Language "openInOTRS")}}`,
+
+	templateKey(KindTaken, ChannelPlain): `TAKEN {{.CustomerID}}   {{.Type}}
+{{tr .Language "ticket"}} {{.TicketNumber}}{{if .Instance}} [{{.Instance}}]{{end}}
+{{.Title}}
+{{.URL}}`,
+	templateKey(KindTaken, ChannelTelegram): `✅ {{bold "TAKEN"}} {{escape .CustomerID}}   {{escape .Type}}
+{{escape (tr .Language "ticket")}} {{code .TicketNumber}}{{if .Instance}} {{escape (printf "[%v]" .Instance)}}{{end}}
+{{escape .Title}}
+{{link .URL (tr .Language "openInOTRS")}}`,
+
+	templateKey(KindClosed, ChannelPlain): `CLOSED {{.CustomerID}}   {{.Type}}
+{{tr .Language "ticket"}} {{.TicketNumber}}{{if .Instance}} [{{.Instance}}]{{end}}
+{{.Title}}
+{{.URL}}`,
+	templateKey(KindClosed, ChannelTelegram): `✅ {{bold "CLOSED"}} {{escape .CustomerID}}   {{escape .Type}}
+{{escape (tr .Language "ticket")}} {{code .TicketNumber}}{{if .Instance}} {{escape (printf "[%v]" .Instance)}}{{end}}
+{{escape .Title}}
+{{link .URL (tr .Language "openInOTRS")}}`,
+
+	templateKey(KindMerged, ChannelPlain): `MERGED {{.CustomerID}}   {{.Type}}
+{{tr .Language "ticket"}} {{.TicketNumber}}{{if .Instance}} [{{.Instance}}]{{end}}
+{{.Title}}
+{{.URL}}`,
+	templateKey(KindMerged, ChannelTelegram): `✅ {{bold "MERGED"}} {{escape .CustomerID}}   {{escape .Type}}
+{{escape (tr .Language "ticket")}} {{code .TicketNumber}}{{if .Instance}} {{escape (printf "[%v]" .Instance)}}{{end}}
+{{escape .Title}}
+{{link .URL (tr .Language "openInOTRS")}}`,
+
+	templateKey(KindDigest, ChannelPlain): `{{tr .Language "openTickets"}} {{len .Tickets}}{{if .Team}}   {{.Team}}{{end}}
+{{range .Tickets}}
+{{.Priority}}   UP {{.Age}}   {{.CustomerID}}{{if eq .Lock "lock"}}   {{tr .Language "locked"}}{{end}}
+{{tr .Language "ticket"}} {{.TicketNumber}}{{if .Instance}} [{{.Instance}}]{{end}}
+{{.Title}}
+{{.URL}}
+{{end}}`,
+	templateKey(KindDigest, ChannelTelegram): `{{bold (printf "%v %v" (tr .Language "openTickets") (len .Tickets))}}{{if .Team}}   {{escape .Team}}{{end}}
+{{range .Tickets}}
+{{emoji .Priority}} {{code .TicketNumber}}{{if .Instance}} {{escape (printf "[%v]" .Instance)}}{{end}} {{escape (printf "UP %v" .Age)}}   {{escape .CustomerID}}{{if eq .Lock "lock"}} 🔒{{end}}
+{{escape .Title}}
+{{link .URL (tr .Language "openInOTRS")}}
+{{end}}`,
+}
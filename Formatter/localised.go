@@ -0,0 +1,66 @@
+package Formatter
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/locale"
+	"sync"
+	"time"
+)
+
+// Message rendered on demand for recipient language and timezone.
+// Each combination rendered once. Safe for concurrent use.
+type LocalisedMessage struct {
+	kind     string
+	data     TemplateData
+	fallback Message // Message in default language. Also used if message can't be rendered.
+	mx       sync.Mutex
+	rendered map[string]Message
+}
+
+// Render message for kind in default language. Other languages and timezones rendered on demand.
+func RenderLocalised(kind string, data TemplateData) (*LocalisedMessage, error) {
+	message, err := Render(kind, data.Localise(locale.Default, nil))
+	if err != nil {
+		return nil, err
+	}
+	return &LocalisedMessage{
+		kind:     kind,
+		data:     data,
+		fallback: message,
+		rendered: make(map[string]Message),
+	}, nil
+}
+
+// Wrap message which is same for every recipient.
+func FixedMessage(message Message) *LocalisedMessage {
+	return &LocalisedMessage{fallback: message}
+}
+
+// Return message in default language and OTRS server timezone.
+func (m *LocalisedMessage) Default() Message {
+	return m.fallback
+}
+
+// Return message for recipient language and timezone. Nil location means OTRS server timezone.
+// Message in default language returned with error if message can't be rendered.
+func (m *LocalisedMessage) For(language string, location *time.Location) (Message, error) {
+	if m.kind == "" {
+		return m.fallback, nil
+	}
+	key := language
+	if location != nil {
+		key = fmt.Sprint(language, " ", location)
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if message, ok := m.rendered[key]; ok {
+		return message, nil
+	}
+	message, err := Render(m.kind, m.data.Localise(language, location))
+	if err != nil {
+		return m.fallback, err
+	}
+	m.rendered[key] = message
+	return message, nil
+}
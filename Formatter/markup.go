@@ -0,0 +1,100 @@
+package Formatter
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Telegram parse modes.
+const (
+	ParseModePlain      string = ""
+	ParseModeHTML       string = "HTML"
+	ParseModeMarkdownV2 string = "MarkdownV2"
+)
+
+// Formatted message with plain text fallback.
+type Message struct {
+	Text      string // Text with markup.
+	ParseMode string // Telegram parse mode for Text. Empty for plain text.
+	PlainText string // Sent instead of Text if markup rejected by Telegram.
+
+	Keyboard [][]Button // Rows of inline buttons under message. No buttons if empty.
+}
+
+// Inline button under message. Data returned to bot when button pressed.
+type Button struct {
+	Text string
+	Data string
+}
+
+// Wrap plain text into message without markup.
+func PlainMessage(text string) Message {
+	return Message{Text: text, ParseMode: ParseModePlain, PlainText: text}
+}
+
+// Return emoji by OTRS priority. OTRS priority names start with number from 1 (very low) to 5 (very high).
+func PriorityEmoji(priority string) string {
+	switch {
+	case strings.HasPrefix(priority, "5"):
+		return "🔴"
+	case strings.HasPrefix(priority, "4"):
+		return "🟠"
+	case strings.HasPrefix(priority, "3"):
+		return "🟡"
+	case strings.HasPrefix(priority, "2"):
+		return "🟢"
+	default:
+		return "⚪"
+	}
+}
+
+// Markup rules for one parse mode. All arguments are raw user controlled text.
+type markup struct {
+	escape func(text string) string
+	bold   func(text string) string
+	code   func(text string) string
+	link   func(url, text string) string
+}
+
+func markupFor(parseMode string) markup {
+	switch parseMode {
+	case ParseModePlain:
+		return markup{
+			escape: func(text string) string { return text },
+			bold:   func(text string) string { return text },
+			code:   func(text string) string { return text },
+			link:   func(url, text string) string { return url },
+		}
+	case ParseModeMarkdownV2:
+		return markup{
+			escape: EscapeMarkdownV2,
+			bold:   func(text string) string { return fmt.Sprint("*", EscapeMarkdownV2(text), "*") },
+			code:   func(text string) string { return fmt.Sprint("`", markdownV2Code.Replace(text), "`") },
+			link: func(url, text string) string {
+				return fmt.Sprint("[", EscapeMarkdownV2(text), "](", markdownV2Link.Replace(url), ")")
+			},
+		}
+	}
+	return markup{
+		escape: html.EscapeString,
+		bold:   func(text string) string { return fmt.Sprint("<b>", html.EscapeString(text), "</b>") },
+		code:   func(text string) string { return fmt.Sprint("<code>", html.EscapeString(text), "</code>") },
+		link: func(url, text string) string {
+			return fmt.Sprint(`<a href="`, html.EscapeString(url), `">`, html.EscapeString(text), "</a>")
+		},
+	}
+}
+
+// Characters reserved in MarkdownV2 must be escaped everywhere outside code and links.
+var markdownV2Text = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+var markdownV2Code = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+var markdownV2Link = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// Escape text for Telegram MarkdownV2.
+func EscapeMarkdownV2(text string) string {
+	return markdownV2Text.Replace(text)
+}
@@ -0,0 +1,68 @@
+package Formatter
+
+import (
+	"testing"
+)
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	// Every character reserved by Telegram MarkdownV2 and backslash itself.
+	for _, char := range "_*[]()~`>#+-=|{}.!\\" {
+		t.Run(string(char), func(t *testing.T) {
+			text := "a" + string(char) + "b"
+			want := "a\\" + string(char) + "b"
+			if escaped := EscapeMarkdownV2(text); escaped != want {
+				t.Errorf("EscapeMarkdownV2(%q) = %q, want %q", text, escaped, want)
+			}
+		})
+	}
+
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain text", "Printer on fire", "Printer on fire"},
+		{"cyrillic", "Принтер горит", "Принтер горит"},
+		{"ticket number", "Ticket#2024-01.1", `Ticket\#2024\-01\.1`},
+		{"escaped backslash", `\_`, `\\\_`},
+		{"repeated", "**", `\*\*`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if escaped := EscapeMarkdownV2(c.text); escaped != c.want {
+				t.Errorf("EscapeMarkdownV2(%q) = %q, want %q", c.text, escaped, c.want)
+			}
+		})
+	}
+}
+
+func TestMarkupEscaping(t *testing.T) {
+	cases := []struct {
+		name      string
+		parseMode string
+		apply     func(m markup) string
+		want      string
+	}{
+		{"HTML escape", ParseModeHTML, func(m markup) string { return m.escape(`<b>&"'`) }, "&lt;b&gt;&amp;&#34;&#39;"},
+		{"HTML bold", ParseModeHTML, func(m markup) string { return m.bold("a<b") }, "<b>a&lt;b</b>"},
+		{"HTML code", ParseModeHTML, func(m markup) string { return m.code("x>1 && y<2") }, "<code>x&gt;1 &amp;&amp; y&lt;2</code>"},
+		{"HTML link", ParseModeHTML, func(m markup) string { return m.link(`http://otrs/?a=1&b="2"`, "<T>") },
+			`<a href="http://otrs/?a=1&amp;b=&#34;2&#34;">&lt;T&gt;</a>`},
+		{"MarkdownV2 bold", ParseModeMarkdownV2, func(m markup) string { return m.bold("a*b") }, `*a\*b*`},
+		// Only backslash and backtick reserved inside code.
+		{"MarkdownV2 code", ParseModeMarkdownV2, func(m markup) string { return m.code("a_b`c\\d") }, "`a_b\\`c\\\\d`"},
+		// Only backslash and closing parenthesis reserved inside link URL.
+		{"MarkdownV2 link", ParseModeMarkdownV2, func(m markup) string { return m.link(`http://otrs/(1)_x`, "T.1") },
+			`[T\.1](http://otrs/(1\)_x)`},
+		{"plain escape", ParseModePlain, func(m markup) string { return m.escape("<*>&") }, "<*>&"},
+		{"plain link", ParseModePlain, func(m markup) string { return m.link("http://otrs/1", "T") }, "http://otrs/1"},
+		{"unknown mode as HTML", "Markdown", func(m markup) string { return m.escape("<") }, "&lt;"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if result := c.apply(markupFor(c.parseMode)); result != c.want {
+				t.Errorf("got %q, want %q", result, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package Formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/locale"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Message kinds. Each kind has own template.
+const (
+	KindNew        string = "new"
+	KindReminder   string = "reminder"
+	KindEscalation string = "escalation"
+	KindTaken      string = "taken"
+	KindClosed     string = "closed"
+	KindMerged     string = "merged"
+	KindDigest     string = "digest"
+)
+
+// Template channels.
+const (
+	ChannelTelegram string = "telegram" // Telegram message with markup in configured parse mode.
+	ChannelPlain    string = "plain"    // Plain text. Used as markup fallback and in message history.
+	ChannelWebhook  string = "webhook"  // Outbound webhook body.
+)
+
+// Template file name is "<kind>.<channel>.tmpl".
+const TemplateFileExtension string = ".tmpl"
+
+var KindList = []string{KindNew, KindReminder, KindEscalation, KindTaken, KindClosed, KindMerged, KindDigest}
+var ChannelList = []string{ChannelTelegram, ChannelPlain, ChannelWebhook}
+
+// Data available in templates. All ticket fields available directly, e.g. {{.TicketNumber}}.
+type TemplateData struct {
+	OTRSProvider.TicketOTRS
+	Kind            string         // Message kind.
+	Team            string         // Team bounded with ticket client. Empty if not bounded or unknown.
+	Reason          string         // Finish reason for closure messages.
+	Language        string         // Language of recipient. Used with "tr" and "duration" functions.
+	CreatedLocal    string         // Ticket creation time in recipient timezone.
+	Age             string         // Short human readable ticket age in recipient language, e.g. "1 h 25 min".
+	AgeDuration     time.Duration  // Ticket age.
+	AgeMinutes      int64          // Ticket age in minutes.
+	EscalationLevel int            // Number of reminder intervals passed since ticket creation.
+	Tickets         []TemplateData // Tickets for digest.
+
+	ageKnown bool // False if ticket creation time can't be parsed.
+}
+
+// Parsed templates by "<kind>.<channel>" key.
+type templateSet struct {
+	mx        sync.RWMutex
+	parseMode string
+	templates map[string]*template.Template
+}
+
+// Templates used by render functions. Built-in templates until LoadTemplates called.
+var templates = &templateSet{parseMode: ParseModeHTML, templates: make(map[string]*template.Template)}
+
+func init() {
+	for key, text := range defaultTemplateList {
+		parsed, err := parseTemplate(key, text, ParseModeHTML)
+		if err != nil {
+			panic(fmt.Sprintf("invalid built-in template '%v' - '%v'", key, err))
+		}
+		templates.templates[key] = parsed
+	}
+}
+
+// Load templates with priority DB, templates directory, built-in.
+// All templates validated by rendering against sample ticket. Return error if any template invalid.
+func LoadTemplates(directory string, db *DBProvider.DBProvider, parseMode string, logger logger.Logger) error {
+	logger = logger.SetModuleName("Message formatter")
+	logger.Debug(fmt.Sprintf("Load templates from directory '%v' and DB", directory))
+
+	sourceList := make(map[string]string, len(defaultTemplateList))
+	for key, text := range defaultTemplateList {
+		sourceList[key] = text
+	}
+
+	// Read templates directory. Missing directory means no custom templates.
+	fileList, err := ioutil.ReadDir(directory)
+	if err != nil && !os.IsNotExist(err) {
+		logger.Error(fmt.Sprintf("Can't read templates directory '%v' - '%v'", directory, err))
+		return err
+	}
+	for _, file := range fileList {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), TemplateFileExtension) {
+			continue
+		}
+		key := strings.TrimSuffix(file.Name(), TemplateFileExtension)
+		if !isKnownKey(key) {
+			logger.Warning(fmt.Sprintf("Skip template file '%v' with unknown kind or channel", file.Name()))
+			continue
+		}
+		text, err := ioutil.ReadFile(filepath.Join(directory, file.Name()))
+		if err != nil {
+			logger.Error(fmt.Sprintf("Can't read template file '%v' - '%v'", file.Name(), err))
+			return err
+		}
+		sourceList[key] = strings.TrimRight(string(text), "\n")
+		logger.Debug(fmt.Sprintf("Template '%v' loaded from file", key))
+	}
+
+	// Templates stored in DB override files.
+	dbTemplateList, err := (*db).MessageTemplateGetAll()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Can't get templates from DB - '%v'", err))
+		return err
+	}
+	for _, dbTemplate := range dbTemplateList {
+		key := templateKey(dbTemplate.Kind, dbTemplate.Channel)
+		if !isKnownKey(key) {
+			logger.Warning(fmt.Sprintf("Skip template from DB with unknown kind '%v' or channel '%v'", dbTemplate.Kind, dbTemplate.Channel))
+			continue
+		}
+		sourceList[key] = dbTemplate.Body
+		logger.Debug(fmt.Sprintf("Template '%v' loaded from DB", key))
+	}
+
+	// Parse and validate all templates before replace current.
+	parsedList := make(map[string]*template.Template, len(sourceList))
+	for key, text := range sourceList {
+		parsed, err := parseTemplate(key, text, parseMode)
+		if err != nil {
+			logger.Error(fmt.Sprintf("Template '%v' invalid - '%v'", key, err))
+			return err
+		}
+		err = parsed.Execute(ioutil.Discard, sampleData(kindFromKey(key)))
+		if err != nil {
+			logger.Error(fmt.Sprintf("Template '%v' can't be rendered for sample ticket - '%v'", key, err))
+			return err
+		}
+		parsedList[key] = parsed
+	}
+
+	templates.mx.Lock()
+	templates.parseMode = parseMode
+	templates.templates = parsedList
+	templates.mx.Unlock()
+
+	logger.Debug(fmt.Sprintf("Loaded '%v' templates", len(parsedList)))
+	return nil
+}
+
+// Render Telegram message for kind. Plain template used as fallback text.
+// If Telegram template not exists plain text sent without markup.
+func Render(kind string, data TemplateData) (Message, error) {
+	data.Kind = kind
+	plainText, ok, err := RenderChannel(kind, ChannelPlain, data)
+	if err != nil {
+		return Message{}, err
+	}
+	if !ok {
+		return Message{}, fmt.Errorf("%w '%v'", myErrors.ErrTemplateNotFound, templateKey(kind, ChannelPlain))
+	}
+
+	templates.mx.RLock()
+	parseMode := templates.parseMode
+	templates.mx.RUnlock()
+	if parseMode == ParseModePlain {
+		return PlainMessage(plainText), nil
+	}
+
+	text, ok, err := RenderChannel(kind, ChannelTelegram, data)
+	if err != nil {
+		return Message{}, err
+	}
+	if !ok {
+		return PlainMessage(plainText), nil
+	}
+	return Message{Text: text, ParseMode: parseMode, PlainText: plainText}, nil
+}
+
+// Render template for kind and channel. Return false if template not exists.
+func RenderChannel(kind, channel string, data TemplateData) (string, bool, error) {
+	templates.mx.RLock()
+	parsed, ok := templates.templates[templateKey(kind, channel)]
+	templates.mx.RUnlock()
+	if !ok {
+		return "", false, nil
+	}
+
+	data.Kind = kind
+	var text bytes.Buffer
+	err := parsed.Execute(&text, data)
+	if err != nil {
+		return "", true, err
+	}
+	return text.String(), true, nil
+}
+
+// Render template for kind and channel against sample ticket in provided language.
+func Preview(kind, channel, language string) (Message, error) {
+	if !isKnownKey(templateKey(kind, channel)) {
+		return Message{}, fmt.Errorf("%w '%v'", myErrors.ErrTemplateNotFound, templateKey(kind, channel))
+	}
+	data := sampleData(kind).Localise(language, nil)
+	if channel == ChannelTelegram {
+		return Render(kind, data)
+	}
+
+	text, ok, err := RenderChannel(kind, channel, data)
+	if err != nil {
+		return Message{}, err
+	}
+	if !ok {
+		return Message{}, fmt.Errorf("%w '%v'", myErrors.ErrTemplateNotFound, templateKey(kind, channel))
+	}
+	return PlainMessage(text), nil
+}
+
+// Parse template with functions for channel.
+func parseTemplate(key, text, parseMode string) (*template.Template, error) {
+	if channelFromKey(key) != ChannelTelegram {
+		parseMode = ParseModePlain
+	}
+	return template.New(key).Funcs(templateFunctions(parseMode)).Parse(text)
+}
+
+// Functions available in templates. Markup functions escape their arguments.
+func templateFunctions(parseMode string) template.FuncMap {
+	m := markupFor(parseMode)
+	return template.FuncMap{
+		"escape":   m.escape,
+		"bold":     m.bold,
+		"code":     m.code,
+		"link":     m.link,
+		"emoji":    PriorityEmoji,
+		"tr":       catalog.Text,
+		"duration": locale.Duration,
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+}
+
+// Ticket with all fields filled for validation and preview.
+func sampleData(kind string) TemplateData {
+	created := time.Now().Add(-95 * time.Minute)
+	ticket := TemplateData{
+		TicketOTRS: OTRSProvider.TicketOTRS{
+			TicketNumber: "2021010110000017",
+			Type:         "Incident",
+			CustomerID:   "ACME",
+			Priority:     "4 high",
+			Created:      created.Format(OTRSProvider.TimeLayout),
+			CreatedTime:  created,
+			Title:        "Mail server <mx1> is not responding & queue grows",
+			Lock:         "unlock",
+			StateType:    "open",
+			URL:          "https://otrs.example.com/otrs/index.pl?Action=AgentTicketZoom;TicketID=17",
+			Instance:     "production",
+		},
+		Kind:            kind,
+		Team:            "Team1",
+		AgeDuration:     95 * time.Minute,
+		AgeMinutes:      95,
+		EscalationLevel: 19,
+		ageKnown:        true,
+	}
+	if kind == KindDigest {
+		second := ticket
+		second.TicketNumber = "2021010110000018"
+		second.Priority = "3 normal"
+		second.Lock = "lock"
+		second.AgeDuration = 12 * time.Minute
+		second.AgeMinutes = 12
+		ticket.Tickets = []TemplateData{ticket, second}
+	}
+	return ticket.Localise(locale.Default, nil)
+}
+
+func templateKey(kind, channel string) string {
+	return fmt.Sprint(kind, ".", channel)
+}
+
+func kindFromKey(key string) string {
+	return strings.SplitN(key, ".", 2)[0]
+}
+
+func channelFromKey(key string) string {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// Check that key consists of known kind and channel.
+func isKnownKey(key string) bool {
+	return isInList(KindList, kindFromKey(key)) && isInList(ChannelList, channelFromKey(key))
+}
+
+func isInList(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort tickets by priority from highest, then by age from oldest.
+// OTRS priority names start with number so names compared as strings.
+func SortByUrgency(tickets []TemplateData) {
+	sort.SliceStable(tickets, func(i, j int) bool {
+		if tickets[i].Priority != tickets[j].Priority {
+			return tickets[i].Priority > tickets[j].Priority
+		}
+		return tickets[i].AgeDuration > tickets[j].AgeDuration
+	})
+}
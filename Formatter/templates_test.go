@@ -0,0 +1,178 @@
+package Formatter
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+)
+
+// Drop all messages.
+type quietLogger struct{}
+
+func (ql quietLogger) SetModuleName(name string) logger.Logger { return ql }
+func (quietLogger) Error(message string)                       {}
+func (quietLogger) Warning(message string)                     {}
+func (quietLogger) Info(message string)                        {}
+func (quietLogger) Debug(message string)                       {}
+
+// Database with stored templates only.
+type templateDB struct {
+	DBProvider.DBProvider
+	templates []DBProvider.MessageTemplate
+	err       error
+}
+
+func (tdb templateDB) MessageTemplateGetAll() ([]DBProvider.MessageTemplate, error) {
+	return tdb.templates, tdb.err
+}
+
+// Load templates from files and DB. Built-in templates restored after test.
+func loadTemplates(t *testing.T, files map[string]string, stored []DBProvider.MessageTemplate, parseMode string) error {
+	t.Helper()
+	t.Cleanup(func() {
+		var db DBProvider.DBProvider = templateDB{}
+		if err := LoadTemplates(t.TempDir(), &db, ParseModeHTML, quietLogger{}); err != nil {
+			t.Fatalf("restore built-in templates - %v", err)
+		}
+	})
+	directory := t.TempDir()
+	for name, text := range files {
+		err := ioutil.WriteFile(filepath.Join(directory, name), []byte(text), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var db DBProvider.DBProvider = templateDB{templates: stored}
+	return LoadTemplates(directory, &db, parseMode, quietLogger{})
+}
+
+func renderPlain(t *testing.T, kind string) string {
+	t.Helper()
+	text, ok, err := RenderChannel(kind, ChannelPlain, TemplateData{TicketOTRS: OTRSProvider.TicketOTRS{TicketNumber: "42"}})
+	if err != nil || !ok {
+		t.Fatalf("render '%v' - %v, found %v", kind, err, ok)
+	}
+	return text
+}
+
+func TestLoadTemplatesOverrides(t *testing.T) {
+	builtinClosed := renderPlain(t, KindClosed)
+	err := loadTemplates(t, map[string]string{
+		"new.plain.tmpl":      "file new {{.TicketNumber}}\n",
+		"reminder.plain.tmpl": "file reminder {{.TicketNumber}}",
+		"other.plain.tmpl":    "unknown kind",
+		"new.sms.tmpl":        "unknown channel",
+		"notes.txt":           "not a template",
+	}, []DBProvider.MessageTemplate{
+		{Kind: KindReminder, Channel: ChannelPlain, Body: "db reminder {{.TicketNumber}}"},
+		{Kind: "other", Channel: ChannelPlain, Body: "unknown kind"},
+	}, ParseModeHTML)
+	if err != nil {
+		t.Fatalf("load templates - %v", err)
+	}
+
+	cases := map[string]string{
+		KindNew:      "file new 42",    // File overrides built-in, trailing newline trimmed.
+		KindReminder: "db reminder 42", // DB overrides file.
+		KindClosed:   builtinClosed,    // Built-in kept.
+	}
+	for kind, want := range cases {
+		if text := renderPlain(t, kind); text != want {
+			t.Errorf("%v rendered as %q, want %q", kind, text, want)
+		}
+	}
+}
+
+func TestLoadTemplatesInvalidKeepsCurrent(t *testing.T) {
+	before := renderPlain(t, KindNew)
+	for name, files := range map[string]map[string]string{
+		"syntax error":  {"new.plain.tmpl": "{{.TicketNumber"},
+		"unknown field": {"new.plain.tmpl": "{{.NoSuchField}}"},
+		"unknown func":  {"new.plain.tmpl": "{{shout .TicketNumber}}"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := loadTemplates(t, files, nil, ParseModeHTML)
+			if err == nil {
+				t.Fatal("invalid template loaded")
+			}
+			if text := renderPlain(t, KindNew); text != before {
+				t.Errorf("templates replaced after error, rendered %q", text)
+			}
+		})
+	}
+}
+
+func TestLoadTemplatesDBError(t *testing.T) {
+	var db DBProvider.DBProvider = templateDB{err: errors.New("database is locked")}
+	if err := LoadTemplates(t.TempDir(), &db, ParseModeHTML, quietLogger{}); err == nil {
+		t.Error("templates loaded without DB")
+	}
+}
+
+func TestLoadTemplatesParseMode(t *testing.T) {
+	telegram := map[string]string{"new.telegram.tmpl": "{{bold .Title}}", "new.plain.tmpl": "{{.Title}}"}
+	data := TemplateData{TicketOTRS: OTRSProvider.TicketOTRS{Title: "a<b_c"}}
+	cases := []struct {
+		name      string
+		parseMode string
+		want      Message
+	}{
+		{"HTML", ParseModeHTML, Message{Text: "<b>a&lt;b_c</b>", ParseMode: ParseModeHTML, PlainText: "a<b_c"}},
+		{"MarkdownV2", ParseModeMarkdownV2, Message{Text: `*a<b\_c*`, ParseMode: ParseModeMarkdownV2, PlainText: "a<b_c"}},
+		// Telegram template not used without markup.
+		{"plain", ParseModePlain, PlainMessage("a<b_c")},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := loadTemplates(t, telegram, nil, c.parseMode)
+			if err != nil {
+				t.Fatalf("load templates - %v", err)
+			}
+			message, err := Render(KindNew, data)
+			if err != nil {
+				t.Fatalf("render - %v", err)
+			}
+			if message.Text != c.want.Text || message.ParseMode != c.want.ParseMode || message.PlainText != c.want.PlainText {
+				t.Errorf("rendered %+v, want %+v", message, c.want)
+			}
+		})
+	}
+}
+
+func TestPreview(t *testing.T) {
+	for _, kind := range KindList {
+		for _, channel := range []string{ChannelTelegram, ChannelPlain} {
+			message, err := Preview(kind, channel, "en")
+			if err != nil {
+				t.Errorf("preview %v.%v - %v", kind, channel, err)
+				continue
+			}
+			if strings.TrimSpace(message.PlainText) == "" {
+				t.Errorf("preview %v.%v is empty", kind, channel)
+			}
+		}
+	}
+	if _, err := Preview("other", ChannelPlain, "en"); !errors.Is(err, myErrors.ErrTemplateNotFound) {
+		t.Errorf("preview of unknown kind - %v", err)
+	}
+
+	// Webhook templates not built-in.
+	if _, err := Preview(KindNew, ChannelWebhook, "en"); !errors.Is(err, myErrors.ErrTemplateNotFound) {
+		t.Errorf("preview of missing webhook template - %v", err)
+	}
+	err := loadTemplates(t, map[string]string{"new.webhook.tmpl": `{"ticket": {{json .TicketNumber}}}`}, nil, ParseModeHTML)
+	if err != nil {
+		t.Fatalf("load templates - %v", err)
+	}
+	message, err := Preview(KindNew, ChannelWebhook, "en")
+	if err != nil || message.PlainText != `{"ticket": "2021010110000017"}` {
+		t.Errorf("preview of webhook template %q - %v", message.PlainText, err)
+	}
+}
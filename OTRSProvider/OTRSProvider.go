@@ -1,13 +1,52 @@
 package OTRSProvider
 
 import (
+	"fmt"
 	"github.com/Sarraksh/otrs-echo-bot/common/config"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"time"
+)
+
+const (
+	TimeLayout      string = "2006-01-02 15:04:05" // Layout of time fields returned by OTRS.
+	DefaultTimezone string = "Europe/Moscow"       // OTRS server timezone if not configured.
 )
 
 type OTRSProvider interface {
 	Initialise(logger logger.Logger, conf config.OTRSConf)
 	GetTicketDetails(ticketID string) (TicketOTRS, error)
+	SearchTickets(criteria SearchCriteria) ([]TicketOTRS, error)
+	SearchTicketIDs(criteria SearchCriteria) ([]string, error)
+}
+
+// Cache of ticket details over OTRS provider.
+type TicketCache interface {
+	Invalidate(ticketID, changed string) bool
+	Stats() CacheStats
+}
+
+// Ticket details cache counters since start.
+type CacheStats struct {
+	Hits          uint64  `json:"hits"`          // Requests served from cache.
+	Misses        uint64  `json:"misses"`        // Requests passed to OTRS.
+	Coalesced     uint64  `json:"coalesced"`     // Requests waited for same ticket request in flight.
+	Invalidations uint64  `json:"invalidations"` // Entries removed by ticket update notifications.
+	Entries       int     `json:"entries"`       // Tickets in cache now.
+	HitRate       float64 `json:"hitRate"`       // Share of requests served without own OTRS request.
+}
+
+// Filters for ticket search. Empty filters not applied.
+type SearchCriteria struct {
+	Text       string   // Full text search in article subject and body.
+	CustomerID string   // Customer ID, wildcard "*" allowed.
+	StateTypes []string // State types, e.g. "open" or "closed".
+	Priorities []string // Priority IDs, e.g. "5" for "5 very high".
+	Limit      int      // Maximum number of tickets in result.
+	TicketIDs  []string // Ticket IDs.
+
+	CreatedAfter  time.Time // Tickets created after this time.
+	CreatedBefore time.Time // Tickets created before this time or in same second.
+	ChangedAfter  time.Time // Tickets changed after this time.
 }
 
 // Wrapper for correct unmarshall JSON. ORTS returns array of tickets.
@@ -15,15 +54,46 @@ type TicketsFromJSON struct {
 	Ticket []TicketOTRS `json:"Ticket"`
 }
 
+// Wrapper for correct unmarshall TicketSearch JSON. OTRS returns empty object if nothing found.
+type SearchResultFromJSON struct {
+	TicketID []string `json:"TicketID"`
+}
+
+// Error object returned by OTRS GenericInterface instead of operation result.
+type ErrorFromJSON struct {
+	Error struct {
+		ErrorCode    string `json:"ErrorCode"`    // Operation and error, e.g. "TicketGet.AuthFail".
+		ErrorMessage string `json:"ErrorMessage"` // Human readable description.
+	} `json:"Error"`
+}
+
 // For ticket information received from OTRS and further processed information.
 type TicketOTRS struct {
-	TicketNumber string `json:"TicketNumber"` // It is returned in the field of the same name from OTRS.
-	Type         string `json:"Type"`         // It is returned in the field of the same name from OTRS.
-	CustomerID   string `json:"CustomerID"`   // It is returned in the field of the same name from OTRS.
-	Priority     string `json:"Priority"`     // It is returned in the field of the same name from OTRS.
-	Created      string `json:"Created"`      // It is returned in the field of the same name from OTRS.
-	Title        string `json:"Title"`        // It is returned in the field of the same name from OTRS.
-	Lock         string `json:"Lock"`         // It is returned in the field of the same name from OTRS.
-	StateType    string `json:"StateType"`    // It is returned in the field of the same name from OTRS.
-	URL          string // For formatted message.
+	TicketNumber string    `json:"TicketNumber"` // It is returned in the field of the same name from OTRS.
+	Type         string    `json:"Type"`         // It is returned in the field of the same name from OTRS.
+	CustomerID   string    `json:"CustomerID"`   // It is returned in the field of the same name from OTRS.
+	Priority     string    `json:"Priority"`     // It is returned in the field of the same name from OTRS.
+	Created      string    `json:"Created"`      // It is returned in the field of the same name from OTRS.
+	Title        string    `json:"Title"`        // It is returned in the field of the same name from OTRS.
+	Lock         string    `json:"Lock"`         // It is returned in the field of the same name from OTRS.
+	StateType    string    `json:"StateType"`    // It is returned in the field of the same name from OTRS.
+	State        string    `json:"State"`        // It is returned in the field of the same name from OTRS.
+	Owner        string    `json:"Owner"`        // It is returned in the field of the same name from OTRS.
+	Changed      string    `json:"Changed"`      // It is returned in the field of the same name from OTRS.
+	URL          string    // For formatted message.
+	Instance     string    `json:"-"` // Name of OTRS instance. Empty for single unnamed instance.
+	CreatedTime  time.Time `json:"-"` // Created parsed in OTRS server timezone. Zero if can't be parsed.
+}
+
+// Return module name for log with instance name if instance named.
+func ModuleName(module, instance string) string {
+	if instance == "" {
+		return module
+	}
+	return fmt.Sprintf("%v '%v'", module, instance)
+}
+
+// Parse OTRS time field. OTRS returns time without zone in server timezone.
+func ParseTime(value string, location *time.Location) (time.Time, error) {
+	return time.ParseInLocation(TimeLayout, value, location)
 }
@@ -0,0 +1,123 @@
+package basicOTRS
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"net/http"
+	"net/url"
+)
+
+// Ways to pass credentials to OTRS API.
+const (
+	AuthModeQuery   string = "query"   // UserLogin and Password in query string of GET request. Visible in access logs.
+	AuthModeBody    string = "body"    // UserLogin and Password in JSON body of POST request.
+	AuthModeBasic   string = "basic"   // HTTP basic authentication header.
+	AuthModeSession string = "session" // SessionID from SessionCreate operation in JSON body of POST request.
+	AuthModeToken   string = "token"   // Bearer token header. Znuny/OTRS 8 REST API.
+)
+
+type sessionCreateResponse struct {
+	SessionID string
+}
+
+// Build request to OTRS operation with credentials according to auth mode.
+// GET parameters sent in query string, POST parameters in JSON body.
+func (bo *BasicOTRS) newRequest(endpoint string, parameters url.Values, sessionID string) (*http.Request, error) {
+	request := url.Values{}
+	for key, values := range parameters {
+		request[key] = values
+	}
+
+	switch bo.AuthMode {
+	case AuthModeBody:
+		request.Set("UserLogin", bo.Login)
+		request.Set("Password", bo.Password)
+		return newJSONRequest(endpoint, jsonParameters(request))
+	case AuthModeSession:
+		request.Set("SessionID", sessionID)
+		return newJSONRequest(endpoint, jsonParameters(request))
+	case AuthModeQuery:
+		request.Set("UserLogin", bo.Login)
+		request.Set("Password", bo.Password)
+	}
+
+	requestURL := endpoint
+	if len(request) > 0 {
+		requestURL = fmt.Sprint(endpoint, "?", request.Encode())
+	}
+	httpRequest, err := http.NewRequest(http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch bo.AuthMode {
+	case AuthModeBasic:
+		httpRequest.SetBasicAuth(bo.Login, bo.Password)
+	case AuthModeToken:
+		httpRequest.Header.Set("Authorization", fmt.Sprint("Bearer ", bo.Token))
+	}
+	return httpRequest, nil
+}
+
+func newJSONRequest(endpoint string, body interface{}) (*http.Request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return request, nil
+}
+
+// Convert request parameters for JSON body. Repeated parameters became arrays.
+func jsonParameters(parameters url.Values) map[string]interface{} {
+	result := make(map[string]interface{}, len(parameters))
+	for key, values := range parameters {
+		if len(values) == 1 {
+			result[key] = values[0]
+			continue
+		}
+		result[key] = values
+	}
+	return result
+}
+
+// Return current session. Create new session if none or provided session expired.
+// Concurrent callers with same expired session wait for one renewal.
+func (bo *BasicOTRS) session(expired string) (string, error) {
+	bo.sessionMx.Lock()
+	defer bo.sessionMx.Unlock()
+	if bo.sessionID != "" && bo.sessionID != expired {
+		return bo.sessionID, nil
+	}
+
+	if expired != "" {
+		bo.Log.Info("OTRS session expired. Create new session")
+	}
+	request, err := newJSONRequest(bo.SessionURL, map[string]string{
+		"UserLogin": bo.Login,
+		"Password":  bo.Password,
+	})
+	if err != nil {
+		return "", err
+	}
+	body, err := bo.do(request)
+	if err != nil {
+		return "", err
+	}
+	var response sessionCreateResponse
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", myErrors.ErrOTRSServerError, err)
+	}
+	if response.SessionID == "" {
+		return "", fmt.Errorf("%w: no SessionID in SessionCreate response", myErrors.ErrOTRSServerError)
+	}
+	bo.sessionID = response.SessionID
+	bo.Log.Debug("OTRS session created")
+	return bo.sessionID, nil
+}
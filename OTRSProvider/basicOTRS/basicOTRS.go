@@ -3,53 +3,116 @@ package basicOTRS
 import (
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
 	"github.com/Sarraksh/otrs-echo-bot/common/config"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
-	"io/ioutil"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 )
 
 const ModuleName string = "OTRS Provider"
+const MaxSearchDetails int = 100 // Maximum number of tickets with details returned by one search.
 
 type BasicOTRS struct {
+	Instance        string // Instance name for ticket details.
 	URLFormat       string // String for fmt.Sprintf. Represent full URL to OTRS API with %s flag for ticketID.
+	SearchURL       string // Full URL to TicketSearch operation without parameters. Search disabled if empty.
+	SessionURL      string // Full URL to SessionCreate operation. Used in "session" auth mode.
+	AuthMode        string // Way to pass credentials. One of AuthMode constants.
+	Login           string
+	Password        string
+	Token           string
 	TicketURLPrefix string
 	HTTPClient      *http.Client
+	MaxRetries      int            // Retries for timeouts and server errors.
+	RetryInterval   time.Duration  // Delay before first retry.
+	Location        *time.Location // OTRS server timezone.
 	Log             logger.Logger
+	breaker         *circuitBreaker
+
+	sessionMx sync.Mutex
+	sessionID string
 }
 
 func (bo *BasicOTRS) Initialise(logger logger.Logger, conf config.OTRSConf) {
-	bo.Log = logger.SetModuleName(ModuleName)
-
-	// Generate and save URLFormat
-	bo.URLFormat = urlFormat(
-		conf.API.Protocol,
-		conf.Host,
-		conf.API.GetTicketDetailListPath,
-		conf.API.Login,
-		conf.API.Password,
-	)
-	maskedURLString := urlFormat(
-		conf.API.Protocol,
-		conf.Host,
-		conf.API.GetTicketDetailListPath,
-		`*********`,
-		`*********`,
-	)
-	bo.Log.Debug(fmt.Sprintf("Set URLFormat - '%v'", maskedURLString))
+	bo.Log = logger.SetModuleName(OTRSProvider.ModuleName(ModuleName, conf.Name))
+	bo.Instance = conf.Name
+
+	// Generate and save URLFormat. Credentials passed according to auth mode.
+	bo.URLFormat = urlFormat(conf.API.Protocol, conf.Host, conf.API.GetTicketDetailListPath)
+	bo.Log.Debug(fmt.Sprintf("Set URLFormat - '%v'", bo.URLFormat))
+	if conf.API.TicketSearchPath != "" {
+		bo.SearchURL = fmt.Sprint(conf.API.Protocol, `://`, conf.Host, conf.API.TicketSearchPath)
+		bo.Log.Debug(fmt.Sprintf("Set SearchURL - '%v'", bo.SearchURL))
+	}
+	// Unset auth mode kept as "query" for web services configured with GET-only routes.
+	bo.AuthMode = conf.API.AuthMode
+	switch bo.AuthMode {
+	case "":
+		bo.AuthMode = AuthModeQuery
+		bo.Log.Warning("Option 'API.AuthMode' not set, credentials passed in URL. Default deprecated, set AuthMode explicitly - \"body\" needs POST routes in OTRS web service")
+	case AuthModeQuery:
+		bo.Log.Warning("Credentials passed in URL and may appear in OTRS and proxy access logs. Use another auth mode")
+	}
+	if bo.AuthMode == AuthModeSession {
+		bo.SessionURL = fmt.Sprint(conf.API.Protocol, `://`, conf.Host, conf.API.SessionCreatePath)
+		bo.Log.Debug(fmt.Sprintf("Set SessionURL - '%v'", bo.SessionURL))
+	}
+	bo.Log.Debug(fmt.Sprintf("Set AuthMode - '%v'", bo.AuthMode))
+	bo.Login = conf.API.Login
+	bo.Password = conf.API.Password
+	bo.Token = conf.API.Token
 
 	// Avoid insecure connection error if OTRS API available by http.
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: conf.API.InsecureConnection},
 	}
-	bo.HTTPClient = &http.Client{Transport: tr}
-	bo.Log.Debug(fmt.Sprintf("Transport initialised. Set InsecureConnection as '%v'", conf.API.InsecureConnection))
+	timeout := DefaultTimeout
+	if conf.API.Timeout > 0 {
+		timeout = time.Duration(conf.API.Timeout) * time.Second
+	}
+	bo.HTTPClient = &http.Client{Transport: tr, Timeout: timeout}
+	bo.Log.Debug(fmt.Sprintf("Transport initialised. Set InsecureConnection as '%v', timeout '%v'", conf.API.InsecureConnection, timeout))
+
+	// Retries and circuit breaker.
+	bo.MaxRetries = DefaultMaxRetries
+	switch {
+	case conf.API.MaxRetries > 0:
+		bo.MaxRetries = conf.API.MaxRetries
+	case conf.API.MaxRetries < 0:
+		bo.MaxRetries = 0
+	}
+	bo.RetryInterval = DefaultRetryInterval
+	threshold := DefaultBreakerThreshold
+	if conf.API.BreakerThreshold > 0 {
+		threshold = conf.API.BreakerThreshold
+	}
+	cooldown := DefaultBreakerCooldown
+	if conf.API.BreakerCooldown > 0 {
+		cooldown = time.Duration(conf.API.BreakerCooldown) * time.Second
+	}
+	bo.breaker = newCircuitBreaker(threshold, cooldown, bo.Log)
 
 	// Set TicketURLPrefix.
 	bo.TicketURLPrefix = conf.TicketURLPrefix
 
+	// Set OTRS server timezone. Validated with config so error is unexpected.
+	timezone := conf.Timezone
+	if timezone == "" {
+		timezone = OTRSProvider.DefaultTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		bo.Log.Error(fmt.Sprintf("Can't load timezone '%v' - '%v'. Use UTC", timezone, err))
+		location = time.UTC
+	}
+	bo.Location = location
+
 	bo.Log.Debug("Initialisation complete")
 }
 
@@ -57,40 +120,156 @@ func (bo *BasicOTRS) GetTicketDetails(ticketID string) (OTRSProvider.TicketOTRS,
 	bo.Log.Debug(fmt.Sprintf("Start GetTicketDetails sequence for '%v'", ticketID))
 	defer bo.Log.Debug(fmt.Sprintf("Stop  GetTicketDetails sequence for '%v'", ticketID))
 
-	requestURL := fmt.Sprintf(bo.URLFormat, ticketID)
-	response, err := bo.HTTPClient.Get(requestURL)
-	if err != nil {
-		bo.Log.Error(fmt.Sprintf("GET request '%+v'", err))
-		return OTRSProvider.TicketOTRS{}, err // TODO - make less sensitive for errors
+	body, err := bo.call(fmt.Sprintf(bo.URLFormat, ticketID), nil)
+	if errors.Is(err, myErrors.ErrTicketAccessDenied) {
+		err = bo.confirmMissing(ticketID, err)
 	}
-	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		bo.Log.Error(fmt.Sprintf("ReadAll from responce - '%+v'", err))
-		return OTRSProvider.TicketOTRS{}, err // TODO - make less sensitive for errors
+		bo.Log.Error(fmt.Sprintf("Get ticket '%v' - '%v'", ticketID, err))
+		return OTRSProvider.TicketOTRS{}, err
 	}
 	var ticketsFromJSON OTRSProvider.TicketsFromJSON
-	bo.Log.Debug(fmt.Sprintf("Respose body - '%+v'", string(body)))
 	err = json.Unmarshal(body, &ticketsFromJSON)
 	if err != nil {
 		bo.Log.Error(fmt.Sprintf("Unmarshal error - '%+v'", err))
-		return OTRSProvider.TicketOTRS{}, err // TODO - make less sensitive for errors
+		return OTRSProvider.TicketOTRS{}, fmt.Errorf("%w: %v", myErrors.ErrOTRSServerError, err)
+	}
+
+	// Missing ticket reported by error code, so response without ticket is unexpected.
+	if len(ticketsFromJSON.Ticket) == 0 {
+		bo.Log.Error(fmt.Sprintf("No ticket '%v' in response", ticketID))
+		return OTRSProvider.TicketOTRS{}, fmt.Errorf("%w: no ticket '%v' in response", myErrors.ErrOTRSServerError, ticketID)
 	}
 
 	ticketDetails := ticketsFromJSON.Ticket[0]
 	ticketDetails.URL = fmt.Sprint(bo.TicketURLPrefix, ticketID)
+	ticketDetails.Instance = bo.Instance
+	ticketDetails.CreatedTime, err = OTRSProvider.ParseTime(ticketDetails.Created, bo.Location)
+	if err != nil {
+		bo.Log.Warning(fmt.Sprintf("Can't parse ticket '%v' creation time '%v' - '%v'", ticketID, ticketDetails.Created, err))
+	}
 	return ticketDetails, nil
 }
 
-func urlFormat(protocol, URL, endpoint, login, password string) string {
+// Return ErrTicketNotFound if ticket with denied access not found by TicketSearch.
+// Otherwise access error returned, because ticket may exist in queue not available to API user.
+func (bo *BasicOTRS) confirmMissing(ticketID string, accessErr error) error {
+	if bo.SearchURL == "" {
+		return accessErr
+	}
+	ticketIDList, err := bo.SearchTicketIDs(OTRSProvider.SearchCriteria{TicketIDs: []string{ticketID}})
+	if err != nil {
+		bo.Log.Warning(fmt.Sprintf("Can't check existence of ticket '%v' - '%v'", ticketID, err))
+		return accessErr
+	}
+	if len(ticketIDList) > 0 {
+		return accessErr
+	}
+	return fmt.Errorf("%w: ticket '%v' not found by search after '%v'", myErrors.ErrTicketNotFound, ticketID, accessErr)
+}
+
+// Search tickets by TicketSearch operation and return details of found tickets.
+// Tickets sorted by age from newest as returned by OTRS. At most MaxSearchDetails tickets returned,
+// details requested concurrently by OTRSProvider.DetailsWorkers goroutines.
+func (bo *BasicOTRS) SearchTickets(criteria OTRSProvider.SearchCriteria) ([]OTRSProvider.TicketOTRS, error) {
+	bo.Log.Debug(fmt.Sprintf("Start SearchTickets sequence for '%+v'", criteria))
+	defer bo.Log.Debug(fmt.Sprintf("Stop  SearchTickets sequence for '%+v'", criteria))
+
+	if criteria.Limit <= 0 || criteria.Limit > MaxSearchDetails {
+		criteria.Limit = MaxSearchDetails
+	}
+	ticketIDList, err := bo.SearchTicketIDs(criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get details for found tickets.
+	results := make([]OTRSProvider.TicketResult, len(ticketIDList))
+	OTRSProvider.ForEachConcurrently(len(ticketIDList), func(index int) {
+		ticket, err := bo.GetTicketDetails(ticketIDList[index])
+		results[index] = OTRSProvider.TicketResult{Ticket: ticket, Err: err}
+	})
+	ticketList := make([]OTRSProvider.TicketOTRS, 0, len(ticketIDList))
+	for index, result := range results {
+		if result.Err != nil {
+			bo.Log.Warning(fmt.Sprintf("Skip found ticket '%v' - '%v'", ticketIDList[index], result.Err))
+			continue
+		}
+		ticketList = append(ticketList, result.Ticket)
+	}
+	return ticketList, nil
+}
+
+// Search tickets by TicketSearch operation and return IDs of found tickets.
+func (bo *BasicOTRS) SearchTicketIDs(criteria OTRSProvider.SearchCriteria) ([]string, error) {
+	if bo.SearchURL == "" {
+		return nil, myErrors.ErrSearchNotConfigured
+	}
+
+	body, err := bo.call(bo.SearchURL, searchParameters(criteria, bo.Location))
+	if err != nil {
+		bo.Log.Error(fmt.Sprintf("Search tickets - '%v'", err))
+		return nil, err
+	}
+	var searchResult OTRSProvider.SearchResultFromJSON
+	err = json.Unmarshal(body, &searchResult)
+	if err != nil {
+		bo.Log.Error(fmt.Sprintf("Unmarshal error - '%+v'", err))
+		return nil, fmt.Errorf("%w: %v", myErrors.ErrOTRSServerError, err)
+	}
+
+	ticketIDList := searchResult.TicketID
+	if criteria.Limit > 0 && len(ticketIDList) > criteria.Limit {
+		ticketIDList = ticketIDList[:criteria.Limit]
+	}
+	return ticketIDList, nil
+}
+
+// Convert search criteria into TicketSearch request parameters. Time filters passed in OTRS server timezone.
+func searchParameters(criteria OTRSProvider.SearchCriteria, location *time.Location) url.Values {
+	parameters := url.Values{}
+	parameters.Set("SortBy", "Age")
+	parameters.Set("OrderBy", "Up")
+	if criteria.Limit > 0 {
+		parameters.Set("Limit", fmt.Sprint(criteria.Limit))
+	}
+	if criteria.Text != "" {
+		// Text found in subject or body of any article.
+		parameters.Set("FullTextIndex", "1")
+		parameters.Set("ContentSearch", "OR")
+		parameters.Set("Subject", fmt.Sprint("*", criteria.Text, "*"))
+		parameters.Set("Body", fmt.Sprint("*", criteria.Text, "*"))
+	}
+	for _, ticketID := range criteria.TicketIDs {
+		parameters.Add("TicketID", ticketID)
+	}
+	if criteria.CustomerID != "" {
+		parameters.Set("CustomerID", criteria.CustomerID)
+	}
+	for _, stateType := range criteria.StateTypes {
+		parameters.Add("StateType", stateType)
+	}
+	for _, priority := range criteria.Priorities {
+		parameters.Add("PriorityIDs", priority)
+	}
+	if !criteria.CreatedAfter.IsZero() {
+		parameters.Set("TicketCreateTimeNewerDate", criteria.CreatedAfter.In(location).Format(OTRSProvider.TimeLayout))
+	}
+	if !criteria.CreatedBefore.IsZero() {
+		parameters.Set("TicketCreateTimeOlderDate", criteria.CreatedBefore.In(location).Format(OTRSProvider.TimeLayout))
+	}
+	if !criteria.ChangedAfter.IsZero() {
+		parameters.Set("TicketChangeTimeNewerDate", criteria.ChangedAfter.In(location).Format(OTRSProvider.TimeLayout))
+	}
+	return parameters
+}
+
+func urlFormat(protocol, URL, endpoint string) string {
 	return fmt.Sprint(
 		protocol,
 		`://`,
 		URL,
 		endpoint,
 		`%s`,
-		`?UserLogin=`,
-		login,
-		`&Password=`,
-		password,
 	)
 }
@@ -0,0 +1,493 @@
+package basicOTRS
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider/fakeOTRS"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger/CLILogger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+)
+
+var testTicket = OTRSProvider.TicketOTRS{
+	TicketNumber: "2024010110000011",
+	Title:        "Printer on fire",
+	CustomerID:   "ACME",
+	Priority:     "3 normal",
+	StateType:    "open",
+	Created:      "2024-01-01 10:00:00",
+}
+
+// Start fake OTRS with one ticket and client with short retry interval and breaker cooldown.
+func newClient(t *testing.T, edit func(conf *config.OTRSConf)) (*BasicOTRS, *fakeOTRS.Server) {
+	t.Helper()
+	server := fakeOTRS.NewServer()
+	t.Cleanup(server.Close)
+	server.AddTicket("1", testTicket)
+
+	conf := server.Conf()
+	conf.API.AuthMode = AuthModeBody
+	conf.API.BreakerCooldown = 1
+	if edit != nil {
+		edit(&conf)
+	}
+	bo := &BasicOTRS{}
+	bo.Initialise(CLILogger.NewDefault(), conf)
+	bo.RetryInterval = time.Millisecond
+	bo.breaker.cooldown = 50 * time.Millisecond
+	return bo, server
+}
+
+func TestGetTicketDetails(t *testing.T) {
+	for _, mode := range []string{AuthModeQuery, AuthModeBody, AuthModeBasic, AuthModeSession, AuthModeToken} {
+		t.Run(mode, func(t *testing.T) {
+			bo, _ := newClient(t, func(conf *config.OTRSConf) { conf.API.AuthMode = mode })
+
+			ticket, err := bo.GetTicketDetails("1")
+			if err != nil {
+				t.Fatalf("get ticket - %v", err)
+			}
+			if ticket.TicketNumber != testTicket.TicketNumber || ticket.CreatedTime.IsZero() || ticket.URL == "" {
+				t.Errorf("unexpected ticket %+v", ticket)
+			}
+		})
+	}
+}
+
+func TestSessionRenewal(t *testing.T) {
+	bo, server := newClient(t, func(conf *config.OTRSConf) { conf.API.AuthMode = AuthModeSession })
+	if _, err := bo.GetTicketDetails("1"); err != nil {
+		t.Fatalf("get ticket - %v", err)
+	}
+
+	server.ExpireSessions()
+	if _, err := bo.GetTicketDetails("1"); err != nil {
+		t.Fatalf("get ticket with expired session - %v", err)
+	}
+	// Session create and ticket for each call, plus failed request with expired session.
+	if requests := server.Requests(); requests != 5 {
+		t.Errorf("%v requests, want 5", requests)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	cases := []struct {
+		name     string
+		failures []fakeOTRS.Failure
+		ticketID string
+		want     error
+		requests int
+	}{
+		// Missing ticket confirmed by search.
+		{name: "missing ticket", ticketID: "404", want: myErrors.ErrTicketNotFound, requests: 2},
+		{name: "access denied to existing ticket", failures: []fakeOTRS.Failure{{ErrorCode: "TicketGet.AccessDenied"}}, want: myErrors.ErrTicketAccessDenied, requests: 2},
+		{name: "access denied and search failed", ticketID: "404", failures: []fakeOTRS.Failure{{}, {ErrorCode: "TicketSearch.InternalError"}, {ErrorCode: "TicketSearch.InternalError"}, {ErrorCode: "TicketSearch.InternalError"}}, want: myErrors.ErrTicketAccessDenied, requests: 4},
+		{name: "invalid ticket", failures: []fakeOTRS.Failure{{ErrorCode: "TicketGet.NotValid"}}, want: myErrors.ErrTicketNotFound, requests: 1},
+		{name: "auth failed", failures: []fakeOTRS.Failure{{ErrorCode: "TicketGet.AuthFail"}}, want: myErrors.ErrOTRSAuthFailed, requests: 1},
+		{name: "unauthorised status", failures: []fakeOTRS.Failure{{Status: http.StatusUnauthorized}}, want: myErrors.ErrOTRSAuthFailed, requests: 1},
+		{name: "forbidden status", failures: []fakeOTRS.Failure{{Status: http.StatusForbidden}}, want: myErrors.ErrOTRSAuthFailed, requests: 1},
+		// Wrong operation path or proxy error must not end events.
+		{name: "not found status", failures: repeat(fakeOTRS.Failure{Status: http.StatusNotFound}, 3), want: myErrors.ErrOTRSServerError, requests: 3},
+		{name: "access denied of other operation", failures: repeat(fakeOTRS.Failure{ErrorCode: "TicketSearch.AccessDenied"}, 3), want: myErrors.ErrOTRSServerError, requests: 3},
+		{name: "server error", failures: repeat(fakeOTRS.Failure{Status: http.StatusInternalServerError}, 3), want: myErrors.ErrOTRSServerError, requests: 3},
+		{name: "unknown error code", failures: repeat(fakeOTRS.Failure{ErrorCode: "TicketGet.InternalError"}, 3), want: myErrors.ErrOTRSServerError, requests: 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bo, server := newClient(t, nil)
+			server.Fail(c.failures...)
+			ticketID := c.ticketID
+			if ticketID == "" {
+				ticketID = "1"
+			}
+
+			_, err := bo.GetTicketDetails(ticketID)
+
+			if !errors.Is(err, c.want) {
+				t.Errorf("error '%v', want '%v'", err, c.want)
+			}
+			if requests := server.Requests(); requests != c.requests {
+				t.Errorf("%v requests, want %v", requests, c.requests)
+			}
+		})
+	}
+}
+
+func TestAccessDeniedWithoutSearch(t *testing.T) {
+	bo, server := newClient(t, func(conf *config.OTRSConf) { conf.API.TicketSearchPath = "" })
+
+	_, err := bo.GetTicketDetails("404")
+
+	if !errors.Is(err, myErrors.ErrTicketAccessDenied) || errors.Is(err, myErrors.ErrTicketNotFound) {
+		t.Errorf("error '%v', want only '%v'", err, myErrors.ErrTicketAccessDenied)
+	}
+	if requests := server.Requests(); requests != 1 {
+		t.Errorf("%v requests, want 1", requests)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	bo, server := newClient(t, nil)
+	server.Fail(fakeOTRS.Failure{Status: http.StatusBadGateway}, fakeOTRS.Failure{Status: http.StatusServiceUnavailable})
+
+	ticket, err := bo.GetTicketDetails("1")
+
+	if err != nil {
+		t.Fatalf("get ticket after retries - %v", err)
+	}
+	if ticket.TicketNumber != testTicket.TicketNumber {
+		t.Errorf("unexpected ticket %+v", ticket)
+	}
+	if requests := server.Requests(); requests != 3 {
+		t.Errorf("%v requests, want 3", requests)
+	}
+}
+
+func TestRetryDisabled(t *testing.T) {
+	bo, server := newClient(t, func(conf *config.OTRSConf) { conf.API.MaxRetries = -1 })
+	server.Fail(fakeOTRS.Failure{Status: http.StatusBadGateway})
+
+	_, err := bo.GetTicketDetails("1")
+
+	if !errors.Is(err, myErrors.ErrOTRSServerError) {
+		t.Errorf("error '%v', want server error", err)
+	}
+	if requests := server.Requests(); requests != 1 {
+		t.Errorf("%v requests, want 1", requests)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	bo, server := newClient(t, func(conf *config.OTRSConf) { conf.API.MaxRetries = 1 })
+	bo.HTTPClient.Timeout = 20 * time.Millisecond
+	server.Fail(fakeOTRS.Failure{Delay: 200 * time.Millisecond})
+
+	ticket, err := bo.GetTicketDetails("1")
+
+	if err != nil {
+		t.Fatalf("get ticket after timeout - %v", err)
+	}
+	if ticket.TicketNumber != testTicket.TicketNumber {
+		t.Errorf("unexpected ticket %+v", ticket)
+	}
+
+	server.Fail(fakeOTRS.Failure{Delay: 200 * time.Millisecond}, fakeOTRS.Failure{Delay: 200 * time.Millisecond})
+	_, err = bo.GetTicketDetails("1")
+	if !errors.Is(err, myErrors.ErrOTRSTimeout) {
+		t.Errorf("error '%v', want timeout", err)
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	interval := 100 * time.Millisecond
+	for attempt := 1; attempt <= 3; attempt++ {
+		base := interval << (attempt - 1)
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 100; i++ {
+			delay := retryDelay(interval, attempt)
+			if delay < base || delay >= 2*base {
+				t.Fatalf("attempt %v delay %v not in [%v, %v)", attempt, delay, base, 2*base)
+			}
+			seen[delay] = true
+		}
+		if len(seen) < 10 {
+			t.Errorf("attempt %v has only %v distinct delays", attempt, len(seen))
+		}
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	bo, server := newClient(t, func(conf *config.OTRSConf) {
+		conf.API.MaxRetries = -1
+		conf.API.BreakerThreshold = 2
+	})
+
+	// Consecutive failures open breaker.
+	server.Fail(fakeOTRS.Failure{Status: http.StatusInternalServerError}, fakeOTRS.Failure{Status: http.StatusInternalServerError})
+	for i := 0; i < 2; i++ {
+		if _, err := bo.GetTicketDetails("1"); !errors.Is(err, myErrors.ErrOTRSServerError) {
+			t.Fatalf("failure %v - '%v'", i, err)
+		}
+	}
+
+	// Open breaker rejects requests without sending.
+	if _, err := bo.GetTicketDetails("1"); !errors.Is(err, myErrors.ErrOTRSUnavailable) {
+		t.Fatalf("open breaker - '%v'", err)
+	}
+	if requests := server.Requests(); requests != 2 {
+		t.Fatalf("%v requests sent, want 2", requests)
+	}
+
+	// Failed trial after cooldown opens breaker again.
+	time.Sleep(60 * time.Millisecond)
+	server.Fail(fakeOTRS.Failure{Status: http.StatusBadGateway})
+	if _, err := bo.GetTicketDetails("1"); !errors.Is(err, myErrors.ErrOTRSServerError) {
+		t.Fatalf("failed trial - '%v'", err)
+	}
+	if _, err := bo.GetTicketDetails("1"); !errors.Is(err, myErrors.ErrOTRSUnavailable) {
+		t.Fatalf("breaker after failed trial - '%v'", err)
+	}
+
+	// Successful trial closes breaker.
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if _, err := bo.GetTicketDetails("1"); err != nil {
+			t.Fatalf("request %v after successful trial - '%v'", i, err)
+		}
+	}
+	if requests := server.Requests(); requests != 6 {
+		t.Errorf("%v requests sent, want 6", requests)
+	}
+}
+
+func TestBreakerMissingTicketIsSuccess(t *testing.T) {
+	bo, server := newClient(t, func(conf *config.OTRSConf) {
+		conf.API.MaxRetries = -1
+		conf.API.BreakerThreshold = 2
+	})
+	server.Fail(fakeOTRS.Failure{Status: http.StatusInternalServerError})
+	_, _ = bo.GetTicketDetails("1")
+	_, _ = bo.GetTicketDetails("404")
+	server.Fail(fakeOTRS.Failure{Status: http.StatusInternalServerError})
+	_, _ = bo.GetTicketDetails("1")
+
+	// Missing ticket answered by OTRS resets failure count.
+	if _, err := bo.GetTicketDetails("1"); err != nil {
+		t.Errorf("breaker opened by non consecutive failures - '%v'", err)
+	}
+}
+
+func TestBreakerHalfOpenSingleTrial(t *testing.T) {
+	breaker := newCircuitBreaker(1, 10*time.Millisecond, CLILogger.NewDefault())
+	breaker.failure()
+	time.Sleep(20 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	var mx sync.Mutex
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if breaker.allow() == nil {
+				mx.Lock()
+				allowed++
+				mx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 1 {
+		t.Fatalf("%v trial requests allowed, want 1", allowed)
+	}
+
+	breaker.success()
+	if err := breaker.allow(); err != nil {
+		t.Errorf("closed breaker - '%v'", err)
+	}
+}
+
+func TestSearchTicketIDs(t *testing.T) {
+	bo, server := newClient(t, nil)
+	server.AddTicket("2", OTRSProvider.TicketOTRS{Title: "Printer jam", CustomerID: "ACME", StateType: "open"})
+	server.AddTicket("3", OTRSProvider.TicketOTRS{Title: "Network down", CustomerID: "Other", StateType: "open"})
+
+	ticketIDList, err := bo.SearchTicketIDs(OTRSProvider.SearchCriteria{Text: "printer", CustomerID: "ACME"})
+
+	if err != nil {
+		t.Fatalf("search - %v", err)
+	}
+	if len(ticketIDList) != 2 || ticketIDList[0] != "1" || ticketIDList[1] != "2" {
+		t.Errorf("found %v, want [1 2]", ticketIDList)
+	}
+}
+
+func TestSearchTicketsCapsAndKeepsOrder(t *testing.T) {
+	bo, server := newClient(t, nil)
+	start := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	for n := 2; n <= MaxSearchDetails+20; n++ {
+		server.AddTicket(fmt.Sprint(n), OTRSProvider.TicketOTRS{
+			TicketNumber: fmt.Sprint(n),
+			Created:      start.Add(time.Duration(n) * time.Minute).Format(OTRSProvider.TimeLayout),
+		})
+	}
+
+	ticketList, err := bo.SearchTickets(OTRSProvider.SearchCriteria{})
+
+	if err != nil {
+		t.Fatalf("search - %v", err)
+	}
+	if len(ticketList) != MaxSearchDetails {
+		t.Fatalf("%v tickets, want %v", len(ticketList), MaxSearchDetails)
+	}
+	// Newest first as returned by search.
+	for index, ticket := range ticketList {
+		if want := fmt.Sprint(MaxSearchDetails + 20 - index); ticket.TicketNumber != want {
+			t.Fatalf("ticket %v at position %v, want %v", ticket.TicketNumber, index, want)
+		}
+	}
+	// Search and capped details only.
+	if requests := server.Requests(); requests != MaxSearchDetails+1 {
+		t.Errorf("%v requests, want %v", requests, MaxSearchDetails+1)
+	}
+}
+
+func TestSearchTicketsConcurrentDetails(t *testing.T) {
+	bo, server := newClient(t, nil)
+	for n := 2; n <= 16; n++ {
+		server.AddTicket(fmt.Sprint(n), testTicket)
+	}
+	delay := 20 * time.Millisecond
+	server.SetDelay(delay)
+
+	started := time.Now()
+	ticketList, err := bo.SearchTickets(OTRSProvider.SearchCriteria{})
+	elapsed := time.Since(started)
+
+	if err != nil || len(ticketList) != 16 {
+		t.Fatalf("found %v tickets - %v", len(ticketList), err)
+	}
+	// Search plus two rounds of concurrent requests with margin, one by one takes 17 delays.
+	if elapsed > 8*delay {
+		t.Errorf("search took '%v', details requested one by one", elapsed)
+	}
+}
+
+func repeat(failure fakeOTRS.Failure, count int) []fakeOTRS.Failure {
+	failures := make([]fakeOTRS.Failure, count)
+	for i := range failures {
+		failures[i] = failure
+	}
+	return failures
+}
+
+func TestMaskPassword(t *testing.T) {
+	password := "p@ss w/rd&1+%"
+	for _, text := range []string{
+		"Get \"http://otrs/Ticket/1?Password=" + url.QueryEscape(password) + "&UserLogin=bot\": dial tcp: connection refused",
+		"Get \"http://otrs/Ticket/" + url.PathEscape(password) + "\": timeout",
+		"password " + password + " in text",
+	} {
+		masked := maskPassword(text, password)
+		if strings.Contains(masked, password) || strings.Contains(masked, url.QueryEscape(password)) || strings.Contains(masked, url.PathEscape(password)) {
+			t.Errorf("password not masked in '%v'", masked)
+		}
+		if !strings.Contains(masked, "*********") {
+			t.Errorf("no mask in '%v'", masked)
+		}
+	}
+}
+
+func TestQueryModeErrorHidesPassword(t *testing.T) {
+	bo, server := newClient(t, func(conf *config.OTRSConf) {
+		conf.API.AuthMode = AuthModeQuery
+		conf.API.Password = "p@ss w/rd&1+%"
+		conf.API.MaxRetries = -1
+	})
+	// Connection refused error contains request URL.
+	server.Close()
+
+	_, err := bo.GetTicketDetails("1")
+
+	if err == nil {
+		t.Fatal("request to closed server succeeded")
+	}
+	if strings.Contains(err.Error(), url.QueryEscape("p@ss w/rd&1+%")) || strings.Contains(err.Error(), "p@ss w/rd&1+%") {
+		t.Errorf("password in error '%v'", err)
+	}
+}
+
+// Keep log messages of all levels.
+type recordingLogger struct {
+	mx       *sync.Mutex
+	messages *[]string
+}
+
+func newRecordingLogger() recordingLogger {
+	return recordingLogger{mx: &sync.Mutex{}, messages: &[]string{}}
+}
+
+func (rl recordingLogger) SetModuleName(name string) logger.Logger { return rl }
+func (rl recordingLogger) Error(message string)                    { rl.add(message) }
+func (rl recordingLogger) Warning(message string)                  { rl.add(message) }
+func (rl recordingLogger) Info(message string)                     { rl.add(message) }
+func (rl recordingLogger) Debug(message string)                    { rl.add(message) }
+
+func (rl recordingLogger) add(message string) {
+	rl.mx.Lock()
+	defer rl.mx.Unlock()
+	*rl.messages = append(*rl.messages, message)
+}
+
+func (rl recordingLogger) all() string {
+	rl.mx.Lock()
+	defer rl.mx.Unlock()
+	return strings.Join(*rl.messages, "\n")
+}
+
+func TestDefaultAuthModeIsDeprecatedQuery(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	server.AddTicket("1", testTicket)
+	conf := server.Conf()
+	conf.API.AuthMode = ""
+	log := newRecordingLogger()
+	bo := &BasicOTRS{}
+	bo.Initialise(log, conf)
+
+	if bo.AuthMode != AuthModeQuery {
+		t.Errorf("default auth mode '%v'", bo.AuthMode)
+	}
+	if !strings.Contains(log.all(), "Option 'API.AuthMode' not set") {
+		t.Errorf("no deprecation warning in log:\n%v", log.all())
+	}
+	if _, err := bo.GetTicketDetails("1"); err != nil {
+		t.Errorf("request in default mode - %v", err)
+	}
+}
+
+func TestSessionIDNotLogged(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	server.AddTicket("1", testTicket)
+	conf := server.Conf()
+	conf.API.AuthMode = AuthModeSession
+	log := newRecordingLogger()
+	bo := &BasicOTRS{}
+	bo.Initialise(log, conf)
+
+	_, err := bo.GetTicketDetails("1")
+	if err != nil {
+		t.Fatalf("get ticket - %v", err)
+	}
+	if bo.sessionID == "" {
+		t.Fatal("session not created")
+	}
+	if strings.Contains(log.all(), bo.sessionID) {
+		t.Errorf("SessionID '%v' in log:\n%v", bo.sessionID, log.all())
+	}
+	if !strings.Contains(log.all(), `"SessionID":"*********"`) {
+		t.Errorf("no masked SessionCreate reply in log:\n%v", log.all())
+	}
+}
+
+func TestMaskSessionID(t *testing.T) {
+	for body, expected := range map[string]string{
+		`{"SessionID":"abc123"}`:                `{"SessionID":"*********"}`,
+		`{"SessionID" : "abc123", "Other":"x"}`: `{"SessionID" : "*********", "Other":"x"}`,
+		`{"Ticket":[{"TicketID":"1"}]}`:         `{"Ticket":[{"TicketID":"1"}]}`,
+	} {
+		if masked := maskSessionID(body); masked != expected {
+			t.Errorf("maskSessionID(%v) = '%v', expected '%v'", body, masked, expected)
+		}
+	}
+}
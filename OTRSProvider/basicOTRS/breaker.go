@@ -0,0 +1,62 @@
+package basicOTRS
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"sync"
+	"time"
+)
+
+// Circuit breaker for OTRS requests.
+// Opened after threshold consecutive failures. After cooldown one trial request allowed.
+// Successful trial closes breaker, failed trial opens it for another cooldown.
+type circuitBreaker struct {
+	mx        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool // Trial request in flight.
+	Log       logger.Logger
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, logger logger.Logger) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, Log: logger}
+}
+
+// Return ErrOTRSUnavailable if request not allowed.
+func (b *circuitBreaker) allow() error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if time.Now().Before(b.openUntil) || b.trial {
+		return fmt.Errorf("%w: paused till '%v'", myErrors.ErrOTRSUnavailable, b.openUntil.Format(time.RFC3339))
+	}
+	b.trial = true
+	b.Log.Info("Send trial request to OTRS")
+	return nil
+}
+
+func (b *circuitBreaker) success() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.failures >= b.threshold {
+		b.Log.Info("OTRS available again. Resume requests")
+	}
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		b.Log.Error(fmt.Sprintf("OTRS failed '%v' times in a row. Pause requests till '%v'", b.failures, b.openUntil))
+	}
+}
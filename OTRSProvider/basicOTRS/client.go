@@ -0,0 +1,170 @@
+package basicOTRS
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTimeout          time.Duration = 10 * time.Second
+	DefaultMaxRetries       int           = 2
+	DefaultBreakerThreshold int           = 5
+	DefaultBreakerCooldown  time.Duration = 30 * time.Second
+	DefaultRetryInterval    time.Duration = 500 * time.Millisecond // First retry delay. Doubled after each retry, jitter added.
+)
+
+// Send request to OTRS operation and return body of successful response.
+// Timeouts and server errors retried with exponential backoff and jitter.
+// Return ErrOTRSUnavailable without request while circuit breaker open.
+func (bo *BasicOTRS) call(endpoint string, parameters url.Values) ([]byte, error) {
+	var err error
+	for attempt := 0; attempt <= bo.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := retryDelay(bo.RetryInterval, attempt)
+			bo.Log.Warning(fmt.Sprintf("Retry OTRS request in '%v' after error - '%v'", delay, err))
+			time.Sleep(delay)
+		}
+
+		err = bo.breaker.allow()
+		if err != nil {
+			return nil, err
+		}
+		var body []byte
+		body, err = bo.callOnce(endpoint, parameters)
+		if !isRetryable(err) {
+			bo.breaker.success()
+			return body, err
+		}
+		bo.breaker.failure()
+	}
+	return nil, err
+}
+
+// Return delay before retry attempt. Interval doubled for every next attempt and random jitter
+// up to same interval added so clients not retry simultaneously.
+func retryDelay(interval time.Duration, attempt int) time.Duration {
+	delay := interval << (attempt - 1)
+	return delay + time.Duration(rand.Int63n(int64(delay)))
+}
+
+// Send one request with credentials. Expired session renewed once.
+func (bo *BasicOTRS) callOnce(endpoint string, parameters url.Values) ([]byte, error) {
+	if bo.AuthMode != AuthModeSession {
+		request, err := bo.newRequest(endpoint, parameters, "")
+		if err != nil {
+			return nil, err
+		}
+		return bo.do(request)
+	}
+
+	sessionID, err := bo.session("")
+	if err != nil {
+		return nil, err
+	}
+	request, err := bo.newRequest(endpoint, parameters, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	body, err := bo.do(request)
+	if !errors.Is(err, myErrors.ErrOTRSAuthFailed) {
+		return body, err
+	}
+	sessionID, err = bo.session(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	request, err = bo.newRequest(endpoint, parameters, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return bo.do(request)
+}
+
+// Send request and classify result.
+func (bo *BasicOTRS) do(request *http.Request) ([]byte, error) {
+	response, err := bo.HTTPClient.Do(request)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("%w: %v", myErrors.ErrOTRSTimeout, maskPassword(err.Error(), bo.Password))
+		}
+		return nil, fmt.Errorf("%w: %v", myErrors.ErrOTRSServerError, maskPassword(err.Error(), bo.Password))
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("%w: %v", myErrors.ErrOTRSTimeout, err)
+		}
+		return nil, fmt.Errorf("%w: %v", myErrors.ErrOTRSServerError, err)
+	}
+	bo.Log.Debug(fmt.Sprintf("Response status '%v' body - '%v'", response.StatusCode, maskSessionID(string(body))))
+
+	// Not found status means wrong operation path or proxy error, not missing ticket.
+	switch {
+	case response.StatusCode == http.StatusUnauthorized, response.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%w: status '%v'", myErrors.ErrOTRSAuthFailed, response.StatusCode)
+	case response.StatusCode < 200 || response.StatusCode > 299:
+		return nil, fmt.Errorf("%w: status '%v'", myErrors.ErrOTRSServerError, response.StatusCode)
+	}
+
+	// GenericInterface reports operation errors in body with success status.
+	var errorFromJSON OTRSProvider.ErrorFromJSON
+	if json.Unmarshal(body, &errorFromJSON) == nil && errorFromJSON.Error.ErrorCode != "" {
+		return nil, classifyOTRSError(errorFromJSON.Error.ErrorCode, errorFromJSON.Error.ErrorMessage)
+	}
+	return body, nil
+}
+
+// Convert OTRS error code into typed error.
+// OTRS reports nonexistent ticket and ticket without permission alike as access denied,
+// so missing ticket confirmed by caller.
+func classifyOTRSError(code, message string) error {
+	switch {
+	case strings.HasSuffix(code, ".AuthFail"):
+		return fmt.Errorf("%w: %v - %v", myErrors.ErrOTRSAuthFailed, code, message)
+	case code == "TicketGet.AccessDenied":
+		return fmt.Errorf("%w: %v - %v", myErrors.ErrTicketAccessDenied, code, message)
+	case code == "TicketGet.NotValid":
+		return fmt.Errorf("%w: %v - %v", myErrors.ErrTicketNotFound, code, message)
+	default:
+		return fmt.Errorf("%w: %v - %v", myErrors.ErrOTRSServerError, code, message)
+	}
+}
+
+// Check if request may succeed on retry. Only failures of OTRS itself retried.
+func isRetryable(err error) bool {
+	return errors.Is(err, myErrors.ErrOTRSTimeout) || errors.Is(err, myErrors.ErrOTRSServerError)
+}
+
+// Hide password from URL in error text. Password in URL only in "query" auth mode.
+// URL-encoded password masked too, because special characters escaped in URL.
+func maskPassword(text, password string) string {
+	if password == "" {
+		return text
+	}
+	for _, form := range []string{password, url.QueryEscape(password), url.PathEscape(password)} {
+		text = strings.ReplaceAll(text, form, "*********")
+	}
+	return text
+}
+
+var sessionIDField = regexp.MustCompile(`("SessionID"\s*:\s*")[^"]*(")`)
+
+// Replace SessionID value in response body so SessionCreate reply can be logged.
+func maskSessionID(body string) string {
+	return sessionIDField.ReplaceAllString(body, "${1}*********${2}")
+}
@@ -0,0 +1,212 @@
+package cachedOTRS
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"golang.org/x/sync/singleflight"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ModuleName      string        = "OTRS Provider Cache"
+	DefaultTTL      time.Duration = 60 * time.Second // Ticket details lifetime if not configured.
+	ReportInterval  time.Duration = 5 * time.Minute  // Interval between stats reports and expired entries cleanup.
+	changeTimeDelta time.Duration = time.Second      // OTRS change time has seconds precision.
+)
+
+// Implement OTRSProvider interface over another provider.
+// Ticket details cached for TTL. Concurrent requests for same ticket coalesced into one OTRS request.
+type CachedOTRS struct {
+	Next OTRSProvider.OTRSProvider // Provider which requests OTRS.
+	TTL  time.Duration             // Zero or negative disables cache.
+	Log  logger.Logger
+
+	Location *time.Location // OTRS server timezone for change time from ticket update notifications.
+
+	mx            sync.Mutex
+	entries       map[string]cacheEntry
+	invalidatedAt map[string]time.Time // Last invalidation by ticket. Results of requests started before it not cached.
+	group         singleflight.Group
+
+	hits          uint64
+	lookups       uint64 // Requests not served from cache.
+	misses        uint64 // Requests sent to OTRS.
+	invalidations uint64
+}
+
+type cacheEntry struct {
+	ticket  OTRSProvider.TicketOTRS
+	fetched time.Time // Request start time.
+	expires time.Time
+}
+
+func (c *CachedOTRS) Initialise(logger logger.Logger, conf config.OTRSConf) {
+	c.Next.Initialise(logger, conf)
+	c.Log = logger.SetModuleName(OTRSProvider.ModuleName(ModuleName, conf.Name))
+
+	switch {
+	case conf.CacheTTL == 0:
+		c.TTL = DefaultTTL
+	case conf.CacheTTL < 0:
+		c.TTL = 0
+	default:
+		c.TTL = time.Duration(conf.CacheTTL) * time.Second
+	}
+	timezone := conf.Timezone
+	if timezone == "" {
+		timezone = OTRSProvider.DefaultTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Can't load timezone '%v' - '%v'. Use UTC", timezone, err))
+		location = time.UTC
+	}
+	c.Location = location
+	c.entries = make(map[string]cacheEntry)
+	c.invalidatedAt = make(map[string]time.Time)
+	if c.TTL > 0 {
+		go c.maintain()
+	}
+	c.Log.Debug(fmt.Sprintf("Initialisation complete. TTL '%v'", c.TTL))
+}
+
+// Return ticket details from cache or from OTRS if not cached or expired.
+func (c *CachedOTRS) GetTicketDetails(ticketID string) (OTRSProvider.TicketOTRS, error) {
+	if c.TTL <= 0 {
+		return c.Next.GetTicketDetails(ticketID)
+	}
+
+	now := time.Now()
+	c.mx.Lock()
+	entry, ok := c.entries[ticketID]
+	c.mx.Unlock()
+	if ok && now.Before(entry.expires) {
+		atomic.AddUint64(&c.hits, 1)
+		c.Log.Debug(fmt.Sprintf("Ticket '%v' served from cache", ticketID))
+		return entry.ticket, nil
+	}
+
+	atomic.AddUint64(&c.lookups, 1)
+	result, err, _ := c.group.Do(ticketID, func() (interface{}, error) {
+		atomic.AddUint64(&c.misses, 1)
+		started := time.Now()
+		ticket, err := c.Next.GetTicketDetails(ticketID)
+		if err != nil {
+			return ticket, err
+		}
+		c.store(ticketID, ticket, started)
+		return ticket, nil
+	})
+	return result.(OTRSProvider.TicketOTRS), err
+}
+
+// Search not cached. Results depend on criteria and change with every ticket update.
+func (c *CachedOTRS) SearchTickets(criteria OTRSProvider.SearchCriteria) ([]OTRSProvider.TicketOTRS, error) {
+	return c.Next.SearchTickets(criteria)
+}
+
+// Search not cached.
+func (c *CachedOTRS) SearchTicketIDs(criteria OTRSProvider.SearchCriteria) ([]string, error) {
+	return c.Next.SearchTicketIDs(criteria)
+}
+
+// Remove cached ticket if it fetched before provided change time in OTRS format.
+// Empty or invalid change time removes ticket unconditionally. Return true if ticket removed.
+func (c *CachedOTRS) Invalidate(ticketID, changedString string) bool {
+	if c.TTL <= 0 {
+		return false
+	}
+	var changed time.Time
+	if changedString != "" {
+		var err error
+		changed, err = OTRSProvider.ParseTime(changedString, c.Location)
+		if err != nil {
+			c.Log.Warning(fmt.Sprintf("Can't parse change time '%v' for ticket '%v' - '%v'", changedString, ticketID, err))
+		}
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	entry, ok := c.entries[ticketID]
+	if ok && !changed.IsZero() && !entry.fetched.Before(changed.Add(changeTimeDelta)) {
+		c.Log.Debug(fmt.Sprintf("Ticket '%v' cached after change at '%v'. Keep cache", ticketID, changed))
+		return false
+	}
+
+	// New requests must not join request started before change.
+	delete(c.entries, ticketID)
+	c.invalidatedAt[ticketID] = time.Now()
+	c.group.Forget(ticketID)
+	atomic.AddUint64(&c.invalidations, 1)
+	c.Log.Debug(fmt.Sprintf("Ticket '%v' removed from cache", ticketID))
+	return true
+}
+
+// Return cache counters.
+func (c *CachedOTRS) Stats() OTRSProvider.CacheStats {
+	c.mx.Lock()
+	entries := len(c.entries)
+	c.mx.Unlock()
+
+	stats := OTRSProvider.CacheStats{
+		Hits:          atomic.LoadUint64(&c.hits),
+		Misses:        atomic.LoadUint64(&c.misses),
+		Invalidations: atomic.LoadUint64(&c.invalidations),
+		Entries:       entries,
+	}
+	lookups := atomic.LoadUint64(&c.lookups)
+	if lookups > stats.Misses {
+		stats.Coalesced = lookups - stats.Misses
+	}
+	total := stats.Hits + stats.Coalesced + stats.Misses
+	if total > 0 {
+		stats.HitRate = float64(stats.Hits+stats.Coalesced) / float64(total)
+	}
+	return stats
+}
+
+// Save ticket unless it invalidated while request in flight.
+func (c *CachedOTRS) store(ticketID string, ticket OTRSProvider.TicketOTRS, started time.Time) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if invalidated, ok := c.invalidatedAt[ticketID]; ok && !started.After(invalidated) {
+		c.Log.Debug(fmt.Sprintf("Ticket '%v' invalidated while requested. Not cached", ticketID))
+		return
+	}
+	c.entries[ticketID] = cacheEntry{
+		ticket:  ticket,
+		fetched: started,
+		expires: started.Add(c.TTL),
+	}
+}
+
+// Periodically remove expired entries and report cache stats.
+func (c *CachedOTRS) maintain() {
+	ticker := time.NewTicker(ReportInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		c.mx.Lock()
+		for ticketID, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, ticketID)
+			}
+		}
+		for ticketID, invalidated := range c.invalidatedAt {
+			if now.Sub(invalidated) > c.TTL {
+				delete(c.invalidatedAt, ticketID)
+			}
+		}
+		c.mx.Unlock()
+
+		stats := c.Stats()
+		c.Log.Info(fmt.Sprintf(
+			"Cache stats. Entries '%v', hits '%v', coalesced '%v', misses '%v', invalidations '%v', hit rate '%.2f'",
+			stats.Entries, stats.Hits, stats.Coalesced, stats.Misses, stats.Invalidations, stats.HitRate,
+		))
+	}
+}
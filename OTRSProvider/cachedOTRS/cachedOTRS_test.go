@@ -0,0 +1,265 @@
+package cachedOTRS
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+)
+
+// Drop all messages.
+type quietLogger struct{}
+
+func (ql quietLogger) SetModuleName(name string) logger.Logger { return ql }
+func (quietLogger) Error(message string)                       {}
+func (quietLogger) Warning(message string)                     {}
+func (quietLogger) Info(message string)                        {}
+func (quietLogger) Debug(message string)                       {}
+
+// Provider counting ticket requests. Requests wait for release if release channel set.
+type countingOTRS struct {
+	OTRSProvider.OTRSProvider
+	requests uint64
+	started  chan struct{} // Receives value when request started. Optional.
+	release  chan struct{} // Requests wait for value or close. Optional.
+	err      error
+}
+
+func (co *countingOTRS) Initialise(logger logger.Logger, conf config.OTRSConf) {}
+
+func (co *countingOTRS) GetTicketDetails(ticketID string) (OTRSProvider.TicketOTRS, error) {
+	request := atomic.AddUint64(&co.requests, 1)
+	if co.started != nil {
+		co.started <- struct{}{}
+	}
+	if co.release != nil {
+		<-co.release
+	}
+	if co.err != nil {
+		return OTRSProvider.TicketOTRS{}, co.err
+	}
+	return OTRSProvider.TicketOTRS{TicketNumber: ticketID, Title: fmt.Sprint("request ", request)}, nil
+}
+
+func (co *countingOTRS) count() uint64 {
+	return atomic.LoadUint64(&co.requests)
+}
+
+func newCache(next *countingOTRS, ttl time.Duration) *CachedOTRS {
+	return &CachedOTRS{
+		Next:          next,
+		TTL:           ttl,
+		Log:           quietLogger{},
+		Location:      time.UTC,
+		entries:       make(map[string]cacheEntry),
+		invalidatedAt: make(map[string]time.Time),
+	}
+}
+
+func getTicket(t *testing.T, c *CachedOTRS, ticketID string) OTRSProvider.TicketOTRS {
+	t.Helper()
+	ticket, err := c.GetTicketDetails(ticketID)
+	if err != nil {
+		t.Fatalf("get ticket '%v' - %v", ticketID, err)
+	}
+	return ticket
+}
+
+func TestInitialiseTTL(t *testing.T) {
+	for _, c := range []struct {
+		cacheTTL int
+		want     time.Duration
+	}{
+		{0, DefaultTTL},
+		{-1, 0},
+		{30, 30 * time.Second},
+	} {
+		cache := &CachedOTRS{Next: &countingOTRS{}}
+		cache.Initialise(quietLogger{}, config.OTRSConf{CacheTTL: c.cacheTTL})
+		if cache.TTL != c.want {
+			t.Errorf("cache TTL %v configured as '%v', want '%v'", c.cacheTTL, cache.TTL, c.want)
+		}
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	next := &countingOTRS{}
+	c := newCache(next, 100*time.Millisecond)
+
+	first := getTicket(t, c, "1")
+	if cached := getTicket(t, c, "1"); cached != first || next.count() != 1 {
+		t.Errorf("ticket '%+v' requested %v times, want cached '%+v'", cached, next.count(), first)
+	}
+	getTicket(t, c, "2")
+	if next.count() != 2 {
+		t.Errorf("other ticket served from cache")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if expired := getTicket(t, c, "1"); expired == first || next.count() != 3 {
+		t.Errorf("expired ticket '%+v' served from cache", expired)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 3 || stats.Coalesced != 0 || stats.Entries != 2 {
+		t.Errorf("stats %+v, want 1 hit, 3 misses and 2 entries", stats)
+	}
+	if stats.HitRate != 0.25 {
+		t.Errorf("hit rate %v, want 0.25", stats.HitRate)
+	}
+}
+
+func TestCacheErrorNotCached(t *testing.T) {
+	next := &countingOTRS{err: errors.New("OTRS unavailable")}
+	c := newCache(next, time.Minute)
+
+	for n := 0; n < 2; n++ {
+		if _, err := c.GetTicketDetails("1"); err == nil {
+			t.Fatal("error not returned")
+		}
+	}
+	if next.count() != 2 {
+		t.Errorf("ticket requested %v times, want 2", next.count())
+	}
+	if stats := c.Stats(); stats.Entries != 0 {
+		t.Errorf("%v entries after errors", stats.Entries)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	next := &countingOTRS{}
+	c := newCache(next, 0)
+
+	getTicket(t, c, "1")
+	getTicket(t, c, "1")
+	if next.count() != 2 {
+		t.Errorf("ticket requested %v times, want 2", next.count())
+	}
+	if c.Invalidate("1", "") {
+		t.Error("ticket invalidated in disabled cache")
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	format := func(t time.Time) string { return t.UTC().Format(OTRSProvider.TimeLayout) }
+	cases := []struct {
+		name    string
+		changed func(fetched time.Time) string
+		want    bool
+	}{
+		{"no change time", func(time.Time) string { return "" }, true},
+		{"invalid change time", func(time.Time) string { return "yesterday" }, true},
+		{"changed after fetch", func(fetched time.Time) string { return format(fetched.Add(2 * time.Second)) }, true},
+		// Change time truncated to seconds, so change in second of fetch may be after it.
+		{"changed in second of fetch", func(fetched time.Time) string { return format(fetched) }, true},
+		{"changed before fetch", func(fetched time.Time) string { return format(fetched.Add(-2 * time.Second)) }, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			next := &countingOTRS{}
+			cache := newCache(next, time.Minute)
+			getTicket(t, cache, "1")
+			fetched := cache.entries["1"].fetched
+
+			if removed := cache.Invalidate("1", c.changed(fetched)); removed != c.want {
+				t.Errorf("ticket removed %v, want %v", removed, c.want)
+			}
+			getTicket(t, cache, "1")
+			if requests := next.count(); (requests == 2) != c.want {
+				t.Errorf("ticket requested %v times after invalidation %v", requests, c.want)
+			}
+		})
+	}
+
+	// Invalidation of ticket not in cache counted.
+	cache := newCache(&countingOTRS{}, time.Minute)
+	if !cache.Invalidate("1", "") || cache.Stats().Invalidations != 1 {
+		t.Errorf("not cached ticket invalidation not counted - %+v", cache.Stats())
+	}
+}
+
+func TestConcurrentRequestsCoalesced(t *testing.T) {
+	next := &countingOTRS{release: make(chan struct{})}
+	c := newCache(next, time.Minute)
+	const requests = 10
+
+	var wg sync.WaitGroup
+	tickets := make([]OTRSProvider.TicketOTRS, requests)
+	for n := 0; n < requests; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			tickets[n] = getTicket(t, c, "1")
+		}(n)
+	}
+	// Wait till all requests join request in flight.
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadUint64(&c.lookups) < requests && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	if next.count() != 1 {
+		t.Errorf("ticket requested %v times, want 1", next.count())
+	}
+	for n, ticket := range tickets {
+		if ticket != tickets[0] {
+			t.Errorf("request %v got '%+v', want '%+v'", n, ticket, tickets[0])
+		}
+	}
+	if stats := c.Stats(); stats.Misses != 1 || stats.Coalesced != requests-1 || stats.HitRate != 0.9 {
+		t.Errorf("stats %+v, want 1 miss and %v coalesced", stats, requests-1)
+	}
+}
+
+// Ticket changed while requested from OTRS: response may be outdated, so it not cached
+// and new requests not joined to request in flight.
+func TestInvalidateDuringRequest(t *testing.T) {
+	next := &countingOTRS{started: make(chan struct{}, 2), release: make(chan struct{}, 2)}
+	c := newCache(next, time.Minute)
+
+	done := make(chan OTRSProvider.TicketOTRS)
+	go func() { done <- getTicket(t, c, "1") }()
+	<-next.started
+	c.Invalidate("1", "")
+
+	go func() { done <- getTicket(t, c, "1") }()
+	<-next.started
+	next.release <- struct{}{}
+	next.release <- struct{}{}
+	<-done
+	<-done
+	if next.count() != 2 {
+		t.Fatalf("ticket requested %v times, want 2", next.count())
+	}
+
+	// Only request started after invalidation cached.
+	getTicket(t, c, "1")
+	if next.count() != 2 {
+		t.Errorf("ticket requested %v times, want 2", next.count())
+	}
+
+	// Response of request started before invalidation not cached.
+	c.Invalidate("1", "")
+	next.started = nil
+	next.release = make(chan struct{})
+	go func() { done <- getTicket(t, c, "1") }()
+	for atomic.LoadUint64(&next.requests) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Invalidate("1", "")
+	close(next.release)
+	<-done
+	next.release = nil
+	getTicket(t, c, "1")
+	if next.count() != 4 {
+		t.Errorf("ticket requested %v times, want 4", next.count())
+	}
+}
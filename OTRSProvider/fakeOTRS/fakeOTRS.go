@@ -0,0 +1,309 @@
+// Package fakeOTRS provides in-process OTRS GenericInterface server for tests and local runs.
+// Server answers TicketGet, TicketSearch and SessionCreate like GenericTicketConnectorREST web service
+// and can inject delays, HTTP errors and OTRS error objects.
+package fakeOTRS
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Login        string = "bot"
+	Password     string = "secret"
+	Token        string = "token"
+	TicketPath   string = "/otrs/nph-genericinterface.pl/Webservice/GenericTicketConnectorREST/Ticket/"
+	SearchPath   string = "/otrs/nph-genericinterface.pl/Webservice/GenericTicketConnectorREST/TicketSearch"
+	SessionPath  string = "/otrs/nph-genericinterface.pl/Webservice/GenericTicketConnectorREST/Session"
+	TicketPrefix string = "/otrs/index.pl?Action=AgentTicketZoom;TicketID="
+)
+
+// Injected failure for one request.
+type Failure struct {
+	Status    int           // HTTP status of response. 200 if not set.
+	ErrorCode string        // OTRS error code in response body, e.g. "TicketGet.AuthFail".
+	Delay     time.Duration // Delay before response.
+}
+
+// Fake OTRS server. Safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mx       sync.Mutex
+	tickets  map[string]OTRSProvider.TicketOTRS
+	failures []Failure
+	delay    time.Duration
+	requests int
+	sessions map[string]bool
+	lastID   int
+}
+
+// Start new fake OTRS server. Close it after use.
+func NewServer() *Server {
+	s := &Server{
+		tickets:  make(map[string]OTRSProvider.TicketOTRS),
+		sessions: make(map[string]bool),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Return OTRS configuration pointing to fake server.
+// Server accepts credentials in any auth mode, set AuthMode in returned configuration to choose one.
+func (s *Server) Conf() config.OTRSConf {
+	host := strings.TrimPrefix(s.URL, "http://")
+	return config.OTRSConf{
+		Host:            host,
+		TicketURLPrefix: fmt.Sprint(s.URL, TicketPrefix),
+		Timezone:        "UTC",
+		API: config.OTRSAPI{
+			Login:                   Login,
+			Password:                Password,
+			Token:                   Token,
+			SessionCreatePath:       SessionPath,
+			Protocol:                "http",
+			GetTicketDetailListPath: TicketPath,
+			TicketSearchPath:        SearchPath,
+		},
+	}
+}
+
+// Add or replace ticket.
+func (s *Server) AddTicket(ticketID string, ticket OTRSProvider.TicketOTRS) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.tickets[ticketID] = ticket
+}
+
+// Remove ticket. Requests for it answered as for nonexistent ticket.
+func (s *Server) RemoveTicket(ticketID string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.tickets, ticketID)
+}
+
+// Queue failures for next requests in order.
+func (s *Server) Fail(failures ...Failure) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.failures = append(s.failures, failures...)
+}
+
+// Set delay for every response.
+func (s *Server) SetDelay(delay time.Duration) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.delay = delay
+}
+
+// Invalidate all sessions. Next request with session fails as with expired session.
+func (s *Server) ExpireSessions() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.sessions = make(map[string]bool)
+}
+
+// Return number of received requests.
+func (s *Server) Requests() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.requests
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mx.Lock()
+	s.requests++
+	failure := Failure{Delay: s.delay}
+	if len(s.failures) > 0 {
+		failure = s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	s.mx.Unlock()
+
+	time.Sleep(failure.Delay)
+	if failure.Status != 0 && failure.Status != http.StatusOK {
+		w.WriteHeader(failure.Status)
+		return
+	}
+
+	query, err := parameters(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	operation := "TicketGet"
+	switch r.URL.Path {
+	case SearchPath:
+		operation = "TicketSearch"
+	case SessionPath:
+		operation = "SessionCreate"
+	}
+	switch {
+	case failure.ErrorCode != "":
+		writeError(w, failure.ErrorCode, "Injected failure")
+	case r.URL.Path == SessionPath:
+		s.createSession(w, query)
+	case !s.authorised(r, query):
+		writeError(w, fmt.Sprint(operation, ".AuthFail"), "Authorization failing!")
+	case r.URL.Path == SearchPath:
+		s.search(w, query)
+	case strings.HasPrefix(r.URL.Path, TicketPath):
+		s.ticket(w, strings.TrimPrefix(r.URL.Path, TicketPath))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Check credentials passed in any supported way.
+func (s *Server) authorised(r *http.Request, query url.Values) bool {
+	if login, password, ok := r.BasicAuth(); ok {
+		return login == Login && password == Password
+	}
+	if header := r.Header.Get("Authorization"); header != "" {
+		return header == fmt.Sprint("Bearer ", Token)
+	}
+	if sessionID := query.Get("SessionID"); sessionID != "" {
+		s.mx.Lock()
+		defer s.mx.Unlock()
+		return s.sessions[sessionID]
+	}
+	return query.Get("UserLogin") == Login && query.Get("Password") == Password
+}
+
+// Answer SessionCreate.
+func (s *Server) createSession(w http.ResponseWriter, query url.Values) {
+	if query.Get("UserLogin") != Login || query.Get("Password") != Password {
+		writeError(w, "SessionCreate.AuthFail", "Authorization failing!")
+		return
+	}
+	s.mx.Lock()
+	s.lastID++
+	sessionID := fmt.Sprint("session", s.lastID)
+	s.sessions[sessionID] = true
+	s.mx.Unlock()
+	writeJSON(w, map[string]string{"SessionID": sessionID})
+}
+
+// Answer TicketGet. OTRS reports nonexistent ticket as access denied.
+func (s *Server) ticket(w http.ResponseWriter, ticketID string) {
+	s.mx.Lock()
+	ticket, ok := s.tickets[ticketID]
+	s.mx.Unlock()
+	if !ok {
+		writeError(w, "TicketGet.AccessDenied", "User does not have access to the ticket!")
+		return
+	}
+	writeJSON(w, OTRSProvider.TicketsFromJSON{Ticket: []OTRSProvider.TicketOTRS{ticket}})
+}
+
+// Answer TicketSearch. Text matched against title. OTRS returns empty object if nothing found.
+// Time filters compared with Created and Changed ticket fields as strings in OTRS layout.
+// Tickets sorted by age from newest like with "SortBy=Age" and "OrderBy=Up".
+func (s *Server) search(w http.ResponseWriter, query url.Values) {
+	text := strings.Trim(first(query["Subject"]), "*")
+	customerID := first(query["CustomerID"])
+	stateTypes := query["StateType"]
+	priorities := query["PriorityIDs"]
+	createdAfter := first(query["TicketCreateTimeNewerDate"])
+	createdBefore := first(query["TicketCreateTimeOlderDate"])
+	changedAfter := first(query["TicketChangeTimeNewerDate"])
+	ticketIDs := query["TicketID"]
+
+	s.mx.Lock()
+	ticketIDList := make([]string, 0, len(s.tickets))
+	for ticketID, ticket := range s.tickets {
+		switch {
+		case len(ticketIDs) > 0 && !contains(ticketIDs, ticketID):
+		case text != "" && !strings.Contains(strings.ToLower(ticket.Title), strings.ToLower(text)):
+		case customerID != "" && ticket.CustomerID != customerID:
+		case len(stateTypes) > 0 && !contains(stateTypes, ticket.StateType):
+		case len(priorities) > 0 && !contains(priorities, strings.SplitN(ticket.Priority, " ", 2)[0]):
+		case createdAfter != "" && ticket.Created <= createdAfter:
+		case createdBefore != "" && ticket.Created > createdBefore:
+		case changedAfter != "" && ticket.Changed <= changedAfter:
+		default:
+			ticketIDList = append(ticketIDList, ticketID)
+		}
+	}
+
+	if len(ticketIDList) == 0 {
+		s.mx.Unlock()
+		writeJSON(w, struct{}{})
+		return
+	}
+	sort.Slice(ticketIDList, func(i, j int) bool {
+		left, right := s.tickets[ticketIDList[i]], s.tickets[ticketIDList[j]]
+		if left.Created != right.Created {
+			return left.Created > right.Created
+		}
+		return ticketIDList[i] < ticketIDList[j]
+	})
+	s.mx.Unlock()
+
+	if limit, err := strconv.Atoi(first(query["Limit"])); err == nil && limit > 0 && len(ticketIDList) > limit {
+		ticketIDList = ticketIDList[:limit]
+	}
+	writeJSON(w, OTRSProvider.SearchResultFromJSON{TicketID: ticketIDList})
+}
+
+// Return request parameters from query string or JSON body.
+func parameters(r *http.Request) (url.Values, error) {
+	query := r.URL.Query()
+	if r.Method != http.MethodPost {
+		return query, nil
+	}
+	var body map[string]interface{}
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range body {
+		switch value := value.(type) {
+		case []interface{}:
+			for _, v := range value {
+				query.Add(key, fmt.Sprint(v))
+			}
+		default:
+			query.Set(key, fmt.Sprint(value))
+		}
+	}
+	return query, nil
+}
+
+func writeError(w http.ResponseWriter, code, message string) {
+	var response OTRSProvider.ErrorFromJSON
+	response.Error.ErrorCode = code
+	response.Error.ErrorMessage = message
+	writeJSON(w, response)
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
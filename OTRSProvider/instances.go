@@ -0,0 +1,135 @@
+package OTRSProvider
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"sync"
+)
+
+const DetailsWorkers int = 8 // Concurrent OTRS requests for ticket list details.
+
+// Named OTRS instances served by bot. First added instance is default.
+// Events stored before instances introduced have empty instance name and belong to default instance.
+type Instances struct {
+	names     []string
+	providers map[string]OTRSProvider
+}
+
+func NewInstances() *Instances {
+	return &Instances{providers: make(map[string]OTRSProvider)}
+}
+
+// Add initialised provider for instance.
+func (i *Instances) Add(name string, provider OTRSProvider) {
+	if _, ok := i.providers[name]; !ok {
+		i.names = append(i.names, name)
+	}
+	i.providers[name] = provider
+}
+
+// Return provider for instance. Empty name means default instance.
+func (i *Instances) Get(name string) (OTRSProvider, error) {
+	if name == "" {
+		name = i.Default()
+	}
+	provider, ok := i.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%v'", myErrors.ErrUnknownOTRSInstance, name)
+	}
+	return provider, nil
+}
+
+// Return name of default instance.
+func (i *Instances) Default() string {
+	if len(i.names) == 0 {
+		return ""
+	}
+	return i.names[0]
+}
+
+// Return instance names in configuration order.
+func (i *Instances) Names() []string {
+	return append([]string(nil), i.names...)
+}
+
+// Return ticket details from instance. Empty name means default instance.
+func (i *Instances) GetTicketDetails(name, ticketID string) (TicketOTRS, error) {
+	provider, err := i.Get(name)
+	if err != nil {
+		return TicketOTRS{}, err
+	}
+	return provider.GetTicketDetails(ticketID)
+}
+
+// Ticket in named OTRS instance. Empty instance means default instance.
+type TicketRef struct {
+	Instance string
+	TicketID string
+}
+
+// Ticket details or error of request for one ticket of list.
+type TicketResult struct {
+	Ticket TicketOTRS
+	Err    error
+}
+
+// Return details for every ticket of list in same order. Requests sent by at most DetailsWorkers
+// goroutines through instance providers, so cached tickets not requested again.
+func (i *Instances) GetTicketDetailsList(refs []TicketRef) []TicketResult {
+	results := make([]TicketResult, len(refs))
+	ForEachConcurrently(len(refs), func(index int) {
+		ticket, err := i.GetTicketDetails(refs[index].Instance, refs[index].TicketID)
+		results[index] = TicketResult{Ticket: ticket, Err: err}
+	})
+	return results
+}
+
+// Call job for every index from 0 to count-1 in at most DetailsWorkers goroutines and wait for all calls.
+func ForEachConcurrently(count int, job func(index int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	workers := DetailsWorkers
+	if count < workers {
+		workers = count
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				job(index)
+			}
+		}()
+	}
+	for index := 0; index < count; index++ {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// Search tickets in every instance. Instances which failed skipped, first error returned
+// only if all instances failed.
+func (i *Instances) SearchTickets(criteria SearchCriteria) ([]TicketOTRS, error) {
+	var result []TicketOTRS
+	var firstErr error
+	failed := 0
+	for _, name := range i.names {
+		tickets, err := i.providers[name].SearchTickets(criteria)
+		if err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		result = append(result, tickets...)
+	}
+	if failed == len(i.names) && firstErr != nil {
+		return nil, firstErr
+	}
+	if criteria.Limit > 0 && len(result) > criteria.Limit {
+		result = result[:criteria.Limit]
+	}
+	return result, nil
+}
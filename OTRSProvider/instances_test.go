@@ -0,0 +1,105 @@
+package OTRSProvider
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+)
+
+// Requests in flight to all providers.
+type flightCounter struct {
+	mx       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+// Return ticket with number equal to ticket ID and count requests in flight.
+type slowProvider struct {
+	name    string
+	counter *flightCounter
+}
+
+func (sp *slowProvider) Initialise(logger logger.Logger, conf config.OTRSConf) {}
+
+func (sp *slowProvider) GetTicketDetails(ticketID string) (TicketOTRS, error) {
+	counter := sp.counter
+	counter.mx.Lock()
+	counter.inFlight++
+	if counter.inFlight > counter.peak {
+		counter.peak = counter.inFlight
+	}
+	counter.mx.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	counter.mx.Lock()
+	counter.inFlight--
+	counter.mx.Unlock()
+
+	if ticketID == "missing" {
+		return TicketOTRS{}, errors.New("not found")
+	}
+	return TicketOTRS{TicketNumber: ticketID, Instance: sp.name}, nil
+}
+
+func (sp *slowProvider) SearchTickets(criteria SearchCriteria) ([]TicketOTRS, error) {
+	return nil, nil
+}
+
+func (sp *slowProvider) SearchTicketIDs(criteria SearchCriteria) ([]string, error) {
+	return nil, nil
+}
+
+func TestGetTicketDetailsList(t *testing.T) {
+	counter := &flightCounter{}
+	main, second := &slowProvider{name: "main", counter: counter}, &slowProvider{name: "second", counter: counter}
+	instances := NewInstances()
+	instances.Add("main", main)
+	instances.Add("second", second)
+
+	refs := make([]TicketRef, 0, 40)
+	for n := 0; n < 40; n++ {
+		instance := "main"
+		switch {
+		case n%2 == 1:
+			instance = "second"
+		case n%10 == 0:
+			instance = ""
+		}
+		refs = append(refs, TicketRef{Instance: instance, TicketID: string(rune('A' + n))})
+	}
+	refs = append(refs, TicketRef{Instance: "main", TicketID: "missing"}, TicketRef{Instance: "unknown", TicketID: "B"})
+
+	results := instances.GetTicketDetailsList(refs)
+
+	if len(results) != len(refs) {
+		t.Fatalf("%v results for %v tickets", len(results), len(refs))
+	}
+	for index, ref := range refs[:40] {
+		want := ref.Instance
+		if want == "" {
+			want = "main"
+		}
+		got := results[index]
+		if got.Err != nil || got.Ticket.TicketNumber != ref.TicketID || got.Ticket.Instance != want {
+			t.Errorf("result %v is %+v, want ticket '%v' from '%v'", index, got, ref.TicketID, want)
+		}
+	}
+	if results[40].Err == nil {
+		t.Error("error of missing ticket lost")
+	}
+	if results[41].Err == nil {
+		t.Error("unknown instance accepted")
+	}
+	if counter.peak > DetailsWorkers || counter.peak < 2 {
+		t.Errorf("peak concurrency %v, want concurrent requests up to %v", counter.peak, DetailsWorkers)
+	}
+}
+
+func TestGetTicketDetailsListEmpty(t *testing.T) {
+	if results := NewInstances().GetTicketDetailsList(nil); len(results) != 0 {
+		t.Errorf("results for empty list - %+v", results)
+	}
+}
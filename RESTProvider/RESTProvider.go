@@ -3,12 +3,17 @@ package RESTProvider
 import (
 	"context"
 	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
 	"github.com/Sarraksh/otrs-echo-bot/event"
+	"net/http"
 )
 
 type RESTProvider interface {
-	Initialise(logger logger.Logger, db *DBProvider.DBProvider)
+	Initialise(logger logger.Logger, db *DBProvider.DBProvider, otrs *OTRSProvider.Instances, otrsConf []config.OTRSConf, restConf config.RESTConf)
 	PrepareListener(eventProcessor *event.Processor)
+	AddHandler(method, path string, handler http.Handler)
+	AddAdminHandler(method, path string, handler http.Handler)
 	Listen(ctx context.Context, cancel context.CancelFunc) error
 }
@@ -2,10 +2,15 @@ package echoREST
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
 	"github.com/Sarraksh/otrs-echo-bot/event"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
@@ -14,23 +19,59 @@ import (
 	"time"
 )
 
-const ModuleName string = "REST Provider ECHO"
+const (
+	ModuleName     string = "REST Provider ECHO"
+	channelInvoker string = "newticket" // Channel of events created by ticket notifications from OTRS invoker.
+
+	IdempotencyKeyHeader string = "Idempotency-Key" // Header with unique key of notification delivery.
+	IdempotencyKeyField  string = "key"             // Form field with delivery key if header not set.
+)
+
+// Result of ticket notification processing in response.
+const (
+	ResultCreated string = "created" // New event created.
+	ResultExists  string = "exists"  // Event for ticket already exists or delivery already processed.
+	ResultFailed  string = "failed"  // Event not saved. OTRS invoker should retry.
+)
 
 type EchoREST struct {
 	Instance *echo.Echo
 	Log      logger.Logger
 	DB       *DBProvider.DBProvider
+	OTRS     *OTRSProvider.Instances
+	Routes   map[string]string // OTRS instance name by route for ticket notifications.
+
+	adminToken string // Bearer token of admin API. Admin API disabled if empty.
 }
 
 // For marshal response to OTRS.
 type ResponseToOTRS struct {
 	TicketID string
+	Result   string
+	EventID  int64 `json:",omitempty"`
 }
 
-// Initialise echoREST module.
-func (eREST *EchoREST) Initialise(logger logger.Logger, db *DBProvider.DBProvider) {
+// Initialise echoREST module. Default instance notifications accepted on "/newticket" if route not configured,
+// other instances only on own route. Instance of notification defined only by route, so sender can't
+// attribute ticket to other instance. Admin API available only with configured token.
+func (eREST *EchoREST) Initialise(logger logger.Logger, db *DBProvider.DBProvider, otrs *OTRSProvider.Instances, otrsConf []config.OTRSConf, restConf config.RESTConf) {
 	eREST.Log = logger.SetModuleName(ModuleName)
 	eREST.DB = db
+	eREST.OTRS = otrs
+	eREST.adminToken = restConf.AdminToken
+	if eREST.adminToken == "" {
+		eREST.Log.Info("Admin token not set. Admin API disabled")
+	}
+	eREST.Routes = make(map[string]string, len(otrsConf))
+	for i, conf := range otrsConf {
+		path := conf.WebhookPath
+		if path == "" && i == 0 {
+			path = config.DefaultOTRSWebhookPath
+		}
+		if path != "" {
+			eREST.Routes[path] = conf.Name
+		}
+	}
 }
 
 // Prepare http listener.
@@ -40,59 +81,189 @@ func (eREST *EchoREST) PrepareListener(eventProcessor *event.Processor) {
 	e := echo.New()             // Echo instance
 	e.Use(middleware.Logger())  // Middleware
 	e.Use(middleware.Recover()) // Middleware
+	eREST.Instance = e
+
+	// Handle requests with new event from OTRS instances.
+	for path, instance := range eREST.Routes {
+		eREST.Log.Debug(fmt.Sprintf("Accept ticket notifications from OTRS instance '%v' on '%v'", instance, path))
+		e.POST(path, eREST.newTicketHandler(eventProcessor, instance))
+	}
+
+	// Ticket details cache counters. Default instance if "instance" parameter not provided.
+	eREST.addAdminRoute(http.MethodGet, "/api/v1/metrics/otrs-cache", func(c echo.Context) error {
+		provider, err := eREST.OTRS.Get(c.QueryParam("instance"))
+		if err != nil {
+			return c.NoContent(http.StatusNotFound)
+		}
+		cache, ok := provider.(OTRSProvider.TicketCache)
+		if !ok {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.JSON(http.StatusOK, cache.Stats())
+	})
+
+	// History of OTRS event.
+	eREST.addAdminRoute(http.MethodGet, "/api/v1/events/:id/timeline", eREST.timelineHandler)
+
+	eREST.Log.Debug(fmt.Sprintf("REST instance initialised"))
+}
 
-	// Handle requests with new event from OTRS.
-	pathNewTicket := "newticket"
-	e.POST(fmt.Sprint("/", pathNewTicket), func(c echo.Context) error {
+// Return handler for ticket notifications bound to OTRS instance.
+func (eREST *EchoREST) newTicketHandler(eventProcessor *event.Processor, routeInstance string) echo.HandlerFunc {
+	return func(c echo.Context) error {
 		id := c.FormValue("id")
-		eREST.Log.Debug(fmt.Sprintf("Recived new event from '%+v' with ticket id /'%+v'", pathNewTicket, id))
+		instance := routeInstance
+		eREST.Log.Debug(fmt.Sprintf("Recived new event from '%+v' for instance '%v' with ticket id /'%+v'", c.Path(), instance, id))
 
 		// Parse text to integer end response with error if fail.
 		idInt, err := strconv.Atoi(id)
 		if err != nil {
-			c.Response().Header().Set("ResponseSuccess", "0")                             // Needed by OTRS invoker
-			c.Response().Header().Set("ResponseErrorMessage", "Invalid id field content") // Needed by OTRS invoker
-			responseBody := ResponseToOTRS{
-				TicketID: id,
-			}
-			data, err := json.Marshal(&responseBody)
-			if err != nil {
-				eREST.Log.Error(fmt.Sprintf("Can't marshal body for response to OTRS - '%+v'", err))
-			}
-			eREST.Log.Debug(fmt.Sprintf("Send response with body - '%+v'", string(data)))
-			return c.JSONBlob(http.StatusBadRequest, data)
+			return eREST.respond(c, http.StatusBadRequest, ResponseToOTRS{TicketID: id, Result: ResultFailed}, "Invalid id field content")
+		}
+		provider, err := eREST.OTRS.Get(instance)
+		if err != nil {
+			eREST.Log.Warning(fmt.Sprintf("Event for ticket '%v' rejected - '%v'", id, err))
+			return eREST.respond(c, http.StatusBadRequest, ResponseToOTRS{TicketID: id, Result: ResultFailed}, "Unknown OTRS instance")
+		}
+
+		// Ticket changed in OTRS. Optional "changed" field contains ticket change time.
+		if cache, ok := provider.(OTRSProvider.TicketCache); ok {
+			cache.Invalidate(id, c.FormValue("changed"))
 		}
 
 		// Save data into DB.
-		// If event already exist write nothing.
-		db := *eREST.DB
-		eventExist, err := db.OTRSEventIsExistsWithTicketIDAndType(int64(idInt), pathNewTicket)
-		if !eventExist {
-			err = db.OTRSEventCreateNew(pathNewTicket, pathNewTicket, int64(idInt))
+		// If event already exist or delivery already processed write nothing.
+		key := c.Request().Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			key = c.FormValue(IdempotencyKeyField)
+		}
+		eventID, created, err := (*eREST.DB).OTRSEventCreateOnce(channelInvoker, event.TypeNewTicket, instance, int64(idInt), key)
+		if err != nil {
+			eREST.Log.Error(fmt.Sprintf("Can't save event for ticket '%v' from instance '%v' - '%v'", id, instance, err))
+			return eREST.respond(c, http.StatusInternalServerError, ResponseToOTRS{TicketID: id, Result: ResultFailed}, "Event not saved")
+		}
+		response := ResponseToOTRS{TicketID: id, Result: ResultExists, EventID: eventID}
+		if !created {
+			eREST.Log.Debug(fmt.Sprintf("Event for ticket '%v' from instance '%v' already exists", id, instance))
+			return eREST.respond(c, http.StatusOK, response, "")
 		}
+		response.Result = ResultCreated
 
 		// Invoke event processor
 		eventProcessor.ProcessEvent()
 
-		c.Response().Header().Set("ResponseSuccess", "1")     // Needed by OTRS invoker
-		c.Response().Header().Set("ResponseErrorMessage", "") // Needed by OTRS invoker
-		responseBody := ResponseToOTRS{
-			TicketID: id,
-		}
-		data, err := json.Marshal(&responseBody)
-		if err != nil {
-			eREST.Log.Error(fmt.Sprintf("Can't marshal body for response to OTRS - '%+v'", err))
-		}
-		eREST.Log.Debug(fmt.Sprintf("Send response with body - '%+v'", string(data)))
-		return c.JSONBlob(http.StatusOK, data)
-	},
-	) // Route
+		return eREST.respond(c, http.StatusOK, response, "")
+	}
+}
 
-	eREST.Log.Debug(fmt.Sprintf("REST instance initialised"))
-	eREST.Instance = e
+// Event with history in order of occurrence. Time in RFC 3339 format.
+type timelineResponse struct {
+	ID           int64           `json:"id"`
+	Status       string          `json:"status"`
+	Type         string          `json:"type"`
+	Instance     string          `json:"instance,omitempty"`
+	TicketID     int64           `json:"ticketID"`
+	TicketNumber string          `json:"ticketNumber,omitempty"`
+	Created      string          `json:"created"`
+	Finished     string          `json:"finished,omitempty"`
+	Timeline     []timelineEntry `json:"timeline"`
+}
+
+type timelineEntry struct {
+	Time    string `json:"time"`
+	Kind    string `json:"kind"`
+	Actor   string `json:"actor,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
+// Return event with history by event ID.
+func (eREST *EchoREST) timelineHandler(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	db := *eREST.DB
+	otrsEvent, err := db.OTRSEventGetByID(id)
+	if errors.Is(err, myErrors.ErrEventNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		eREST.Log.Error(fmt.Sprintf("Can't get event '%v' - '%v'", id, err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	historyList, err := db.EventHistoryGetByEvent(id)
+	if err != nil {
+		eREST.Log.Error(fmt.Sprintf("Can't get history of event '%v' - '%v'", id, err))
+		return c.NoContent(http.StatusInternalServerError)
+	}
+
+	response := timelineResponse{
+		ID:           otrsEvent.ID,
+		Status:       otrsEvent.Status,
+		Type:         otrsEvent.Type,
+		Instance:     otrsEvent.Instance,
+		TicketID:     otrsEvent.TicketID,
+		TicketNumber: otrsEvent.TicketNumber,
+		Created:      time.Unix(otrsEvent.Created, 0).Format(time.RFC3339),
+		Timeline:     make([]timelineEntry, 0, len(historyList)),
+	}
+	if otrsEvent.Finished != 0 {
+		response.Finished = time.Unix(otrsEvent.Finished, 0).Format(time.RFC3339)
+	}
+	for _, entry := range historyList {
+		response.Timeline = append(response.Timeline, timelineEntry{
+			Time:    time.Unix(entry.Created, 0).Format(time.RFC3339),
+			Kind:    entry.Kind,
+			Actor:   entry.Actor,
+			Details: entry.Details,
+		})
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// Send response with headers needed by OTRS invoker. Empty error message means success.
+func (eREST *EchoREST) respond(c echo.Context, status int, responseBody ResponseToOTRS, errorMessage string) error {
+	success := "1"
+	if errorMessage != "" {
+		success = "0"
+	}
+	c.Response().Header().Set("ResponseSuccess", success)           // Needed by OTRS invoker
+	c.Response().Header().Set("ResponseErrorMessage", errorMessage) // Needed by OTRS invoker
+	data, err := json.Marshal(&responseBody)
+	if err != nil {
+		eREST.Log.Error(fmt.Sprintf("Can't marshal body for response to OTRS - '%+v'", err))
+	}
+	eREST.Log.Debug(fmt.Sprintf("Send response with body - '%+v'", string(data)))
+	return c.JSONBlob(status, data)
+}
+
+// Add route handled by another module. Must be called after PrepareListener.
+func (eREST *EchoREST) AddHandler(method, path string, handler http.Handler) {
+	eREST.Log.Debug(fmt.Sprintf("Add route '%v %v'", method, path))
+	eREST.Instance.Add(method, path, echo.WrapHandler(handler))
+}
+
+// Add admin API route handled by another module. Must be called after PrepareListener.
+func (eREST *EchoREST) AddAdminHandler(method, path string, handler http.Handler) {
+	eREST.addAdminRoute(method, path, echo.WrapHandler(handler))
+}
+
+// Add route to admin API. Route not added if admin API disabled.
+func (eREST *EchoREST) addAdminRoute(method, path string, handler echo.HandlerFunc) {
+	if eREST.adminToken == "" {
+		eREST.Log.Debug(fmt.Sprintf("Admin API disabled. Skip route '%v %v'", method, path))
+		return
+	}
+	eREST.Log.Debug(fmt.Sprintf("Add admin route '%v %v'", method, path))
+	eREST.Instance.Add(method, path, handler, middleware.KeyAuth(eREST.validAdminToken))
+}
+
+// Compare token in constant time.
+func (eREST *EchoREST) validAdminToken(token string, c echo.Context) (bool, error) {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(eREST.adminToken)) == 1, nil
 }
 
-//
+// Start listener and shutdown it on context done.
 func (eREST *EchoREST) Listen(ctx context.Context, cancel context.CancelFunc) error {
 	go listenerWrapper(eREST.Instance, cancel, eREST.Log)
 	eREST.Log.Debug("Listener started")
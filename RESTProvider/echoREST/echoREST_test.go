@@ -0,0 +1,178 @@
+package echoREST
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger/CLILogger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"github.com/Sarraksh/otrs-echo-bot/event"
+)
+
+// Remember created events. No events due for processing.
+type fakeDB struct {
+	DBProvider.DBProvider
+	mx      sync.Mutex
+	created []string // Instances of created events.
+}
+
+func (f *fakeDB) OTRSEventCreateOnce(Channel, Type, Instance string, TicketID int64, IdempotencyKey string) (int64, bool, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.created = append(f.created, Instance)
+	return int64(len(f.created)), true, nil
+}
+
+func (f *fakeDB) OTRSEventGetByID(id int64) (DBProvider.OTRSEvent, error) {
+	return DBProvider.OTRSEvent{ID: id, Status: "New", Created: 1}, nil
+}
+
+func (f *fakeDB) EventHistoryGetByEvent(eventID int64) ([]DBProvider.EventHistory, error) {
+	return nil, nil
+}
+
+func (f *fakeDB) OTRSEventClaim(owner string, leaseUntil, dueBefore int64) (int64, string, string, error) {
+	return 0, "", "", myErrors.ErrNoActiveEvents
+}
+
+// OTRS instance without tickets.
+type emptyOTRS struct{}
+
+func (emptyOTRS) Initialise(logger logger.Logger, conf config.OTRSConf) {}
+func (emptyOTRS) GetTicketDetails(ticketID string) (OTRSProvider.TicketOTRS, error) {
+	return OTRSProvider.TicketOTRS{}, myErrors.ErrTicketNotFound
+}
+func (emptyOTRS) SearchTickets(criteria OTRSProvider.SearchCriteria) ([]OTRSProvider.TicketOTRS, error) {
+	return nil, nil
+}
+func (emptyOTRS) SearchTicketIDs(criteria OTRSProvider.SearchCriteria) ([]string, error) {
+	return nil, nil
+}
+
+// Prepare listener for default instance "main" on "/newticket" and instance "second" on "/second".
+func newTestREST(t *testing.T) (*EchoREST, *fakeDB) {
+	t.Helper()
+	return newTestRESTWithConf(t, config.RESTConf{})
+}
+
+// Prepare listener with REST options.
+func newTestRESTWithConf(t *testing.T, restConf config.RESTConf) (*EchoREST, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{}
+	var db DBProvider.DBProvider = fake
+	instances := OTRSProvider.NewInstances()
+	instances.Add("main", emptyOTRS{})
+	instances.Add("second", emptyOTRS{})
+
+	eREST := &EchoREST{}
+	eREST.Initialise(CLILogger.NewDefault(), &db, instances, []config.OTRSConf{
+		{Name: "main"},
+		{Name: "second", WebhookPath: "/second"},
+	}, restConf)
+	eREST.PrepareListener(&event.Processor{DB: &db, Log: CLILogger.NewDefault()})
+	return eREST, fake
+}
+
+func postTicket(eREST *EchoREST, path, id string, header http.Header) *httptest.ResponseRecorder {
+	form := url.Values{"id": {id}}
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	eREST.Instance.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestInstanceByRoute(t *testing.T) {
+	eREST, db := newTestREST(t)
+
+	for path, want := range map[string]string{"/newticket": "main", "/second": "second"} {
+		db.created = nil
+		response := postTicket(eREST, path, "42", nil)
+		if response.Code != http.StatusOK || response.Header().Get("ResponseSuccess") != "1" {
+			t.Fatalf("%v answered %v - %v", path, response.Code, response.Body)
+		}
+		if len(db.created) != 1 || db.created[0] != want {
+			t.Errorf("%v created events for %v, want %v", path, db.created, want)
+		}
+	}
+}
+
+func TestInstanceHeaderIgnored(t *testing.T) {
+	eREST, db := newTestREST(t)
+
+	response := postTicket(eREST, "/second", "42", http.Header{"X-Otrs-Instance": {"main"}})
+
+	if response.Code != http.StatusOK {
+		t.Fatalf("answered %v - %v", response.Code, response.Body)
+	}
+	if len(db.created) != 1 || db.created[0] != "second" {
+		t.Errorf("event created for %v, want route instance 'second'", db.created)
+	}
+}
+
+func TestInvalidTicketID(t *testing.T) {
+	eREST, db := newTestREST(t)
+
+	response := postTicket(eREST, "/newticket", "abc", nil)
+
+	if response.Code != http.StatusBadRequest || response.Header().Get("ResponseSuccess") != "0" {
+		t.Errorf("answered %v with success '%v'", response.Code, response.Header().Get("ResponseSuccess"))
+	}
+	if len(db.created) != 0 {
+		t.Errorf("event created for invalid ID")
+	}
+}
+
+func getAdmin(eREST *EchoREST, path, authorization string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	recorder := httptest.NewRecorder()
+	eREST.Instance.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAdminToken(t *testing.T) {
+	eREST, _ := newTestRESTWithConf(t, config.RESTConf{AdminToken: "secret"})
+	eREST.AddAdminHandler(http.MethodGet, "/api/v1/reports/test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, path := range []string{"/api/v1/events/1/timeline", "/api/v1/reports/test"} {
+		for authorization, want := range map[string]int{
+			"":              http.StatusBadRequest,
+			"Bearer wrong":  http.StatusUnauthorized,
+			"Basic secret":  http.StatusBadRequest,
+			"Bearer secret": http.StatusOK,
+		} {
+			if response := getAdmin(eREST, path, authorization); response.Code != want {
+				t.Errorf("%v with authorization '%v' answered %v, want %v", path, authorization, response.Code, want)
+			}
+		}
+	}
+}
+
+func TestAdminAPIDisabledWithoutToken(t *testing.T) {
+	eREST, _ := newTestREST(t)
+	eREST.AddAdminHandler(http.MethodGet, "/api/v1/reports/test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, path := range []string{"/api/v1/events/1/timeline", "/api/v1/reports/test", "/api/v1/metrics/otrs-cache"} {
+		if response := getAdmin(eREST, path, "Bearer "); response.Code != http.StatusNotFound {
+			t.Errorf("%v answered %v without admin token configured", path, response.Code)
+		}
+	}
+}
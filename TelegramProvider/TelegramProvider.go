@@ -2,12 +2,32 @@ package TelegramProvider
 
 import (
 	"context"
+	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
 	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"net/http"
+)
+
+// Message priorities for send queue. Lower value sent first.
+const (
+	PriorityReply    int = iota // Response to user command.
+	PriorityAlert               // New ticket alert.
+	PriorityReminder            // Reminder for not processed ticket.
 )
 
 type TelegramProvider interface {
-	Initialise(botToken string, logger logger.Logger, db *DBProvider.DBProvider) error
+	Initialise(
+		conf config.TelegramConf,
+		logger logger.Logger,
+		db *DBProvider.DBProvider,
+		otrs *OTRSProvider.Instances,
+		client *ClientProvider.ClientProvider,
+	) error
 	UpdateListener(ctx context.Context, cancel context.CancelFunc) error
-	SendEventMessage(chatID int64, text string) error
+	SendEventMessage(chatID int64, message Formatter.Message, priority int) error
+	QueueDepth() int
+	WebhookHandler() (path string, handler http.HandlerFunc)
 }
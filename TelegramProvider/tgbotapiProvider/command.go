@@ -2,15 +2,23 @@ package tgbotapiProvider
 
 import (
 	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/CalendarProvider"
 	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/locale"
 	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Extract all commands from received message.
+// Commands with "@botname" suffix of another bot are skipped.
 // If entities not received or contain only non-commands, return empty slice.
-func extractCommandList(message tgbotapi.Message) []Command {
+func extractCommandList(message tgbotapi.Message, botName string) []Command {
 	// If entities not received return empty slice.
 	if message.Entities == nil {
 		return make([]Command, 0, 0)
@@ -26,6 +34,16 @@ func extractCommandList(message tgbotapi.Message) []Command {
 			command := Command{
 				Name:   message.Text[firstCharacter:lastCharacter],
 				Offset: uint64(firstCharacter),
+				Length: uint64(lastCharacter - firstCharacter),
+			}
+
+			// Split "/command@botname" form used in groups.
+			if at := strings.Index(command.Name, "@"); at >= 0 {
+				if !strings.EqualFold(command.Name[at+1:], botName) {
+					continue
+				}
+				command.Name = command.Name[:at]
+				command.Addressed = true
 			}
 			commandList = append(commandList, command)
 		}
@@ -34,7 +52,7 @@ func extractCommandList(message tgbotapi.Message) []Command {
 	return commandList
 }
 
-// Get russian name from argument.
+// Get name from argument. Name can contain only letters.
 func getNameFromCommandArgument(text string, commandOffset, commandLength uint64) (string, error) {
 	argumentOffset := commandOffset + commandLength + 1
 	if int64(len(text))-1-int64(argumentOffset) <= 0 {
@@ -51,8 +69,8 @@ func getNameFromCommandArgument(text string, commandOffset, commandLength uint64
 		return "", myErrors.ErrArgumentNotProvided
 	}
 
-	reRussianLettersOnly := regexp.MustCompile(`^[абвгдеёжзийклмнопрстуфхцчшщъыьэюяАБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯ]+$`)
-	argument := reRussianLettersOnly.FindString(firstArgument)
+	reLettersOnly := regexp.MustCompile(`^\p{L}+$`)
+	argument := reLettersOnly.FindString(firstArgument)
 
 	if len(argument) < 1 {
 		return "", myErrors.ErrInvalidArgument
@@ -62,30 +80,30 @@ func getNameFromCommandArgument(text string, commandOffset, commandLength uint64
 }
 
 // Logic for /firstName command.
-func commandFirstName(bot TelegramModule, message tgbotapi.Message, command Command) {
+func commandFirstName(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
 	bot.Log.Debug(fmt.Sprintf("'%v' command received. Change FirstName", command))
 	db := *bot.DB
 	err := updateFirstName(db, command, message.Text, message.Chat.ID)
 	if err != nil {
 		bot.Log.Error(fmt.Sprintf("Can't update FirstName - '%v'", err))
-		sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, invalidFirstNameResponse, bot.Log)
+		bot.reply(message.Chat.ID, language, invalidFirstNameResponse)
 	}
 }
 
 // Logic for /lastName command.
-func commandLastName(bot TelegramModule, message tgbotapi.Message, command Command) {
+func commandLastName(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
 	bot.Log.Debug(fmt.Sprintf("'%v' command received. Change LastName", command))
 	db := *bot.DB
 	err := updateLastName(db, command, message.Text, message.Chat.ID)
 	if err != nil {
 		bot.Log.Error(fmt.Sprintf("Can't update FirstName - '%v'", err))
-		sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, invalidLastNameResponse, bot.Log)
+		bot.reply(message.Chat.ID, language, invalidLastNameResponse)
 	}
 }
 
 // Validate argument for /firstName command and write it into DB if valid.
 func updateFirstName(db DBProvider.DBProvider, command Command, text string, telegramID int64) error {
-	firstName, err := getNameFromCommandArgument(text, command.Offset, uint64(len(command.Name)))
+	firstName, err := getNameFromCommandArgument(text, command.Offset, command.Length)
 	if err != nil {
 		return err
 	}
@@ -100,7 +118,7 @@ func updateFirstName(db DBProvider.DBProvider, command Command, text string, tel
 
 // Validate argument for /lastName command and write it into DB if valid.
 func updateLastName(db DBProvider.DBProvider, command Command, text string, telegramID int64) error {
-	lastName, err := getNameFromCommandArgument(text, command.Offset, uint64(len(command.Name)))
+	lastName, err := getNameFromCommandArgument(text, command.Offset, command.Length)
 	if err != nil {
 		return err
 	}
@@ -114,61 +132,363 @@ func updateLastName(db DBProvider.DBProvider, command Command, text string, tele
 }
 
 // Logic for /subscribe* commands.
-func commandSubscribe(bot TelegramModule, message tgbotapi.Message, command Command) {
-	db := *bot.DB
-	DBUserID, err := db.BotUserGetByTelegramID(message.Chat.ID)
-	switch {
-	case err == myErrors.ErrNoUsersFound:
-		// If user not found use "start" command behavior.
-		commandStart(bot, message)
-	case err != nil:
+func commandSubscribe(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	if !isAllowedToManageSubscriptions(bot, message) {
+		bot.reply(message.Chat.ID, language, adminOnlyResponse)
+		return
+	}
+
+	DBUserID, err := getOrCreateBotUser(bot, message)
+	if err != nil {
 		bot.Log.Error(fmt.Sprintf("while subsscribe user with telegram ID '%v' for '%v' - '%v'",
 			message.Chat.ID, command.Name, err))
+		bot.reply(message.Chat.ID, language, errorWileSubscribe)
+		return
 	}
 
 	// Save subscription and response to user with result.
+	db := *bot.DB
 	err = db.SubscriptionListAdd(DBUserID, command.Name[9:])
 	if err != nil {
 		bot.Log.Error(fmt.Sprintf("while subsscribe user with telegram ID '%v' for '%v' - '%v'",
 			message.Chat.ID, command.Name, err))
-		sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, errorWileSubscribe, bot.Log)
+		bot.reply(message.Chat.ID, language, errorWileSubscribe)
 	} else {
-		sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, successfulSubscribe, bot.Log)
+		bot.reply(message.Chat.ID, language, successfulSubscribe)
 	}
 }
 
 // Logic for /unsubscribe* commands.
-func commandUnsubscribe(bot TelegramModule, message tgbotapi.Message, command Command) {
-	db := *bot.DB
-	DBUserID, err := db.BotUserGetByTelegramID(message.Chat.ID)
-	switch {
-	case err == myErrors.ErrNoUsersFound:
-		// If user not found use "start" command behavior.
-		commandStart(bot, message)
-	case err != nil:
+func commandUnsubscribe(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	if !isAllowedToManageSubscriptions(bot, message) {
+		bot.reply(message.Chat.ID, language, adminOnlyResponse)
+		return
+	}
+
+	DBUserID, err := getOrCreateBotUser(bot, message)
+	if err != nil {
 		bot.Log.Error(fmt.Sprintf("while unsubsscribe user with telegram ID '%v' for '%v' - '%v'",
 			message.Chat.ID, command.Name, err))
+		bot.reply(message.Chat.ID, language, errorWileUnsubscribe)
+		return
 	}
 
 	// Remove subscription and response to user with result.
+	db := *bot.DB
 	err = db.SubscriptionListRemove(DBUserID, command.Name[11:])
 	if err != nil {
 		bot.Log.Error(fmt.Sprintf("while unsubsscribe user with telegram ID '%v' for '%v' - '%v'",
 			message.Chat.ID, command.Name, err))
-		sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, errorWileUnsubscribe, bot.Log)
+		bot.reply(message.Chat.ID, language, errorWileUnsubscribe)
 	} else {
-		sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, successfulUnsubscribe, bot.Log)
+		bot.reply(message.Chat.ID, language, successfulUnsubscribe)
 	}
 }
 
 // Logic for /start command.
-func commandStart(bot TelegramModule, message tgbotapi.Message) {
-	sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, startCommandResponse, bot.Log)
+func commandStart(bot TelegramModule, message tgbotapi.Message, language string) {
+	bot.reply(message.Chat.ID, language, startCommandResponse)
+	registerChat(bot, message, language)
+}
 
+// Add chat as bot user. Already registered chat is not an error.
+func registerChat(bot TelegramModule, message tgbotapi.Message, language string) {
 	db := *bot.DB
-	err := db.BotUserAdd(message.Chat.ID)
-	if err != nil && err != myErrors.ErrNoUsersFound {
+	err := db.BotUserAdd(message.Chat.ID, message.Chat.Type, language)
+	if err != nil && err != myErrors.ErrUserAlreadyExists {
 		// TODO - add exit program with error
 		bot.Log.Error(fmt.Sprintf("Can't create new user - '%v'", err))
 	}
 }
+
+// Return bot user ID for chat. If chat not registered yet register it.
+// Start message sent only into private chats so groups and channels not flooded by first command.
+func getOrCreateBotUser(bot TelegramModule, message tgbotapi.Message) (int64, error) {
+	db := *bot.DB
+	DBUserID, err := db.BotUserGetByTelegramID(message.Chat.ID)
+	if err != myErrors.ErrNoUsersFound {
+		return DBUserID, err
+	}
+
+	if message.Chat.IsPrivate() {
+		commandStart(bot, message, bot.language(message))
+	} else {
+		registerChat(bot, message, bot.language(message))
+	}
+	return db.BotUserGetByTelegramID(message.Chat.ID)
+}
+
+// In private chats and channels anyone who can send command can manage subscriptions.
+// In groups only creator and administrators can.
+func isAllowedToManageSubscriptions(bot TelegramModule, message tgbotapi.Message) bool {
+	if !message.Chat.IsGroup() && !message.Chat.IsSuperGroup() {
+		return true
+	}
+	if message.Chat.AllMembersAreAdmins {
+		return true
+	}
+	if message.From == nil {
+		return false
+	}
+
+	member, err := bot.bot.GetChatMember(tgbotapi.ChatConfigWithUser{
+		ChatID: message.Chat.ID,
+		UserID: message.From.ID,
+	})
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't get member '%v' status in chat '%v' - '%v'", message.From.ID, message.Chat.ID, err))
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// Get all arguments after command up to the end of line.
+func getCommandArguments(text string, command Command) []string {
+	argumentOffset := command.Offset + command.Length
+	if argumentOffset >= uint64(len(text)) {
+		return nil
+	}
+	line := strings.SplitN(text[argumentOffset:], "\n", 2)[0]
+	return strings.Fields(line)
+}
+
+// Logic for /preview command. Render template for provided kind against sample ticket.
+func commandPreview(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	arguments := getCommandArguments(message.Text, command)
+	if len(arguments) < 1 {
+		bot.reply(message.Chat.ID, language, previewUsageResponse)
+		return
+	}
+	channel := Formatter.ChannelTelegram
+	if len(arguments) > 1 {
+		channel = arguments[1]
+	}
+
+	preview, err := Formatter.Preview(arguments[0], channel, language)
+	if err != nil {
+		bot.Log.Warning(fmt.Sprintf("Can't render preview for '%v' - '%v'", arguments, err))
+		bot.replyText(message.Chat.ID, fmt.Sprintf("%v\n%v", err, messageList.Text(language, previewUsageResponse)))
+		return
+	}
+	err = bot.queue.Send(message.Chat.ID, preview, TelegramProvider.PriorityReply)
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't send preview - '%v'", err))
+	}
+}
+
+// Logic for /language command. Return language used for responses after command.
+func commandLanguage(bot TelegramModule, message tgbotapi.Message, command Command, language string) string {
+	arguments := getCommandArguments(message.Text, command)
+	if len(arguments) < 1 || !locale.IsSupported(strings.ToLower(arguments[0])) {
+		bot.reply(message.Chat.ID, language, languageUsageResponse)
+		return language
+	}
+	if !isAllowedToManageSubscriptions(bot, message) {
+		bot.reply(message.Chat.ID, language, adminOnlyResponse)
+		return language
+	}
+	newLanguage := strings.ToLower(arguments[0])
+
+	_, err := getOrCreateBotUser(bot, message)
+	if err == nil {
+		err = (*bot.DB).BotUserUpdateLanguage(message.Chat.ID, newLanguage)
+	}
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't change language for chat '%v' to '%v' - '%v'", message.Chat.ID, newLanguage, err))
+		bot.reply(message.Chat.ID, language, errorWileChangeLanguage)
+		return language
+	}
+
+	bot.reply(message.Chat.ID, newLanguage, languageChangedResponse)
+	return newLanguage
+}
+
+// Logic for /timezone command. Timezone used for absolute time in messages.
+func commandTimezone(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	arguments := getCommandArguments(message.Text, command)
+	if len(arguments) < 1 {
+		bot.reply(message.Chat.ID, language, timezoneUsageResponse)
+		return
+	}
+	location, err := locale.ParseTimezone(arguments[0])
+	if err != nil {
+		bot.Log.Debug(fmt.Sprintf("Unknown timezone '%v' - '%v'", arguments[0], err))
+		bot.reply(message.Chat.ID, language, timezoneUsageResponse)
+		return
+	}
+	if !isAllowedToManageSubscriptions(bot, message) {
+		bot.reply(message.Chat.ID, language, adminOnlyResponse)
+		return
+	}
+
+	_, err = getOrCreateBotUser(bot, message)
+	if err == nil {
+		err = (*bot.DB).BotUserUpdateTimezone(message.Chat.ID, location.String())
+	}
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't change timezone for chat '%v' to '%v' - '%v'", message.Chat.ID, location, err))
+		bot.reply(message.Chat.ID, language, errorWileChangeTimezone)
+		return
+	}
+
+	bot.replyText(message.Chat.ID, fmt.Sprint(messageList.Text(language, timezoneChangedResponse), " ", location))
+}
+
+// Turn on do-not-disturb mode for duration or turn it off. Without arguments show current state.
+func commandDND(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	arguments := getCommandArguments(message.Text, command)
+	if len(arguments) < 1 {
+		settings, err := (*bot.DB).BotUserGetSettings(message.Chat.ID)
+		if err != nil || settings.DNDUntil <= time.Now().Unix() {
+			bot.reply(message.Chat.ID, language, dndUsageResponse)
+			return
+		}
+		bot.replyText(message.Chat.ID, fmt.Sprint(messageList.Text(language, dndEnabledResponse), " ", displayTime(settings.DNDUntil, settings.Timezone)))
+		return
+	}
+	var until int64
+	if arguments[0] != "off" {
+		duration, err := parseDNDDuration(arguments[0])
+		if err != nil {
+			bot.Log.Debug(fmt.Sprintf("Invalid do-not-disturb duration '%v' - '%v'", arguments[0], err))
+			bot.reply(message.Chat.ID, language, dndUsageResponse)
+			return
+		}
+		until = time.Now().Add(duration).Unix()
+	}
+	if !isAllowedToManageSubscriptions(bot, message) {
+		bot.reply(message.Chat.ID, language, adminOnlyResponse)
+		return
+	}
+
+	_, err := getOrCreateBotUser(bot, message)
+	if err == nil {
+		err = (*bot.DB).BotUserUpdateDNDUntil(message.Chat.ID, until)
+	}
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't change do-not-disturb mode for chat '%v' - '%v'", message.Chat.ID, err))
+		bot.reply(message.Chat.ID, language, errorWileChangeDND)
+		return
+	}
+
+	if until == 0 {
+		bot.reply(message.Chat.ID, language, dndDisabledResponse)
+		return
+	}
+	settings, _ := (*bot.DB).BotUserGetSettings(message.Chat.ID)
+	bot.replyText(message.Chat.ID, fmt.Sprint(messageList.Text(language, dndEnabledResponse), " ", displayTime(until, settings.Timezone)))
+}
+
+// Set daily quiet hours or turn them off. Without arguments show current quiet hours.
+func commandQuiet(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	arguments := getCommandArguments(message.Text, command)
+	if len(arguments) < 1 {
+		settings, err := (*bot.DB).BotUserGetSettings(message.Chat.ID)
+		if err != nil || settings.QuietHours == "" {
+			bot.reply(message.Chat.ID, language, quietUsageResponse)
+			return
+		}
+		bot.replyText(message.Chat.ID, fmt.Sprint(messageList.Text(language, quietChangedResponse), " ", settings.QuietHours))
+		return
+	}
+	var quietHours string
+	if arguments[0] != "off" {
+		_, _, err := CalendarProvider.ParseDailyRange(arguments[0])
+		if err != nil {
+			bot.Log.Debug(fmt.Sprintf("Invalid quiet hours '%v' - '%v'", arguments[0], err))
+			bot.reply(message.Chat.ID, language, quietUsageResponse)
+			return
+		}
+		quietHours = arguments[0]
+	}
+	if !isAllowedToManageSubscriptions(bot, message) {
+		bot.reply(message.Chat.ID, language, adminOnlyResponse)
+		return
+	}
+
+	_, err := getOrCreateBotUser(bot, message)
+	if err == nil {
+		err = (*bot.DB).BotUserUpdateQuietHours(message.Chat.ID, quietHours)
+	}
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't change quiet hours for chat '%v' to '%v' - '%v'", message.Chat.ID, quietHours, err))
+		bot.reply(message.Chat.ID, language, errorWileChangeQuiet)
+		return
+	}
+
+	if quietHours == "" {
+		bot.reply(message.Chat.ID, language, quietDisabledResponse)
+		return
+	}
+	bot.replyText(message.Chat.ID, fmt.Sprint(messageList.Text(language, quietChangedResponse), " ", quietHours))
+}
+
+// Parse do-not-disturb duration. Supports Go durations like "2h" or "30m" and whole days like "1d".
+func parseDNDDuration(value string) (time.Duration, error) {
+	var duration time.Duration
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, err
+		}
+		duration = time.Duration(days) * 24 * time.Hour
+	} else {
+		var err error
+		duration, err = time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if duration <= 0 {
+		return 0, myErrors.ErrInvalidDuration
+	}
+	return duration, nil
+}
+
+// Format unix timestamp in user timezone. Server timezone used if user timezone not chosen.
+func displayTime(timestamp int64, timezone string) string {
+	location, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "" {
+		location = time.Local
+	}
+	return time.Unix(timestamp, 0).In(location).Format(Formatter.DisplayLayout)
+}
+
+// Choose individual reminders, team digest or both. Without arguments show current mode.
+func commandReminders(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	arguments := getCommandArguments(message.Text, command)
+	if len(arguments) < 1 {
+		settings, err := (*bot.DB).BotUserGetSettings(message.Chat.ID)
+		if err != nil || settings.NotificationMode == "" {
+			settings.NotificationMode = DBProvider.NotificationModeIndividual
+		}
+		bot.replyText(message.Chat.ID, fmt.Sprint(
+			messageList.Text(language, remindersChangedResponse), " ", settings.NotificationMode, "\n\n",
+			messageList.Text(language, remindersUsageResponse),
+		))
+		return
+	}
+	mode := strings.ToLower(arguments[0])
+	switch mode {
+	case DBProvider.NotificationModeIndividual, DBProvider.NotificationModeDigest, DBProvider.NotificationModeBoth:
+	default:
+		bot.reply(message.Chat.ID, language, remindersUsageResponse)
+		return
+	}
+	if !isAllowedToManageSubscriptions(bot, message) {
+		bot.reply(message.Chat.ID, language, adminOnlyResponse)
+		return
+	}
+
+	_, err := getOrCreateBotUser(bot, message)
+	if err == nil {
+		err = (*bot.DB).BotUserUpdateNotificationMode(message.Chat.ID, mode)
+	}
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't change notification mode for chat '%v' to '%v' - '%v'", message.Chat.ID, mode, err))
+		bot.reply(message.Chat.ID, language, errorWileChangeReminders)
+		return
+	}
+
+	bot.replyText(message.Chat.ID, fmt.Sprint(messageList.Text(language, remindersChangedResponse), " ", mode))
+}
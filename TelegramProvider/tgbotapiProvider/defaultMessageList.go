@@ -1,11 +1,72 @@
 package tgbotapiProvider
 
+import "github.com/Sarraksh/otrs-echo-bot/common/locale"
+
+// Keys for bot responses in message catalog.
 const (
-	invalidCommandResponse string = `Неверная команда.
-Для отображения списка команд используйте /help .`
-	noCommandInMessage string = `Команда должна начинаться символом "/".
-Для отображения списка команд используйте /help .`
-	helpCommandResponse string = `Список доступных команд:
+	invalidCommandResponse      string = "invalidCommand"
+	noCommandInMessage          string = "noCommand"
+	helpCommandResponse         string = "help"
+	startCommandResponse        string = "start"
+	invalidFirstNameResponse    string = "invalidFirstName"
+	invalidLastNameResponse     string = "invalidLastName"
+	errorWileSubscribe          string = "errorSubscribe"
+	errorWileUnsubscribe        string = "errorUnsubscribe"
+	successfulSubscribe         string = "successfulSubscribe"
+	successfulUnsubscribe       string = "successfulUnsubscribe"
+	adminOnlyResponse           string = "adminOnly"
+	privateChatOnlyResponse     string = "privateChatOnly"
+	previewUsageResponse        string = "previewUsage"
+	languageUsageResponse       string = "languageUsage"
+	languageChangedResponse     string = "languageChanged"
+	errorWileChangeLanguage     string = "errorChangeLanguage"
+	timezoneUsageResponse       string = "timezoneUsage"
+	timezoneChangedResponse     string = "timezoneChanged"
+	errorWileChangeTimezone     string = "errorChangeTimezone"
+	dndUsageResponse            string = "dndUsage"
+	dndEnabledResponse          string = "dndEnabled"
+	dndDisabledResponse         string = "dndDisabled"
+	errorWileChangeDND          string = "errorChangeDND"
+	quietUsageResponse          string = "quietUsage"
+	quietChangedResponse        string = "quietChanged"
+	quietDisabledResponse       string = "quietDisabled"
+	errorWileChangeQuiet        string = "errorChangeQuiet"
+	remindersUsageResponse      string = "remindersUsage"
+	remindersChangedResponse    string = "remindersChanged"
+	errorWileChangeReminders    string = "errorChangeReminders"
+	errorWileGetTickets         string = "errorGetTickets"
+	noSubscriptionsResponse     string = "noSubscriptions"
+	noTicketsResponse           string = "noTickets"
+	ticketsHeaderResponse       string = "ticketsHeader"
+	ticketUsageResponse         string = "ticketUsage"
+	ticketNotFoundResponse      string = "ticketNotFound"
+	ticketTitleResponse         string = "ticketTitle"
+	ticketCustomerResponse      string = "ticketCustomer"
+	ticketPriorityResponse      string = "ticketPriority"
+	ticketStateResponse         string = "ticketState"
+	ticketLockResponse          string = "ticketLock"
+	ticketOwnerResponse         string = "ticketOwner"
+	ticketInstanceResponse      string = "ticketInstance"
+	ticketCreatedResponse       string = "ticketCreated"
+	ticketEventsResponse        string = "ticketEvents"
+	historyUsageResponse        string = "historyUsage"
+	historyHeaderResponse       string = "historyHeader"
+	searchUsageResponse         string = "searchUsage"
+	searchHeaderResponse        string = "searchHeader"
+	searchNothingFoundResponse  string = "searchNothingFound"
+	searchExpiredResponse       string = "searchExpired"
+	searchNotConfiguredResponse string = "searchNotConfigured"
+	errorWileSearch             string = "errorSearch"
+)
+
+// Bot responses by language.
+var messageList = locale.Catalog{
+	locale.Russian: {
+		invalidCommandResponse: `Неверная команда.
+Для отображения списка команд используйте /help .`,
+		noCommandInMessage: `Команда должна начинаться символом "/".
+Для отображения списка команд используйте /help .`,
+		helpCommandResponse: `Список доступных команд:
 
 Управление подписками на события
 /subscribeTeam1
@@ -15,14 +76,51 @@ const (
 /unsubscribeTeam2
 /unsubscribeTeam3
 
+Заявки клиентов без команды в /tickets и поиске
+/subscribeTeamAll
+/unsubscribeTeamAll
+
 Указание своих имени и фамилии
 /firstName Имя
 /lastName Фамилия
 
+Выбор языка сообщений
+/language ru
+/language en
+
+Выбор часового пояса для времени в сообщениях
+/timezone Europe/Moscow
+
+Тихие часы без некритичных уведомлений
+/quiet 23:00-07:00
+/quiet off
+
+Режим "не беспокоить" на время
+/dnd 2h
+/dnd off
+
+Получение напоминаний: по каждой заявке, сводкой по команде или оба варианта
+/reminders individual
+/reminders digest
+/reminders both
+
+Открытые заявки команд из подписок
+/tickets
+/ticket номер
+/history номер
+
+Поиск заявок в OTRS
+/search текст customer:клиент state:open prio:5
+
+Просмотр шаблона сообщения на тестовой заявке
+/preview вид [канал]
+
 Вывод данного сообщения
 /help
-`
-	startCommandResponse string = `Для начала работы с ботом пожалуйста оформите подписку на события одну из команд:
+
+Бота можно добавить в группу или канал. В группе подписками управляют только администраторы, а команды можно отправлять в виде /help@имя_бота.
+`,
+		startCommandResponse: `Для начала работы с ботом пожалуйста оформите подписку на события одну из команд:
 /subscribeTeam1
 /subscribeTeam2
 /subscribeTeam3
@@ -34,13 +132,213 @@ const (
 
 Для автоматического получения сообщений по всем событиям для дежурных пожалуйста укажите свои имя и фамилию с помощью следующих команд:
 /firstName Имя
-/lastName Фамилия`
-	invalidFirstNameResponse string = `Имя должно содержать только русские буквы.`
-	invalidLastNameResponse  string = `Фамилия должна содержать только русские буквы.`
-	errorWileSubscribe       string = `Ошибка при оформлении подписки.
-Пожалуйста попробуйте ещё раз или посмотрите лог.`
-	errorWileUnsubscribe string = `Ошибка при отмене подписки.
-Пожалуйста попробуйте ещё раз или посмотрите лог.`
-	successfulSubscribe   string = `Подписка успешно оформлена.`
-	successfulUnsubscribe string = `Подписка успешно отменена.`
-)
+/lastName Фамилия
+
+For English use /language en`,
+		invalidFirstNameResponse: `Имя должно содержать только буквы.`,
+		invalidLastNameResponse:  `Фамилия должна содержать только буквы.`,
+		errorWileSubscribe: `Ошибка при оформлении подписки.
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+		errorWileUnsubscribe: `Ошибка при отмене подписки.
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+		successfulSubscribe:     `Подписка успешно оформлена.`,
+		successfulUnsubscribe:   `Подписка успешно отменена.`,
+		adminOnlyResponse:       `Управлять подписками группы могут только её администраторы.`,
+		privateChatOnlyResponse: `Команда доступна только в личной переписке с ботом.`,
+		previewUsageResponse: `Использование: /preview вид [канал]
+Виды: new, reminder, escalation, taken, closed, merged, digest
+Каналы: telegram, plain, webhook`,
+		languageUsageResponse:   `Использование: /language ru или /language en`,
+		languageChangedResponse: `Язык сообщений изменён на русский.`,
+		errorWileChangeLanguage: `Ошибка при смене языка.
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+		timezoneUsageResponse: `Использование: /timezone часовой_пояс
+Часовой пояс указывается в формате IANA, например Europe/Moscow или Asia/Yekaterinburg.`,
+		timezoneChangedResponse: `Часовой пояс изменён на`,
+		errorWileChangeTimezone: `Ошибка при смене часового пояса.
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+		dndUsageResponse: `Использование: /dnd длительность или /dnd off
+Длительность указывается как 30m, 2h или 1d. Критичные заявки доставляются всегда.`,
+		dndEnabledResponse:  `Режим "не беспокоить" включён до`,
+		dndDisabledResponse: `Режим "не беспокоить" выключен.`,
+		errorWileChangeDND: `Ошибка при смене режима "не беспокоить".
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+		quietUsageResponse: `Использование: /quiet 23:00-07:00 или /quiet off
+Новые заявки в тихие часы доставляются после их окончания, напоминания не отправляются. Критичные заявки доставляются всегда.`,
+		quietChangedResponse:  `Тихие часы:`,
+		quietDisabledResponse: `Тихие часы выключены.`,
+		errorWileChangeQuiet: `Ошибка при смене тихих часов.
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+		remindersUsageResponse: `Использование: /reminders individual, /reminders digest или /reminders both
+individual - напоминание по каждой заявке, digest - периодическая сводка открытых заявок команды, both - оба варианта.`,
+		remindersChangedResponse: `Режим напоминаний:`,
+		errorWileChangeReminders: `Ошибка при смене режима напоминаний.
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+		errorWileGetTickets: `Ошибка при получении заявок.
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+		noSubscriptionsResponse: `Нет подписок на команды. Для оформления подписки используйте /start .`,
+		noTicketsResponse:       `Открытых заявок нет.`,
+		ticketsHeaderResponse:   `Открытые заявки`,
+		ticketUsageResponse:     `Использование: /ticket номер_заявки [экземпляр_OTRS]`,
+		ticketNotFoundResponse:  `Заявка не найдена среди событий бота.`,
+		ticketTitleResponse:     `Заявка`,
+		ticketCustomerResponse:  `Клиент`,
+		ticketPriorityResponse:  `Приоритет`,
+		ticketStateResponse:     `Состояние`,
+		ticketLockResponse:      `Блокировка`,
+		ticketOwnerResponse:     `Владелец`,
+		ticketInstanceResponse:  `Экземпляр OTRS`,
+		ticketCreatedResponse:   `Создана`,
+		ticketEventsResponse:    `События бота`,
+		historyUsageResponse:    `Использование: /history номер_заявки [экземпляр_OTRS]`,
+		historyHeaderResponse:   `История заявки`,
+		searchUsageResponse: `Использование: /search текст фильтры
+Фильтры: customer:клиент, state:open|closed|new|pending, prio:номер_приоритета. Например /search принтер customer:ACME state:open prio:5`,
+		searchHeaderResponse:        `Найдено`,
+		searchNothingFoundResponse:  `Ничего не найдено.`,
+		searchExpiredResponse:       `Результат поиска устарел. Повторите /search .`,
+		searchNotConfiguredResponse: `Поиск заявок не настроен.`,
+		errorWileSearch: `Ошибка при поиске заявок.
+Пожалуйста попробуйте ещё раз или посмотрите лог.`,
+	},
+	locale.English: {
+		invalidCommandResponse: `Invalid command.
+Use /help to show the list of commands.`,
+		noCommandInMessage: `Commands must start with "/".
+Use /help to show the list of commands.`,
+		helpCommandResponse: `Available commands:
+
+Manage event subscriptions
+/subscribeTeam1
+/subscribeTeam2
+/subscribeTeam3
+/unsubscribeTeam1
+/unsubscribeTeam2
+/unsubscribeTeam3
+
+Tickets of clients without team in /tickets and search
+/subscribeTeamAll
+/unsubscribeTeamAll
+
+Set your first and last name
+/firstName Name
+/lastName Surname
+
+Choose message language
+/language ru
+/language en
+
+Choose timezone for time in messages
+/timezone Europe/London
+
+Quiet hours without non-critical notifications
+/quiet 23:00-07:00
+/quiet off
+
+Do not disturb for a period
+/dnd 2h
+/dnd off
+
+Receive reminders for every ticket, as a team digest or both
+/reminders individual
+/reminders digest
+/reminders both
+
+Open tickets of subscribed teams
+/tickets
+/ticket number
+/history number
+
+Search tickets in OTRS
+/search text customer:client state:open prio:5
+
+Preview message template on a sample ticket
+/preview kind [channel]
+
+Show this message
+/help
+
+The bot can be added to a group or channel. In groups only administrators manage subscriptions, and commands can be sent as /help@bot_name.
+`,
+		startCommandResponse: `To start using the bot please subscribe to events with one of the commands:
+/subscribeTeam1
+/subscribeTeam2
+/subscribeTeam3
+
+A subscription can be cancelled at any time with the matching command:
+/unsubscribeTeam1
+/unsubscribeTeam2
+/unsubscribeTeam3
+
+To receive all events for the on-duty engineer automatically please set your first and last name with the commands:
+/firstName Name
+/lastName Surname
+
+Для русского языка используйте /language ru`,
+		invalidFirstNameResponse: `First name must contain only letters.`,
+		invalidLastNameResponse:  `Last name must contain only letters.`,
+		errorWileSubscribe: `Subscription failed.
+Please try again or check the log.`,
+		errorWileUnsubscribe: `Unsubscription failed.
+Please try again or check the log.`,
+		successfulSubscribe:     `Subscribed successfully.`,
+		successfulUnsubscribe:   `Unsubscribed successfully.`,
+		adminOnlyResponse:       `Only group administrators can manage group subscriptions.`,
+		privateChatOnlyResponse: `This command is available only in a private chat with the bot.`,
+		previewUsageResponse: `Usage: /preview kind [channel]
+Kinds: new, reminder, escalation, taken, closed, merged, digest
+Channels: telegram, plain, webhook`,
+		languageUsageResponse:   `Usage: /language ru or /language en`,
+		languageChangedResponse: `Message language changed to English.`,
+		errorWileChangeLanguage: `Language change failed.
+Please try again or check the log.`,
+		timezoneUsageResponse: `Usage: /timezone zone
+Timezone is an IANA name, e.g. Europe/London or Asia/Tbilisi.`,
+		timezoneChangedResponse: `Timezone changed to`,
+		errorWileChangeTimezone: `Timezone change failed.
+Please try again or check the log.`,
+		dndUsageResponse: `Usage: /dnd duration or /dnd off
+Duration is given as 30m, 2h or 1d. Critical tickets are always delivered.`,
+		dndEnabledResponse:  `Do not disturb is on until`,
+		dndDisabledResponse: `Do not disturb is off.`,
+		errorWileChangeDND: `Do not disturb change failed.
+Please try again or check the log.`,
+		quietUsageResponse: `Usage: /quiet 23:00-07:00 or /quiet off
+New tickets during quiet hours are delivered when they end, reminders are skipped. Critical tickets are always delivered.`,
+		quietChangedResponse:  `Quiet hours:`,
+		quietDisabledResponse: `Quiet hours are off.`,
+		errorWileChangeQuiet: `Quiet hours change failed.
+Please try again or check the log.`,
+		remindersUsageResponse: `Usage: /reminders individual, /reminders digest or /reminders both
+individual - a reminder for every ticket, digest - a periodic digest of open team tickets, both - both of them.`,
+		remindersChangedResponse: `Reminder mode:`,
+		errorWileChangeReminders: `Reminder mode change failed.
+Please try again or check the log.`,
+		errorWileGetTickets: `Failed to get tickets.
+Please try again or check the log.`,
+		noSubscriptionsResponse: `No team subscriptions. Use /start to subscribe.`,
+		noTicketsResponse:       `No open tickets.`,
+		ticketsHeaderResponse:   `Open tickets`,
+		ticketUsageResponse:     `Usage: /ticket ticket_number [OTRS_instance]`,
+		ticketNotFoundResponse:  `Ticket not found among bot events.`,
+		ticketTitleResponse:     `Ticket`,
+		ticketCustomerResponse:  `Customer`,
+		ticketPriorityResponse:  `Priority`,
+		ticketStateResponse:     `State`,
+		ticketLockResponse:      `Lock`,
+		ticketOwnerResponse:     `Owner`,
+		ticketInstanceResponse:  `OTRS instance`,
+		ticketCreatedResponse:   `Created`,
+		ticketEventsResponse:    `Bot events`,
+		historyUsageResponse:    `Usage: /history ticket_number [OTRS_instance]`,
+		historyHeaderResponse:   `Ticket history`,
+		searchUsageResponse: `Usage: /search text filters
+Filters: customer:client, state:open|closed|new|pending, prio:priority_number. E.g. /search printer customer:ACME state:open prio:5`,
+		searchHeaderResponse:        `Found`,
+		searchNothingFoundResponse:  `Nothing found.`,
+		searchExpiredResponse:       `Search result expired. Repeat /search .`,
+		searchNotConfiguredResponse: `Ticket search is not configured.`,
+		errorWileSearch: `Ticket search failed.
+Please try again or check the log.`,
+	},
+}
@@ -0,0 +1,162 @@
+package tgbotapiProvider
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	searchLimit          int    = 50        // Maximum number of tickets in search result.
+	searchCallbackPrefix string = "search:" // Callback data prefix for /search page buttons. Page number follows.
+
+	searchResultTTL    time.Duration = 30 * time.Minute // Time search result available for paging.
+	searchResultsLimit int           = 1000             // Maximum number of chats with stored search result.
+)
+
+// Last search result by chat. Used for paging without repeated search.
+// Results expire after searchResultTTL and oldest result removed when searchResultsLimit reached.
+type searchResults struct {
+	mx      sync.Mutex
+	results map[int64]searchResult
+}
+
+// Search result with time of search.
+type searchResult struct {
+	tickets []OTRSProvider.TicketOTRS
+	created time.Time
+}
+
+func newSearchResults() *searchResults {
+	return &searchResults{results: make(map[int64]searchResult)}
+}
+
+func (s *searchResults) set(chatID int64, tickets []OTRSProvider.TicketOTRS) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	now := time.Now()
+	s.evict(now)
+	if _, ok := s.results[chatID]; !ok && len(s.results) >= searchResultsLimit {
+		var oldestChat int64
+		var oldest time.Time
+		for chat, result := range s.results {
+			if oldest.IsZero() || result.created.Before(oldest) {
+				oldestChat, oldest = chat, result.created
+			}
+		}
+		delete(s.results, oldestChat)
+	}
+	s.results[chatID] = searchResult{tickets: tickets, created: now}
+}
+
+func (s *searchResults) get(chatID int64) ([]OTRSProvider.TicketOTRS, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	result, ok := s.results[chatID]
+	if !ok || time.Since(result.created) > searchResultTTL {
+		return nil, false
+	}
+	return result.tickets, true
+}
+
+// Remove expired results. Caller holds lock.
+func (s *searchResults) evict(now time.Time) {
+	for chat, result := range s.results {
+		if now.Sub(result.created) > searchResultTTL {
+			delete(s.results, chat)
+		}
+	}
+}
+
+// Search tickets in all OTRS instances by text and filters and show first page of result.
+func commandSearch(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	criteria, ok := parseSearchQuery(getCommandArguments(message.Text, command))
+	if !ok {
+		bot.reply(message.Chat.ID, language, searchUsageResponse)
+		return
+	}
+	teamList, denied := bot.readerTeams(message.Chat.ID)
+	if denied != "" {
+		bot.reply(message.Chat.ID, language, denied)
+		return
+	}
+
+	found, err := bot.OTRS.SearchTickets(criteria)
+	if err == myErrors.ErrSearchNotConfigured {
+		bot.reply(message.Chat.ID, language, searchNotConfiguredResponse)
+		return
+	}
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't search tickets by '%+v' - '%v'", criteria, err))
+		bot.reply(message.Chat.ID, language, errorWileSearch)
+		return
+	}
+	// Only tickets of chat teams shown.
+	tickets := make([]OTRSProvider.TicketOTRS, 0, len(found))
+	for _, ticket := range found {
+		if bot.isTicketVisible(teamList, ticket.CustomerID) {
+			tickets = append(tickets, ticket)
+		}
+	}
+	bot.searches.set(message.Chat.ID, tickets)
+
+	text, keyboard := bot.searchPage(message.Chat.ID, language, 0)
+	response := Formatter.PlainMessage(text)
+	response.Keyboard = keyboard
+	err = bot.queue.Send(message.Chat.ID, response, TelegramProvider.PriorityReply)
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't send message - '%v'", err))
+	}
+}
+
+// Return text and navigation buttons for page of last search result in chat.
+func (bot TelegramModule) searchPage(chatID int64, language string, page int) (string, [][]Formatter.Button) {
+	ticketList, ok := bot.searches.get(chatID)
+	if !ok {
+		return messageList.Text(language, searchExpiredResponse), nil
+	}
+	if len(ticketList) == 0 {
+		return messageList.Text(language, searchNothingFoundResponse), nil
+	}
+	tickets := make([]Formatter.TemplateData, 0, len(ticketList))
+	for _, ticket := range ticketList {
+		tickets = append(tickets, Formatter.NewTemplateData(ticket, bot.Log).Localise(language, nil))
+	}
+	return ticketListPage(language, messageList.Text(language, searchHeaderResponse), tickets, page, searchCallbackPrefix)
+}
+
+// Parse search query. Words with "customer:", "state:" and "prio:" prefixes are filters, other words are text.
+// Return false if query is empty or filter has no value.
+func parseSearchQuery(arguments []string) (OTRSProvider.SearchCriteria, bool) {
+	criteria := OTRSProvider.SearchCriteria{Limit: searchLimit}
+	words := make([]string, 0, len(arguments))
+	for _, argument := range arguments {
+		filter := strings.SplitN(argument, ":", 2)
+		if len(filter) != 2 {
+			words = append(words, argument)
+			continue
+		}
+		if filter[1] == "" {
+			return criteria, false
+		}
+		switch strings.ToLower(filter[0]) {
+		case "customer":
+			criteria.CustomerID = filter[1]
+		case "state":
+			criteria.StateTypes = append(criteria.StateTypes, strings.ToLower(filter[1]))
+		case "prio", "priority":
+			criteria.Priorities = append(criteria.Priorities, filter[1])
+		default:
+			words = append(words, argument)
+		}
+	}
+	criteria.Text = strings.Join(words, " ")
+	empty := criteria.Text == "" && criteria.CustomerID == "" && len(criteria.StateTypes) == 0 && len(criteria.Priorities) == 0
+	return criteria, !empty
+}
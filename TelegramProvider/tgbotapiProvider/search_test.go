@@ -0,0 +1,77 @@
+package tgbotapiProvider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+)
+
+func TestSearchResultsExpire(t *testing.T) {
+	searches := newSearchResults()
+	searches.set(1, []OTRSProvider.TicketOTRS{{TicketNumber: "1"}})
+	searches.set(2, []OTRSProvider.TicketOTRS{{TicketNumber: "2"}})
+
+	// Age first result beyond TTL.
+	expired := searches.results[1]
+	expired.created = time.Now().Add(-searchResultTTL - time.Second)
+	searches.results[1] = expired
+
+	if _, ok := searches.get(1); ok {
+		t.Error("expired result returned")
+	}
+	if tickets, ok := searches.get(2); !ok || tickets[0].TicketNumber != "2" {
+		t.Errorf("fresh result lost - %v %v", tickets, ok)
+	}
+
+	// Expired results removed on next search.
+	searches.set(3, nil)
+	if _, ok := searches.results[1]; ok {
+		t.Error("expired result kept in memory")
+	}
+}
+
+func TestSearchResultsLimit(t *testing.T) {
+	searches := newSearchResults()
+	for chat := int64(0); chat < int64(searchResultsLimit)+10; chat++ {
+		searches.set(chat, nil)
+		// Distinct search times so oldest result is well defined.
+		result := searches.results[chat]
+		result.created = time.Now().Add(time.Duration(chat-int64(searchResultsLimit)) * time.Millisecond)
+		searches.results[chat] = result
+	}
+
+	if len(searches.results) != searchResultsLimit {
+		t.Errorf("%v results stored, want %v", len(searches.results), searchResultsLimit)
+	}
+	for chat := int64(0); chat < 10; chat++ {
+		if _, ok := searches.get(chat); ok {
+			t.Errorf("oldest result of chat %v kept", chat)
+		}
+	}
+	if _, ok := searches.get(int64(searchResultsLimit) + 9); !ok {
+		t.Error("newest result evicted")
+	}
+
+	// Repeated search of stored chat replaces result without eviction.
+	searches.set(int64(searchResultsLimit)+9, nil)
+	if len(searches.results) != searchResultsLimit {
+		t.Errorf("%v results stored after repeated search", len(searches.results))
+	}
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	criteria, ok := parseSearchQuery([]string{"printer", "customer:ACME", "state:Open", "prio:5", "broken"})
+	if !ok {
+		t.Fatal("valid query rejected")
+	}
+	if criteria.Text != "printer broken" || criteria.CustomerID != "ACME" || criteria.StateTypes[0] != "open" || criteria.Priorities[0] != "5" {
+		t.Errorf("unexpected criteria %+v", criteria)
+	}
+	if _, ok := parseSearchQuery([]string{"customer:"}); ok {
+		t.Error("filter without value accepted")
+	}
+	if _, ok := parseSearchQuery(nil); ok {
+		t.Error("empty query accepted")
+	}
+}
@@ -0,0 +1,225 @@
+package tgbotapiProvider
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"sync"
+	"time"
+)
+
+// Defaults based on Telegram API flood limits.
+const (
+	DefaultGlobalRateLimit int           = 25 // Messages per second for all chats.
+	DefaultChatRateLimit   int           = 1  // Messages per second for one private chat.
+	DefaultGroupRateLimit  int           = 20 // Messages per minute for one group or channel.
+	queueReportInterval    time.Duration = time.Minute
+	idleWait               time.Duration = time.Hour
+)
+
+// Single message waiting for send.
+type sendTask struct {
+	chatID   int64
+	message  Formatter.Message
+	priority int
+	sequence uint64     // Keep order for tasks with same priority.
+	result   chan error // Receive send result. Buffered so dispatcher never blocks.
+}
+
+// Central queue for all outgoing messages.
+// Dispatcher sends messages one by one with global and per chat intervals
+// and pause all sending if Telegram API responds with "retry_after".
+type sendQueue struct {
+	mx             sync.Mutex
+	tasks          []*sendTask
+	sequence       uint64
+	lastSent       time.Time           // Last send time for any chat.
+	lastSentByChat map[int64]time.Time // Last send time for each chat.
+	pausedUntil    time.Time           // Set after "Too Many Requests" response.
+	globalInterval time.Duration
+	chatInterval   time.Duration
+	groupInterval  time.Duration
+	wake           chan struct{}
+	stop           chan struct{}
+	stopOnce       sync.Once
+	send           func(chatID int64, message Formatter.Message) error
+	Log            logger.Logger
+}
+
+// Prepare queue with rates from config. Zero rate means default.
+func newSendQueue(globalRate, chatRate, groupRate int, send func(chatID int64, message Formatter.Message) error, log logger.Logger) *sendQueue {
+	if globalRate <= 0 {
+		globalRate = DefaultGlobalRateLimit
+	}
+	if chatRate <= 0 {
+		chatRate = DefaultChatRateLimit
+	}
+	if groupRate <= 0 {
+		groupRate = DefaultGroupRateLimit
+	}
+	return &sendQueue{
+		tasks:          make([]*sendTask, 0, 128),
+		lastSentByChat: make(map[int64]time.Time),
+		globalInterval: time.Second / time.Duration(globalRate),
+		chatInterval:   time.Second / time.Duration(chatRate),
+		groupInterval:  time.Minute / time.Duration(groupRate),
+		wake:           make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		send:           send,
+		Log:            log,
+	}
+}
+
+// Put message into queue and wait for send result.
+func (q *sendQueue) Send(chatID int64, message Formatter.Message, priority int) error {
+	task := &sendTask{
+		chatID:   chatID,
+		message:  message,
+		priority: priority,
+		result:   make(chan error, 1),
+	}
+
+	q.mx.Lock()
+	q.sequence++
+	task.sequence = q.sequence
+	q.tasks = append(q.tasks, task)
+	q.mx.Unlock()
+	q.notify()
+
+	select {
+	case err := <-task.result:
+		return err
+	case <-q.stop:
+		return myErrors.ErrSendQueueStopped
+	}
+}
+
+// Return number of messages waiting for send.
+func (q *sendQueue) Depth() int {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+	return len(q.tasks)
+}
+
+// Stop dispatcher. Waiting senders receive ErrSendQueueStopped.
+func (q *sendQueue) Stop() {
+	q.stopOnce.Do(func() { close(q.stop) })
+}
+
+// Wake dispatcher without blocking.
+func (q *sendQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Send tasks until stopped.
+func (q *sendQueue) run() {
+	reportTicker := time.NewTicker(queueReportInterval)
+	defer reportTicker.Stop()
+
+	for {
+		task, wait := q.next(time.Now())
+		if task == nil {
+			timer := time.NewTimer(wait)
+			select {
+			case <-q.wake:
+			case <-timer.C:
+			case <-reportTicker.C:
+				q.report()
+			case <-q.stop:
+				timer.Stop()
+				return
+			}
+			timer.Stop()
+			continue
+		}
+
+		err := q.send(task.chatID, task.message)
+		q.complete(task, err)
+	}
+}
+
+// Take task with highest priority among chats ready for send.
+// If no task ready return time to wait for next check.
+func (q *sendQueue) next(now time.Time) (*sendTask, time.Duration) {
+	q.mx.Lock()
+	defer q.mx.Unlock()
+
+	if len(q.tasks) == 0 {
+		return nil, idleWait
+	}
+	if now.Before(q.pausedUntil) {
+		return nil, q.pausedUntil.Sub(now)
+	}
+	if globalReady := q.lastSent.Add(q.globalInterval); now.Before(globalReady) {
+		return nil, globalReady.Sub(now)
+	}
+
+	bestIndex := -1
+	wait := idleWait
+	for i, task := range q.tasks {
+		chatReady := q.lastSentByChat[task.chatID].Add(q.intervalForChat(task.chatID))
+		if now.Before(chatReady) {
+			if chatReady.Sub(now) < wait {
+				wait = chatReady.Sub(now)
+			}
+			continue
+		}
+		if bestIndex < 0 || isBefore(task, q.tasks[bestIndex]) {
+			bestIndex = i
+		}
+	}
+	if bestIndex < 0 {
+		return nil, wait
+	}
+
+	task := q.tasks[bestIndex]
+	q.tasks = append(q.tasks[:bestIndex], q.tasks[bestIndex+1:]...)
+	q.lastSent = now
+	q.lastSentByChat[task.chatID] = now
+	return task, 0
+}
+
+// Return task to queue if Telegram asked to retry later, otherwise pass result to sender.
+func (q *sendQueue) complete(task *sendTask, err error) {
+	apiErr, ok := err.(tgbotapi.Error)
+	if !ok || apiErr.RetryAfter <= 0 {
+		task.result <- err
+		return
+	}
+
+	retryAfter := time.Duration(apiErr.RetryAfter) * time.Second
+	q.Log.Warning(fmt.Sprintf("Flood limit reached while send to chat '%v'. Pause sending for '%v'", task.chatID, retryAfter))
+	q.mx.Lock()
+	q.pausedUntil = time.Now().Add(retryAfter)
+	q.tasks = append(q.tasks, task)
+	q.mx.Unlock()
+}
+
+// Write queue depth into log if something waiting.
+func (q *sendQueue) report() {
+	depth := q.Depth()
+	if depth > 0 {
+		q.Log.Info(fmt.Sprintf("Send queue depth '%v'", depth))
+	}
+}
+
+// Groups and channels have negative chat ID and much lower limit than private chats.
+func (q *sendQueue) intervalForChat(chatID int64) time.Duration {
+	if chatID < 0 {
+		return q.groupInterval
+	}
+	return q.chatInterval
+}
+
+// Lower priority value sent first. Same priority sent in order of receiving.
+func isBefore(a, b *sendTask) bool {
+	if a.priority != b.priority {
+		return a.priority < b.priority
+	}
+	return a.sequence < b.sequence
+}
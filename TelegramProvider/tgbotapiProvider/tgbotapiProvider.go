@@ -3,33 +3,60 @@ package tgbotapiProvider
 import (
 	"context"
 	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
 	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/locale"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"log"
+	"strings"
 )
 
-const ModuleName = "TelegramProvider TgBotApi"
+const (
+	ModuleName         string = "TelegramProvider TgBotApi"
+	ModePolling        string = "polling"
+	ModeWebhook        string = "webhook"
+	DefaultWebhookPath string = "/telegram"
+)
 
 // Implement TelegramProvider interface.
 type TelegramModule struct {
-	bot *tgbotapi.BotAPI
-	Log logger.Logger
-	DB  *DBProvider.DBProvider
+	bot            *tgbotapi.BotAPI
+	Conf           config.TelegramConf
+	Log            logger.Logger
+	DB             *DBProvider.DBProvider
+	OTRS           *OTRSProvider.Instances
+	Client         *ClientProvider.ClientProvider
+	webhookUpdates chan tgbotapi.Update // Updates received by webhook handler.
+	queue          *sendQueue           // All outgoing messages sent through it.
+	searches       *searchResults       // Last search result by chat.
 }
 
 // Contain command name and offset.
 type Command struct {
-	Name   string // Command name.
-	Offset uint64 // First command character (after slash) in message text.
+	Name      string // Command name without bot name suffix.
+	Offset    uint64 // First command character (after slash) in message text.
+	Length    uint64 // Command length in message text including "@botname" suffix.
+	Addressed bool   // True if command explicitly addressed to this bot by "@botname" suffix.
 }
 
 // Initialise telegram bot.
-// Add created bot and provided logger and DB modules into provider.
-func (bot *TelegramModule) Initialise(botToken string, logger logger.Logger, db *DBProvider.DBProvider) error {
+// Add created bot and provided logger, DB, OTRS and Client modules into provider.
+func (bot *TelegramModule) Initialise(
+	conf config.TelegramConf,
+	logger logger.Logger,
+	db *DBProvider.DBProvider,
+	otrs *OTRSProvider.Instances,
+	client *ClientProvider.ClientProvider,
+) error {
 	logger = logger.SetModuleName(ModuleName)
 	logger.Debug("Initialisation started")
-	newBot, err := tgbotapi.NewBotAPI(botToken)
+	newBot, err := tgbotapi.NewBotAPI(conf.Token)
 	if err != nil {
 		logger.Error(fmt.Sprintf("Initialisation failed - '%v'", err))
 		return err
@@ -42,14 +69,59 @@ func (bot *TelegramModule) Initialise(botToken string, logger logger.Logger, db
 	logger.Debug(fmt.Sprintf("Authorized on account %s", newBot.Self.UserName))
 	logger.Debug("Initialisation complete")
 
+	// Fill defaults.
+	if conf.Mode == "" {
+		conf.Mode = ModePolling
+	}
+	if conf.WebhookPath == "" {
+		conf.WebhookPath = DefaultWebhookPath
+	}
+
 	bot.bot = newBot
+	bot.Conf = conf
 	bot.Log = logger
 	bot.DB = db
+	bot.OTRS = otrs
+	bot.Client = client
+	bot.webhookUpdates = make(chan tgbotapi.Update, 100)
+	bot.searches = newSearchResults()
+	bot.queue = newSendQueue(conf.RateLimit, conf.ChatRateLimit, conf.GroupRateLimit,
+		func(chatID int64, message Formatter.Message) error {
+			return sendFormattedMessage(newBot, chatID, message, logger)
+		},
+		logger,
+	)
+	go bot.queue.run()
 	return nil
 }
 
 // Listener for Telegram API updates with context control.
+// In webhook mode fallback to polling if webhook can't be set.
+// Send queue stopped with listener.
 func (bot *TelegramModule) UpdateListener(ctx context.Context, cancel context.CancelFunc) error {
+	defer bot.queue.Stop()
+
+	if bot.Conf.Mode == ModeWebhook {
+		err := bot.setWebhook()
+		if err == nil {
+			bot.Log.Info(fmt.Sprintf("Receive updates by webhook '%v'", bot.Conf.WebhookURL))
+			return bot.listenWebhook(ctx)
+		}
+		bot.Log.Error(fmt.Sprintf("Can't set webhook - '%v'. Fallback to polling", err))
+	}
+
+	bot.Log.Info("Receive updates by polling")
+	return bot.listenPolling(ctx, cancel)
+}
+
+// Receive updates by long polling.
+func (bot *TelegramModule) listenPolling(ctx context.Context, cancel context.CancelFunc) error {
+	// Polling not allowed while webhook set.
+	err := bot.deleteWebhook()
+	if err != nil {
+		bot.Log.Warning(fmt.Sprintf("Can't delete webhook before polling - '%v'", err))
+	}
+
 	// Initialise API listener.
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -64,62 +136,200 @@ func (bot *TelegramModule) UpdateListener(ctx context.Context, cancel context.Ca
 	for {
 		select {
 		case update := <-updates:
-			go messageProcessor(*bot, *update.Message)
+			bot.updateProcessor(update)
 		case <-ctx.Done():
+			bot.bot.StopReceivingUpdates()
 			log.Printf("Closing signal goroutine")
 			return ctx.Err()
 		}
 	}
 }
 
-func (bot *TelegramModule) SendEventMessage(chatID int64, text string) error {
-	return sendPlainTextMessage(bot.bot, chatID, text)
+// Receive updates pushed into webhook handler. Delete webhook on context done.
+func (bot *TelegramModule) listenWebhook(ctx context.Context) error {
+	for {
+		select {
+		case update := <-bot.webhookUpdates:
+			bot.updateProcessor(update)
+		case <-ctx.Done():
+			err := bot.deleteWebhook()
+			if err != nil {
+				bot.Log.Error(fmt.Sprintf("Can't delete webhook - '%v'", err))
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// Dispatch update by type.
+func (bot *TelegramModule) updateProcessor(update tgbotapi.Update) {
+	// Channels send posts instead of messages. Other update types not supported.
+	switch {
+	case update.Message != nil:
+		go messageProcessor(*bot, *update.Message)
+	case update.ChannelPost != nil:
+		go messageProcessor(*bot, *update.ChannelPost)
+	case update.CallbackQuery != nil:
+		go callbackProcessor(*bot, *update.CallbackQuery)
+	default:
+		bot.Log.Debug(fmt.Sprintf("Skip unsupported update '%v'", update.UpdateID))
+	}
+}
+
+// Send message through send queue and wait for result.
+func (bot *TelegramModule) SendEventMessage(chatID int64, message Formatter.Message, priority int) error {
+	return bot.queue.Send(chatID, message, priority)
+}
+
+// Return number of messages waiting in send queue.
+func (bot *TelegramModule) QueueDepth() int {
+	return bot.queue.Depth()
 }
 
-//
+// Process commands from private chat, group or channel.
+// In groups and channels bot stay silent for anything not addressed to it.
 func messageProcessor(bot TelegramModule, message tgbotapi.Message) {
-	commandList := extractCommandList(message)
+	if message.Chat == nil {
+		bot.Log.Debug(fmt.Sprintf("Received message '%v' without chat", message.MessageID))
+		return
+	}
+	isPrivate := message.Chat.IsPrivate()
+	commandList := extractCommandList(message, bot.bot.Self.UserName)
 
 	// If no commands received stop message processing.
 	if len(commandList) == 0 {
 		bot.Log.Debug(fmt.Sprintf("Received no command in message '%v'", message.Text))
-		sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, noCommandInMessage, bot.Log)
+		if isPrivate {
+			bot.reply(message.Chat.ID, bot.language(message), noCommandInMessage)
+		}
 		return
 	}
 
 	// Process all provided commands.
+	language := bot.language(message)
 	for _, command := range commandList {
 		bot.Log.Debug(fmt.Sprintf("Received command '%v' in message '%v'", command.Name, message.Text))
-		switch command.Name {
-		case "help":
-			sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, helpCommandResponse, bot.Log)
-		case "firstName":
-			commandFirstName(bot, message, command)
-		case "lastName":
-			commandLastName(bot, message, command)
-		case "start":
-			commandStart(bot, message)
-		case "subscribeTeam1", "subscribeTeam2", "subscribeTeam3":
-			commandSubscribe(bot, message, command)
-		case "unsubscribeTeam1", "unsubscribeTeam2", "unsubscribeTeam3":
-			commandUnsubscribe(bot, message, command)
+		switch {
+		case command.Name == "help":
+			bot.reply(message.Chat.ID, language, helpCommandResponse)
+		case command.Name == "firstName" && isPrivate:
+			commandFirstName(bot, message, command, language)
+		case command.Name == "lastName" && isPrivate:
+			commandLastName(bot, message, command, language)
+		case command.Name == "firstName", command.Name == "lastName":
+			bot.reply(message.Chat.ID, language, privateChatOnlyResponse)
+		case command.Name == "start":
+			commandStart(bot, message, language)
+		case command.Name == "language":
+			language = commandLanguage(bot, message, command, language)
+		case command.Name == "timezone":
+			commandTimezone(bot, message, command, language)
+		case command.Name == "dnd":
+			commandDND(bot, message, command, language)
+		case command.Name == "quiet":
+			commandQuiet(bot, message, command, language)
+		case command.Name == "reminders":
+			commandReminders(bot, message, command, language)
+		case command.Name == "tickets":
+			commandTickets(bot, message, language)
+		case command.Name == "ticket":
+			commandTicket(bot, message, command, language)
+		case command.Name == "history":
+			commandHistory(bot, message, command, language)
+		case command.Name == "search":
+			commandSearch(bot, message, command, language)
+		case command.Name == "preview":
+			commandPreview(bot, message, command, language)
+		case strings.HasPrefix(command.Name, "subscribeTeam") && isKnownTeam(command.Name[9:]):
+			commandSubscribe(bot, message, command, language)
+		case strings.HasPrefix(command.Name, "unsubscribeTeam") && isKnownTeam(command.Name[11:]):
+			commandUnsubscribe(bot, message, command, language)
+		case isPrivate || command.Addressed:
+			bot.reply(message.Chat.ID, language, invalidCommandResponse)
 		default:
-			sendPlainTextMessageLogErr(bot.bot, message.Chat.ID, invalidCommandResponse, bot.Log)
+			// Unknown command in group may be addressed to another bot.
+			bot.Log.Debug(fmt.Sprintf("Skip unknown command '%v' in chat '%v'", command.Name, message.Chat.ID))
 		}
 	}
 }
 
+// Check team name from subscription commands.
+func isKnownTeam(team string) bool {
+	switch team {
+	case "Team1", "Team2", "Team3", catchAllTeam:
+		return true
+	}
+	return false
+}
+
+// Return language chosen for chat. For new chats use language from Telegram user settings.
+func (bot TelegramModule) language(message tgbotapi.Message) string {
+	settings, err := (*bot.DB).BotUserGetSettings(message.Chat.ID)
+	if err != nil && err != myErrors.ErrNoUsersFound {
+		bot.Log.Error(fmt.Sprintf("Can't get language for chat '%v' - '%v'", message.Chat.ID, err))
+	}
+	if locale.IsSupported(settings.Language) {
+		return settings.Language
+	}
+	if message.From == nil {
+		return locale.Default
+	}
+	return locale.FromTelegramCode(message.From.LanguageCode)
+}
+
 // Send simple text message into provided chat.
-func sendPlainTextMessage(bot *tgbotapi.BotAPI, chatID int64, text string) error {
+func sendPlainTextMessage(bot *tgbotapi.BotAPI, chatID int64, text string, keyboard [][]Formatter.Button) error {
 	msg := tgbotapi.NewMessage(chatID, text)
+	if len(keyboard) > 0 {
+		msg.ReplyMarkup = inlineKeyboard(keyboard)
+	}
+	_, err := bot.Send(msg)
+	return err
+}
+
+// Send message with markup into provided chat.
+// If Telegram can't parse markup send plain text version instead.
+func sendFormattedMessage(bot *tgbotapi.BotAPI, chatID int64, message Formatter.Message, Log logger.Logger) error {
+	if message.ParseMode == Formatter.ParseModePlain {
+		return sendPlainTextMessage(bot, chatID, message.PlainText, message.Keyboard)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, message.Text)
+	msg.ParseMode = message.ParseMode
+	msg.DisableWebPagePreview = true
+	if len(message.Keyboard) > 0 {
+		msg.ReplyMarkup = inlineKeyboard(message.Keyboard)
+	}
 	_, err := bot.Send(msg)
+	if apiErr, ok := err.(tgbotapi.Error); ok && strings.Contains(apiErr.Message, "can't parse entities") {
+		Log.Warning(fmt.Sprintf("Markup rejected for chat '%v' - '%v'. Send plain text", chatID, err))
+		return sendPlainTextMessage(bot, chatID, message.PlainText, message.Keyboard)
+	}
 	return err
 }
 
-// Send simple text message into provided chat and log error if occurred.
-func sendPlainTextMessageLogErr(bot *tgbotapi.BotAPI, chatID int64, text string, Log logger.Logger) {
-	err := sendPlainTextMessage(bot, chatID, text)
+// Convert buttons into Telegram inline keyboard.
+func inlineKeyboard(keyboard [][]Formatter.Button) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(keyboard))
+	for _, buttons := range keyboard {
+		row := make([]tgbotapi.InlineKeyboardButton, 0, len(buttons))
+		for _, button := range buttons {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(button.Text, button.Data))
+		}
+		rows = append(rows, row)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// Send response from message catalog in provided language.
+func (bot TelegramModule) reply(chatID int64, language, key string) {
+	bot.replyText(chatID, messageList.Text(language, key))
+}
+
+// Send response to command through send queue and log error if occurred.
+func (bot TelegramModule) replyText(chatID int64, text string) {
+	err := bot.queue.Send(chatID, Formatter.PlainMessage(text), TelegramProvider.PriorityReply)
 	if err != nil {
-		Log.Error(fmt.Sprintf("Can't send message - '%v'", err))
+		bot.Log.Error(fmt.Sprintf("Can't send message - '%v'", err))
 	}
 }
@@ -0,0 +1,109 @@
+package tgbotapiProvider
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger/CLILogger"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Fake Bot API answering sendMessage. Messages with parse mode answered with configured error.
+type fakeBotAPI struct {
+	*httptest.Server
+	markupError string // Error description for messages with parse mode. Accepted if empty.
+
+	mx       sync.Mutex
+	messages []url.Values
+}
+
+func newFakeBotAPI(t *testing.T, markupError string) (*fakeBotAPI, *tgbotapi.BotAPI) {
+	t.Helper()
+	api := &fakeBotAPI{markupError: markupError}
+	api.Server = httptest.NewServer(http.HandlerFunc(api.handle))
+	t.Cleanup(api.Close)
+
+	// Bot API endpoint fixed in library, so requests redirected to fake server by transport.
+	target, _ := url.Parse(api.URL)
+	client := &http.Client{Transport: redirectTransport{target: target}}
+	return api, &tgbotapi.BotAPI{Token: "token", Client: client, Buffer: 100}
+}
+
+func (api *fakeBotAPI) handle(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	api.mx.Lock()
+	api.messages = append(api.messages, r.PostForm)
+	api.mx.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.PostForm.Get("parse_mode") != "" && api.markupError != "" {
+		fmt.Fprintf(w, `{"ok": false, "error_code": 400, "description": %q}`, api.markupError)
+		return
+	}
+	fmt.Fprint(w, `{"ok": true, "result": {"message_id": 1, "chat": {"id": 42, "type": "private"}, "date": 0}}`)
+}
+
+func (api *fakeBotAPI) sent() []url.Values {
+	api.mx.Lock()
+	defer api.mx.Unlock()
+	return append([]url.Values(nil), api.messages...)
+}
+
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestSendFormattedMessage(t *testing.T) {
+	message := Formatter.Message{Text: "<b>Ticket</b> 1", ParseMode: Formatter.ParseModeHTML, PlainText: "Ticket 1"}
+	cases := []struct {
+		name        string
+		message     Formatter.Message
+		markupError string
+		wantErr     bool
+		wantTexts   []string // Text of every request in order.
+	}{
+		{name: "markup accepted", message: message, wantTexts: []string{"<b>Ticket</b> 1"}},
+		{name: "markup rejected", message: message, markupError: "Bad Request: can't parse entities: unexpected end tag at byte offset 5",
+			wantTexts: []string{"<b>Ticket</b> 1", "Ticket 1"}},
+		// Other errors not fixed by plain text.
+		{name: "other error", message: message, markupError: "Bad Request: chat not found", wantErr: true,
+			wantTexts: []string{"<b>Ticket</b> 1"}},
+		{name: "plain message", message: Formatter.PlainMessage("Ticket <1>"), markupError: "Bad Request: can't parse entities",
+			wantTexts: []string{"Ticket <1>"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			api, bot := newFakeBotAPI(t, c.markupError)
+
+			err := sendFormattedMessage(bot, 42, c.message, CLILogger.NewDefault())
+
+			if (err != nil) != c.wantErr {
+				t.Errorf("error '%v', want error %v", err, c.wantErr)
+			}
+			sent := api.sent()
+			if len(sent) != len(c.wantTexts) {
+				t.Fatalf("%v requests, want %v", len(sent), len(c.wantTexts))
+			}
+			for index, form := range sent {
+				if form.Get("text") != c.wantTexts[index] {
+					t.Errorf("request %v text %q, want %q", index, form.Get("text"), c.wantTexts[index])
+				}
+			}
+			// Plain text fallback sent without parse mode.
+			if last := sent[len(sent)-1]; c.markupError != "" && !c.wantErr && last.Get("parse_mode") != "" {
+				t.Errorf("fallback sent with parse mode '%v'", last.Get("parse_mode"))
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package tgbotapiProvider
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ticketsPageSize       int    = 5          // Tickets on one page of /tickets response.
+	ticketsCallbackPrefix string = "tickets:" // Callback data prefix for /tickets page buttons. Page number follows.
+	historyMaxLength      int    = 4000       // Longer /history response cut to fit Telegram message limit.
+	catchAllTeam          string = "TeamAll"  // Subscription to tickets of clients without team.
+)
+
+// Show first page of active events for teams to which chat subscribed.
+func commandTickets(bot TelegramModule, message tgbotapi.Message, language string) {
+	text, keyboard := bot.ticketsPage(message.Chat.ID, language, 0)
+	response := Formatter.PlainMessage(text)
+	response.Keyboard = keyboard
+	err := bot.queue.Send(message.Chat.ID, response, TelegramProvider.PriorityReply)
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't send message - '%v'", err))
+	}
+}
+
+// Process inline button press. Only /tickets and /search page buttons supported.
+func callbackProcessor(bot TelegramModule, query tgbotapi.CallbackQuery) {
+	defer func() {
+		_, err := bot.bot.AnswerCallbackQuery(tgbotapi.NewCallback(query.ID, ""))
+		if err != nil {
+			bot.Log.Warning(fmt.Sprintf("Can't answer callback query '%v' - '%v'", query.ID, err))
+		}
+	}()
+	if query.Message == nil || query.Message.Chat == nil {
+		bot.Log.Debug(fmt.Sprintf("Skip callback query '%v' without message", query.ID))
+		return
+	}
+	data := strings.SplitN(query.Data, ":", 2)
+	if len(data) != 2 {
+		bot.Log.Debug(fmt.Sprintf("Skip unsupported callback query with data '%v'", query.Data))
+		return
+	}
+	page, err := strconv.Atoi(data[1])
+	if err != nil || page < 0 {
+		bot.Log.Debug(fmt.Sprintf("Invalid page in callback query data '%v'", query.Data))
+		return
+	}
+
+	// Language chosen by user who pressed button.
+	message := *query.Message
+	message.From = query.From
+	language := bot.language(message)
+
+	var text string
+	var keyboard [][]Formatter.Button
+	switch fmt.Sprint(data[0], ":") {
+	case ticketsCallbackPrefix:
+		text, keyboard = bot.ticketsPage(message.Chat.ID, language, page)
+	case searchCallbackPrefix:
+		text, keyboard = bot.searchPage(message.Chat.ID, language, page)
+	default:
+		bot.Log.Debug(fmt.Sprintf("Skip unsupported callback query with data '%v'", query.Data))
+		return
+	}
+	edit := tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, text)
+	if len(keyboard) > 0 {
+		markup := inlineKeyboard(keyboard)
+		edit.ReplyMarkup = &markup
+	}
+	_, err = bot.bot.Send(edit)
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't edit message '%v' in chat '%v' - '%v'", message.MessageID, message.Chat.ID, err))
+	}
+}
+
+// Return text and navigation buttons for page of active events.
+// Tickets without team shown only to chats subscribed to catch-all team.
+func (bot TelegramModule) ticketsPage(chatID int64, language string, page int) (string, [][]Formatter.Button) {
+	teamList, denied := bot.readerTeams(chatID)
+	if denied != "" {
+		return messageList.Text(language, denied), nil
+	}
+
+	eventList, err := (*bot.DB).OTRSEventGetAllActive()
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't get active events - '%v'", err))
+		return messageList.Text(language, errorWileGetTickets), nil
+	}
+	tickets := make([]Formatter.TemplateData, 0, len(eventList))
+	for index, result := range bot.OTRS.GetTicketDetailsList(ticketRefs(eventList)) {
+		event := eventList[index]
+		ticketDetails, err := result.Ticket, result.Err
+		if err != nil {
+			bot.Log.Error(fmt.Sprintf("Can't get ticket '%v' from instance '%v' - '%v'", event.TicketID, event.Instance, err))
+			continue
+		}
+		if !bot.isTicketVisible(teamList, ticketDetails.CustomerID) {
+			continue
+		}
+		tickets = append(tickets, Formatter.NewTemplateData(ticketDetails, bot.Log).Localise(language, nil))
+	}
+	if len(tickets) == 0 {
+		return messageList.Text(language, noTicketsResponse), nil
+	}
+	Formatter.SortByUrgency(tickets)
+	return ticketListPage(language, messageList.Text(language, ticketsHeaderResponse), tickets, page, ticketsCallbackPrefix)
+}
+
+// Return OTRS tickets of events in same order.
+func ticketRefs(eventList []DBProvider.OTRSEvent) []OTRSProvider.TicketRef {
+	refs := make([]OTRSProvider.TicketRef, 0, len(eventList))
+	for _, event := range eventList {
+		refs = append(refs, OTRSProvider.TicketRef{Instance: event.Instance, TicketID: fmt.Sprint(event.TicketID)})
+	}
+	return refs
+}
+
+// Return text and navigation buttons for page of ticket list.
+// Page buttons return callback data with provided prefix and page number.
+func ticketListPage(language, header string, tickets []Formatter.TemplateData, page int, callbackPrefix string) (string, [][]Formatter.Button) {
+	// Show last page if tickets closed since previous page shown.
+	pageCount := (len(tickets) + ticketsPageSize - 1) / ticketsPageSize
+	if page >= pageCount {
+		page = pageCount - 1
+	}
+	first := page * ticketsPageSize
+	last := first + ticketsPageSize
+	if last > len(tickets) {
+		last = len(tickets)
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("%v %v-%v / %v\n", header, first+1, last, len(tickets)))
+	for _, ticket := range tickets[first:last] {
+		text.WriteString(fmt.Sprintf("\n%v %v   UP %v   %v", Formatter.PriorityEmoji(ticket.Priority), ticket.TicketNumber, ticket.Age, ticket.CustomerID))
+		if ticket.Instance != "" {
+			text.WriteString(fmt.Sprintf("   [%v]", ticket.Instance))
+		}
+		if ticket.Lock == "lock" {
+			text.WriteString(" 🔒")
+		}
+		text.WriteString(fmt.Sprintf("\n%v\n%v\n", ticket.Title, ticket.URL))
+	}
+
+	buttons := make([]Formatter.Button, 0, 2)
+	if page > 0 {
+		buttons = append(buttons, Formatter.Button{Text: "◀", Data: fmt.Sprint(callbackPrefix, page-1)})
+	}
+	if page < pageCount-1 {
+		buttons = append(buttons, Formatter.Button{Text: "▶", Data: fmt.Sprint(callbackPrefix, page+1)})
+	}
+	if len(buttons) == 0 {
+		return text.String(), nil
+	}
+	return text.String(), [][]Formatter.Button{buttons}
+}
+
+// Return teams of chat allowed to read tickets. Only registered chats with subscriptions can read tickets,
+// otherwise key of response for chat returned.
+func (bot TelegramModule) readerTeams(chatID int64) (map[string]bool, string) {
+	teamList, err := bot.chatTeams(chatID)
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't get subscriptions for chat '%v' - '%v'", chatID, err))
+		return nil, errorWileGetTickets
+	}
+	if len(teamList) == 0 {
+		return nil, noSubscriptionsResponse
+	}
+	return teamList, ""
+}
+
+// Check if ticket of client visible for chat subscribed to teams.
+// Tickets without team or with ambiguous team visible only with catch-all subscription.
+// Ticket hidden if team can't be found because of error.
+func (bot TelegramModule) isTicketVisible(teamList map[string]bool, customerID string) bool {
+	team, err := (*bot.Client).GetTeamByClient(customerID)
+	switch err {
+	case nil:
+	case myErrors.ErrNoTeamBounded, myErrors.ErrClientNotExists, myErrors.ErrMoreThenOneTeamBounded:
+		return teamList[catchAllTeam]
+	default:
+		bot.Log.Error(fmt.Sprintf("Can't get team for client '%v'. Hide ticket - '%v'", customerID, err))
+		return false
+	}
+	if team == "" {
+		return teamList[catchAllTeam]
+	}
+	return teamList[team]
+}
+
+// Return set of teams to which chat subscribed. Empty for unknown chat.
+func (bot TelegramModule) chatTeams(chatID int64) (map[string]bool, error) {
+	userID, err := (*bot.DB).BotUserGetByTelegramID(chatID)
+	if err == myErrors.ErrNoUsersFound {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	subscriptionList, err := (*bot.DB).SubscriptionListGetActiveByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	teamList := make(map[string]bool, len(subscriptionList))
+	for _, subscription := range subscriptionList {
+		teamList[subscription] = true
+	}
+	return teamList, nil
+}
+
+// Return events of ticket found by number or ID from first argument. Optional second argument limits search
+// to OTRS instance, otherwise instances searched in configuration order and events of first instance
+// with ticket returned.
+func (bot TelegramModule) ticketEvents(arguments []string) ([]DBProvider.OTRSEvent, error) {
+	// Same number or ID may exist in several instances.
+	instanceList := bot.OTRS.Names()
+	if len(arguments) > 1 {
+		instanceList = []string{arguments[1]}
+	}
+	for _, instance := range instanceList {
+		eventList, err := (*bot.DB).OTRSEventGetByTicket(instance, arguments[0])
+		if err != nil || len(eventList) > 0 {
+			return eventList, err
+		}
+	}
+	return nil, nil
+}
+
+// Show live ticket details from OTRS and history of bot events for ticket.
+// Ticket searched by number or ID among tickets known by bot. Optional second argument limits search to OTRS instance,
+// otherwise ticket of first instance in configuration order shown.
+func commandTicket(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	arguments := getCommandArguments(message.Text, command)
+	if len(arguments) < 1 {
+		bot.reply(message.Chat.ID, language, ticketUsageResponse)
+		return
+	}
+	teamList, denied := bot.readerTeams(message.Chat.ID)
+	if denied != "" {
+		bot.reply(message.Chat.ID, language, denied)
+		return
+	}
+
+	eventList, err := bot.ticketEvents(arguments)
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't get events for ticket '%v' - '%v'", arguments[0], err))
+		bot.reply(message.Chat.ID, language, errorWileGetTickets)
+		return
+	}
+	if len(eventList) == 0 {
+		bot.reply(message.Chat.ID, language, ticketNotFoundResponse)
+		return
+	}
+	ticketDetails, err := bot.OTRS.GetTicketDetails(eventList[0].Instance, fmt.Sprint(eventList[0].TicketID))
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't get ticket '%v' from instance '%v' - '%v'", eventList[0].TicketID, eventList[0].Instance, err))
+		bot.reply(message.Chat.ID, language, errorWileGetTickets)
+		return
+	}
+	// Ticket of other team shown as unknown.
+	if !bot.isTicketVisible(teamList, ticketDetails.CustomerID) {
+		bot.reply(message.Chat.ID, language, ticketNotFoundResponse)
+		return
+	}
+
+	// Absolute time in chat timezone.
+	settings, _ := (*bot.DB).BotUserGetSettings(message.Chat.ID)
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil || settings.Timezone == "" {
+		location = nil
+	}
+	ticket := Formatter.NewTemplateData(ticketDetails, bot.Log).Localise(language, location)
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("%v %v %v\n%v\n\n", Formatter.PriorityEmoji(ticket.Priority), messageList.Text(language, ticketTitleResponse), ticket.TicketNumber, ticket.Title))
+	fields := []struct{ key, value string }{
+		{ticketCustomerResponse, ticket.CustomerID},
+		{ticketPriorityResponse, ticket.Priority},
+		{ticketStateResponse, ticket.State},
+		{ticketLockResponse, ticket.Lock},
+		{ticketOwnerResponse, ticket.Owner},
+		{ticketCreatedResponse, fmt.Sprintf("%v (UP %v)", ticket.CreatedLocal, ticket.Age)},
+	}
+	if ticket.Instance != "" {
+		fields = append(fields, struct{ key, value string }{ticketInstanceResponse, ticket.Instance})
+	}
+	for _, field := range fields {
+		text.WriteString(fmt.Sprintf("%v: %v\n", messageList.Text(language, field.key), field.value))
+	}
+	text.WriteString(fmt.Sprintf("%v\n\n%v:\n", ticket.URL, messageList.Text(language, ticketEventsResponse)))
+	for _, event := range eventList {
+		text.WriteString(fmt.Sprintf("#%v %v %v %v", event.ID, event.Type, event.Status, displayTime(event.Created, settings.Timezone)))
+		if event.Finished != 0 {
+			text.WriteString(fmt.Sprint(" - ", displayTime(event.Finished, settings.Timezone)))
+		}
+		text.WriteString("\n")
+	}
+	bot.replyText(message.Chat.ID, text.String())
+}
+
+// Show history of bot events for ticket: status changes, ticket changes in OTRS, notifications and owner actions.
+// Ticket searched like in /ticket command.
+func commandHistory(bot TelegramModule, message tgbotapi.Message, command Command, language string) {
+	arguments := getCommandArguments(message.Text, command)
+	if len(arguments) < 1 {
+		bot.reply(message.Chat.ID, language, historyUsageResponse)
+		return
+	}
+	teamList, denied := bot.readerTeams(message.Chat.ID)
+	if denied != "" {
+		bot.reply(message.Chat.ID, language, denied)
+		return
+	}
+
+	eventList, err := bot.ticketEvents(arguments)
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't get events for ticket '%v' - '%v'", arguments[0], err))
+		bot.reply(message.Chat.ID, language, errorWileGetTickets)
+		return
+	}
+	if len(eventList) == 0 {
+		bot.reply(message.Chat.ID, language, ticketNotFoundResponse)
+		return
+	}
+	// Client of ticket needed to check team. Ticket of other team shown as unknown.
+	ticketDetails, err := bot.OTRS.GetTicketDetails(eventList[0].Instance, fmt.Sprint(eventList[0].TicketID))
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't get ticket '%v' from instance '%v' - '%v'", eventList[0].TicketID, eventList[0].Instance, err))
+		bot.reply(message.Chat.ID, language, errorWileGetTickets)
+		return
+	}
+	if !bot.isTicketVisible(teamList, ticketDetails.CustomerID) {
+		bot.reply(message.Chat.ID, language, ticketNotFoundResponse)
+		return
+	}
+
+	settings, _ := (*bot.DB).BotUserGetSettings(message.Chat.ID)
+	ticketNumber := eventList[0].TicketNumber
+	if ticketNumber == "" {
+		ticketNumber = arguments[0]
+	}
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("%v %v", messageList.Text(language, historyHeaderResponse), ticketNumber))
+	if eventList[0].Instance != "" {
+		text.WriteString(fmt.Sprintf(" [%v]", eventList[0].Instance))
+	}
+	text.WriteString("\n")
+	for _, event := range eventList {
+		historyList, err := (*bot.DB).EventHistoryGetByEvent(event.ID)
+		if err != nil {
+			bot.Log.Error(fmt.Sprintf("Can't get history of event '%v' - '%v'", event.ID, err))
+			bot.reply(message.Chat.ID, language, errorWileGetTickets)
+			return
+		}
+		text.WriteString(fmt.Sprintf("\n#%v %v %v\n", event.ID, event.Type, event.Status))
+		for _, entry := range historyList {
+			text.WriteString(historyLine(entry, settings.Timezone))
+		}
+	}
+
+	response := text.String()
+	if len(response) > historyMaxLength {
+		response = fmt.Sprint(strings.ToValidUTF8(response[:historyMaxLength], ""), "\n...")
+	}
+	bot.replyText(message.Chat.ID, response)
+}
+
+// Format history entry as single line.
+func historyLine(entry DBProvider.EventHistory, timezone string) string {
+	line := fmt.Sprint(displayTime(entry.Created, timezone), " ", entry.Kind, " ", entry.Details)
+	if entry.Actor != "" {
+		line = fmt.Sprint(line, " (", entry.Actor, ")")
+	}
+	return fmt.Sprint(line, "\n")
+}
@@ -0,0 +1,67 @@
+package tgbotapiProvider
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger/CLILogger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+)
+
+// Client teams from map. Clients missing from map not exist, error client fails lookup.
+type mapClient struct {
+	ClientProvider.ClientProvider
+	teams map[string]string
+}
+
+func (mc mapClient) GetTeamByClient(client string) (string, error) {
+	switch client {
+	case "broken":
+		return "", errors.New("database is locked")
+	case "ambiguous":
+		return "", myErrors.ErrMoreThenOneTeamBounded
+	}
+	team, ok := mc.teams[client]
+	if !ok {
+		return "", myErrors.ErrClientNotExists
+	}
+	if team == "" {
+		return "", myErrors.ErrNoTeamBounded
+	}
+	return team, nil
+}
+
+func TestIsTicketVisible(t *testing.T) {
+	var client ClientProvider.ClientProvider = mapClient{teams: map[string]string{"ACME": "Team1", "Unbound": ""}}
+	bot := TelegramModule{Log: CLILogger.NewDefault(), Client: &client}
+	team := map[string]bool{"Team1": true}
+	otherTeam := map[string]bool{"Team2": true}
+	catchAll := map[string]bool{"Team2": true, catchAllTeam: true}
+
+	cases := []struct {
+		name     string
+		teamList map[string]bool
+		customer string
+		want     bool
+	}{
+		{"own team", team, "ACME", true},
+		{"other team", otherTeam, "ACME", false},
+		{"other team with catch-all", catchAll, "ACME", false},
+		{"no team bound", team, "Unbound", false},
+		{"no team bound with catch-all", catchAll, "Unbound", true},
+		{"unknown client", team, "New", false},
+		{"unknown client with catch-all", catchAll, "New", true},
+		{"several teams bound", team, "ambiguous", false},
+		{"several teams bound with catch-all", catchAll, "ambiguous", true},
+		{"lookup error", team, "broken", false},
+		{"lookup error with catch-all", catchAll, "broken", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if visible := bot.isTicketVisible(c.teamList, c.customer); visible != c.want {
+				t.Errorf("visible %v, want %v", visible, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package tgbotapiProvider
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"net/http"
+	"net/url"
+)
+
+// Header with secret token in every webhook request from Telegram.
+const secretTokenHeader string = "X-Telegram-Bot-Api-Secret-Token"
+
+// Return route and handler for updates pushed by Telegram.
+func (bot *TelegramModule) WebhookHandler() (string, http.HandlerFunc) {
+	return bot.Conf.WebhookPath, bot.handleWebhook
+}
+
+// Verify secret token and pass update to listener. Every request rejected if secret not configured.
+func (bot *TelegramModule) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	secret := r.Header.Get(secretTokenHeader)
+	if bot.Conf.WebhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(bot.Conf.WebhookSecret)) != 1 {
+		bot.Log.Warning(fmt.Sprintf("Reject webhook request from '%v' with invalid secret token", r.RemoteAddr))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		bot.Log.Error(fmt.Sprintf("Can't decode webhook update - '%v'", err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Telegram resend update if response not received in time so drop it on shutdown.
+	select {
+	case bot.webhookUpdates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// Register webhook URL with secret token in Telegram API.
+// Library "SetWebhook" has no secret token option so request made directly.
+func (bot *TelegramModule) setWebhook() error {
+	params := url.Values{}
+	params.Add("url", bot.Conf.WebhookURL)
+	params.Add("secret_token", bot.Conf.WebhookSecret)
+	_, err := bot.bot.MakeRequest("setWebhook", params)
+	return err
+}
+
+// Remove webhook so updates available by polling again.
+func (bot *TelegramModule) deleteWebhook() error {
+	_, err := bot.bot.MakeRequest("deleteWebhook", url.Values{})
+	return err
+}
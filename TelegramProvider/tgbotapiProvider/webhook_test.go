@@ -0,0 +1,63 @@
+package tgbotapiProvider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger/CLILogger"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const testUpdate string = `{"update_id": 1, "message": {"message_id": 1, "chat": {"id": 42, "type": "private"}, "text": "/subscribe"}}`
+
+func newWebhookBot(secret string) *TelegramModule {
+	return &TelegramModule{
+		Conf:           config.TelegramConf{WebhookSecret: secret},
+		Log:            CLILogger.NewDefault(),
+		webhookUpdates: make(chan tgbotapi.Update, 1),
+	}
+}
+
+func postUpdate(bot *TelegramModule, header http.Header) int {
+	request := httptest.NewRequest(http.MethodPost, "/telegram", strings.NewReader(testUpdate))
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	bot.handleWebhook(recorder, request)
+	return recorder.Code
+}
+
+func TestWebhookRejectsInvalidSecret(t *testing.T) {
+	for name, test := range map[string]struct {
+		configured string
+		header     http.Header
+	}{
+		"missing header":        {"secret", nil},
+		"wrong header":          {"secret", http.Header{secretTokenHeader: {"wrong"}}},
+		"secret not configured": {"", http.Header{secretTokenHeader: {""}}},
+	} {
+		bot := newWebhookBot(test.configured)
+		if code := postUpdate(bot, test.header); code != http.StatusUnauthorized {
+			t.Errorf("%v: answered %v, want %v", name, code, http.StatusUnauthorized)
+		}
+		if len(bot.webhookUpdates) != 0 {
+			t.Errorf("%v: forged update passed to listener", name)
+		}
+	}
+}
+
+func TestWebhookAcceptsValidSecret(t *testing.T) {
+	bot := newWebhookBot("secret")
+
+	if code := postUpdate(bot, http.Header{secretTokenHeader: {"secret"}}); code != http.StatusOK {
+		t.Fatalf("answered %v, want %v", code, http.StatusOK)
+	}
+	update := <-bot.webhookUpdates
+	if update.Message == nil || update.Message.Chat.ID != 42 {
+		t.Errorf("unexpected update %+v", update)
+	}
+}
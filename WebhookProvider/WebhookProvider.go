@@ -0,0 +1,30 @@
+package WebhookProvider
+
+import (
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"time"
+)
+
+// Notification kinds.
+const (
+	KindAlert    string = "alert"    // New ticket event.
+	KindReminder string = "reminder" // Repeated notification for not processed ticket.
+	KindClosure  string = "closure"  // Event finished because ticket locked, closed or merged.
+)
+
+type WebhookProvider interface {
+	Initialise(logger logger.Logger, db *DBProvider.DBProvider, conf []config.WebhookConf) error
+	Notify(notification Notification)
+}
+
+// Data passed to every endpoint and available in body templates.
+type Notification struct {
+	Kind   string                  // One of notification kinds.
+	Reason string                  // Finish reason for closure notifications.
+	Team   string                  // Team bounded with ticket client. Empty if not bounded.
+	Ticket OTRSProvider.TicketOTRS // Ticket details from OTRS.
+	Time   time.Time               // Notification generation time.
+}
@@ -0,0 +1,286 @@
+package httpWebhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/WebhookProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+const (
+	ModuleName             string = "Webhook Provider HTTP"
+	DefaultMethod          string = http.MethodPost
+	DefaultSignatureHeader string = "X-Signature"
+	DefaultRetryInterval   int    = 5  // In seconds
+	DefaultTimeout         int    = 10 // In seconds
+)
+
+// Unit of endpoint retry interval.
+var retryUnit = time.Second
+
+// Implement WebhookProvider interface.
+type HTTPWebhook struct {
+	Endpoints []*Endpoint
+	Log       logger.Logger
+	DB        *DBProvider.DBProvider
+}
+
+// Prepared endpoint configuration.
+type Endpoint struct {
+	Conf       config.WebhookConf
+	Template   *template.Template // Nil if default payload used.
+	HTTPClient *http.Client
+}
+
+// Default payload if endpoint has no template.
+type payload struct {
+	Kind         string `json:"kind"`
+	Reason       string `json:"reason,omitempty"`
+	Team         string `json:"team,omitempty"`
+	Instance     string `json:"instance,omitempty"`
+	Time         string `json:"time"`
+	TicketNumber string `json:"ticketNumber"`
+	Type         string `json:"type"`
+	CustomerID   string `json:"customerID"`
+	Priority     string `json:"priority"`
+	Created      string `json:"created"`
+	Title        string `json:"title"`
+	Lock         string `json:"lock"`
+	StateType    string `json:"stateType"`
+	URL          string `json:"url"`
+}
+
+// Parse endpoint templates and prepare HTTP clients.
+// Return error if any template is invalid.
+func (hw *HTTPWebhook) Initialise(logger logger.Logger, db *DBProvider.DBProvider, conf []config.WebhookConf) error {
+	hw.Log = logger.SetModuleName(ModuleName)
+	hw.DB = db
+	hw.Log.Debug("Initialisation started")
+
+	hw.Endpoints = make([]*Endpoint, 0, len(conf))
+	for _, endpointConf := range conf {
+		endpoint, err := newEndpoint(endpointConf)
+		if err != nil {
+			hw.Log.Error(fmt.Sprintf("Can't prepare endpoint '%v' - '%v'", endpointConf.Name, err))
+			return err
+		}
+		hw.Endpoints = append(hw.Endpoints, endpoint)
+		hw.Log.Debug(fmt.Sprintf("Endpoint '%v' prepared", endpointConf.Name))
+	}
+
+	hw.Log.Debug("Initialisation complete")
+	return nil
+}
+
+// Send notification to all matching endpoints in background.
+func (hw *HTTPWebhook) Notify(notification WebhookProvider.Notification) {
+	for _, endpoint := range hw.Endpoints {
+		if !endpoint.isMatch(notification) {
+			continue
+		}
+		go hw.deliver(endpoint, notification)
+	}
+}
+
+// Render body, store it for delivery tracking and send with retries.
+func (hw *HTTPWebhook) deliver(endpoint *Endpoint, notification WebhookProvider.Notification) {
+	name := endpoint.Conf.Name
+	body, err := endpoint.render(notification, hw.Log)
+	if err != nil {
+		hw.Log.Error(fmt.Sprintf("Can't render body for endpoint '%v' - '%v'. Notification not sent", name, err))
+		return
+	}
+
+	// Schedule message.
+	db := *hw.DB
+	messageID, err := db.MessageListNewWebhookMessage(name, string(body), notification.Ticket.Instance)
+	if err != nil {
+		hw.Log.Error(fmt.Sprintf("While scheduling message for endpoint '%v' - '%v'. Notification not sent or scheduled", name, err))
+		return
+	}
+
+	// Send with exponential backoff.
+	retryInterval := time.Duration(endpoint.Conf.RetryInterval) * retryUnit
+	for attempt := 0; ; attempt++ {
+		err = endpoint.send(body)
+		if err == nil {
+			break
+		}
+		if attempt >= endpoint.Conf.Retries {
+			hw.Log.Error(fmt.Sprintf("While send notification to endpoint '%v' - '%v'. Retries exhausted", name, err))
+			return
+		}
+		hw.Log.Warning(fmt.Sprintf("While send notification to endpoint '%v' - '%v'. Retry in '%v'", name, err, retryInterval))
+		time.Sleep(retryInterval)
+		retryInterval *= 2
+	}
+
+	// Finish message processing.
+	hw.Log.Debug(fmt.Sprintf("Notification to endpoint '%v' sucessfully sent", name))
+	err = db.MessageListMarkDelivered(messageID)
+	if err != nil {
+		hw.Log.Error(fmt.Sprintf("While mark message as delivered - '%v'", err))
+	}
+}
+
+// Fill defaults and parse body template.
+func newEndpoint(conf config.WebhookConf) (*Endpoint, error) {
+	if conf.Method == "" {
+		conf.Method = DefaultMethod
+	}
+	if conf.SignatureHeader == "" {
+		conf.SignatureHeader = DefaultSignatureHeader
+	}
+	if conf.RetryInterval <= 0 {
+		conf.RetryInterval = DefaultRetryInterval
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = DefaultTimeout
+	}
+
+	endpoint := &Endpoint{
+		Conf:       conf,
+		HTTPClient: &http.Client{Timeout: time.Duration(conf.Timeout) * time.Second},
+	}
+	if conf.Template != "" {
+		tmpl, err := template.New(conf.Name).Funcs(templateFunctions).Parse(conf.Template)
+		if err != nil {
+			return nil, err
+		}
+		endpoint.Template = tmpl
+	}
+
+	return endpoint, nil
+}
+
+// Functions available in body templates.
+var templateFunctions = template.FuncMap{
+	// Marshal value to JSON for safe use inside JSON templates.
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Check notification against endpoint filters.
+func (e *Endpoint) isMatch(notification WebhookProvider.Notification) bool {
+	return isAllowed(e.Conf.Kinds, notification.Kind) &&
+		isAllowed(e.Conf.Teams, notification.Team) &&
+		isAllowed(e.Conf.Priorities, notification.Ticket.Priority)
+}
+
+// Empty filter allow any value.
+func isAllowed(filter []string, value string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, allowed := range filter {
+		if allowed == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Render request body by endpoint template, common webhook template for kind or default payload.
+func (e *Endpoint) render(notification WebhookProvider.Notification, logger logger.Logger) ([]byte, error) {
+	if e.Template == nil {
+		data := Formatter.NewTemplateData(notification.Ticket, logger)
+		data.Team = notification.Team
+		data.Reason = notification.Reason
+		text, ok, err := Formatter.RenderChannel(templateKind(notification), Formatter.ChannelWebhook, data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return []byte(text), nil
+		}
+
+		return json.Marshal(payload{
+			Kind:         notification.Kind,
+			Reason:       notification.Reason,
+			Team:         notification.Team,
+			Instance:     notification.Ticket.Instance,
+			Time:         notification.Time.Format(time.RFC3339),
+			TicketNumber: notification.Ticket.TicketNumber,
+			Type:         notification.Ticket.Type,
+			CustomerID:   notification.Ticket.CustomerID,
+			Priority:     notification.Ticket.Priority,
+			Created:      notification.Ticket.Created,
+			Title:        notification.Ticket.Title,
+			Lock:         notification.Ticket.Lock,
+			StateType:    notification.Ticket.StateType,
+			URL:          notification.Ticket.URL,
+		})
+	}
+
+	var body bytes.Buffer
+	err := e.Template.Execute(&body, notification)
+	if err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// Map notification kind to message template kind.
+func templateKind(notification WebhookProvider.Notification) string {
+	switch {
+	case notification.Kind == WebhookProvider.KindAlert:
+		return Formatter.KindNew
+	case notification.Kind == WebhookProvider.KindReminder:
+		return Formatter.KindReminder
+	case notification.Reason == "lock":
+		return Formatter.KindTaken
+	case notification.Reason == "merged":
+		return Formatter.KindMerged
+	default:
+		return Formatter.KindClosed
+	}
+}
+
+// Send single request. Any non 2xx response status is an error.
+func (e *Endpoint) send(body []byte) error {
+	request, err := http.NewRequest(e.Conf.Method, e.Conf.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range e.Conf.Headers {
+		request.Header.Set(key, value)
+	}
+	if e.Conf.Secret != "" {
+		request.Header.Set(e.Conf.SignatureHeader, sign(body, e.Conf.Secret))
+	}
+
+	response, err := e.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%w '%v'", myErrors.ErrUnexpectedResponseStatus, response.Status)
+	}
+	return nil
+}
+
+// Return HMAC-SHA256 signature of body in "sha256=<hex>" form.
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return fmt.Sprint("sha256=", hex.EncodeToString(mac.Sum(nil)))
+}
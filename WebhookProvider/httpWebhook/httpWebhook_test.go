@@ -0,0 +1,279 @@
+package httpWebhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/WebhookProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger/CLILogger"
+)
+
+// Keep delivery tracking in memory.
+type fakeDB struct {
+	DBProvider.DBProvider
+	mx        sync.Mutex
+	scheduled []string
+	delivered []int64
+}
+
+func (f *fakeDB) MessageListNewWebhookMessage(endpoint, body, instance string) (int64, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.scheduled = append(f.scheduled, body)
+	return int64(len(f.scheduled)), nil
+}
+
+func (f *fakeDB) MessageListMarkDelivered(ID int64) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.delivered = append(f.delivered, ID)
+	return nil
+}
+
+// Captured request of test endpoint.
+type request struct {
+	Method string
+	Header http.Header
+	Body   []byte
+}
+
+// Start endpoint that answers with statuses in order, last status repeated.
+func newServer(t *testing.T, statuses ...int) (*httptest.Server, chan request) {
+	t.Helper()
+	requests := make(chan request, 16)
+	var mx sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mx.Lock()
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		mx.Unlock()
+		requests <- request{Method: r.Method, Header: r.Header, Body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func newWebhook(t *testing.T, conf ...config.WebhookConf) (*HTTPWebhook, *fakeDB) {
+	t.Helper()
+	var db DBProvider.DBProvider = &fakeDB{}
+	hw := &HTTPWebhook{}
+	err := hw.Initialise(CLILogger.NewDefault(), &db, conf)
+	if err != nil {
+		t.Fatalf("initialise - %v", err)
+	}
+	return hw, db.(*fakeDB)
+}
+
+var testNotification = WebhookProvider.Notification{
+	Kind: WebhookProvider.KindAlert,
+	Team: "Support",
+	Ticket: OTRSProvider.TicketOTRS{
+		TicketNumber: "2024010110000011",
+		Priority:     "3 normal",
+		CustomerID:   "ACME",
+		Title:        `Printer "on fire"`,
+	},
+	Time: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+}
+
+func TestSignature(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK)
+	hw, _ := newWebhook(t, config.WebhookConf{Name: "signed", URL: server.URL, Secret: "s3cret", SignatureHeader: "X-Hub-Signature"})
+
+	hw.deliver(hw.Endpoints[0], testNotification)
+	got := <-requests
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(got.Body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if signature := got.Header.Get("X-Hub-Signature"); signature != want {
+		t.Errorf("signature '%v', want '%v'", signature, want)
+	}
+	if got.Method != http.MethodPost {
+		t.Errorf("method '%v', want POST", got.Method)
+	}
+}
+
+func TestNoSignatureWithoutSecret(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK)
+	hw, _ := newWebhook(t, config.WebhookConf{Name: "plain", URL: server.URL, Headers: map[string]string{"Authorization": "Bearer x"}})
+
+	hw.deliver(hw.Endpoints[0], testNotification)
+	got := <-requests
+
+	if signature := got.Header.Get(DefaultSignatureHeader); signature != "" {
+		t.Errorf("unexpected signature '%v'", signature)
+	}
+	if header := got.Header.Get("Authorization"); header != "Bearer x" {
+		t.Errorf("custom header '%v' not sent", header)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	retryUnit = 10 * time.Millisecond
+	defer func() { retryUnit = time.Second }()
+
+	cases := []struct {
+		name      string
+		statuses  []int
+		retries   int
+		requests  int
+		delivered bool
+	}{
+		{name: "first attempt", statuses: []int{http.StatusNoContent}, retries: 2, requests: 1, delivered: true},
+		{name: "after retries", statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}, retries: 2, requests: 3, delivered: true},
+		{name: "retries exhausted", statuses: []int{http.StatusServiceUnavailable}, retries: 1, requests: 2, delivered: false},
+		{name: "no retries", statuses: []int{http.StatusNotFound}, retries: 0, requests: 1, delivered: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server, requests := newServer(t, c.statuses...)
+			hw, db := newWebhook(t, config.WebhookConf{Name: "retry", URL: server.URL, Retries: c.retries, RetryInterval: 2})
+
+			started := time.Now()
+			hw.deliver(hw.Endpoints[0], testNotification)
+			elapsed := time.Since(started)
+
+			if len(requests) != c.requests {
+				t.Errorf("requests %v, want %v", len(requests), c.requests)
+			}
+			if delivered := len(db.delivered) == 1; delivered != c.delivered {
+				t.Errorf("delivered %v, want %v", delivered, c.delivered)
+			}
+			if len(db.scheduled) != 1 {
+				t.Errorf("scheduled %v messages, want 1", len(db.scheduled))
+			}
+			// Intervals 20ms, 40ms, ... between attempts.
+			var backoff time.Duration
+			for i, interval := 0, 20*time.Millisecond; i < c.requests-1; i, interval = i+1, interval*2 {
+				backoff += interval
+			}
+			if elapsed < backoff {
+				t.Errorf("delivery took %v, want at least %v of backoff", elapsed, backoff)
+			}
+		})
+	}
+}
+
+func TestDefaultPayload(t *testing.T) {
+	hw, _ := newWebhook(t, config.WebhookConf{Name: "default", URL: "http://localhost"})
+
+	body, err := hw.Endpoints[0].render(testNotification, hw.Log)
+	if err != nil {
+		t.Fatalf("render - %v", err)
+	}
+	var got payload
+	err = json.Unmarshal(body, &got)
+	if err != nil {
+		t.Fatalf("payload is not JSON - %v", err)
+	}
+	if got.Kind != "alert" || got.Team != "Support" || got.TicketNumber != "2024010110000011" || got.Title != `Printer "on fire"` {
+		t.Errorf("unexpected payload %+v", got)
+	}
+	if got.Time != "2024-01-01T10:00:00Z" {
+		t.Errorf("time '%v'", got.Time)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	hw, _ := newWebhook(t, config.WebhookConf{
+		Name:     "template",
+		URL:      "http://localhost",
+		Template: `{"text":{{json .Ticket.Title}},"kind":"{{.Kind}}","team":"{{.Team}}"}`,
+	})
+
+	body, err := hw.Endpoints[0].render(testNotification, hw.Log)
+	if err != nil {
+		t.Fatalf("render - %v", err)
+	}
+	want := `{"text":"Printer \"on fire\"","kind":"alert","team":"Support"}`
+	if string(body) != want {
+		t.Errorf("body '%s', want '%v'", body, want)
+	}
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	var db DBProvider.DBProvider = &fakeDB{}
+	hw := &HTTPWebhook{}
+	err := hw.Initialise(CLILogger.NewDefault(), &db, []config.WebhookConf{{Name: "broken", Template: "{{.Kind"}})
+	if err == nil {
+		t.Fatal("invalid template accepted")
+	}
+}
+
+func TestTemplateExecutionError(t *testing.T) {
+	server, requests := newServer(t, http.StatusOK)
+	hw, db := newWebhook(t, config.WebhookConf{Name: "missing", URL: server.URL, Template: `{{.Missing}}`})
+
+	hw.deliver(hw.Endpoints[0], testNotification)
+
+	if len(requests) != 0 || len(db.scheduled) != 0 {
+		t.Errorf("notification with broken body sent")
+	}
+}
+
+func TestFilters(t *testing.T) {
+	cases := []struct {
+		name  string
+		conf  config.WebhookConf
+		match bool
+	}{
+		{name: "no filters", conf: config.WebhookConf{}, match: true},
+		{name: "kind allowed", conf: config.WebhookConf{Kinds: []string{"reminder", "alert"}}, match: true},
+		{name: "kind denied", conf: config.WebhookConf{Kinds: []string{"closure"}}, match: false},
+		{name: "team allowed", conf: config.WebhookConf{Teams: []string{"Support"}}, match: true},
+		{name: "team denied", conf: config.WebhookConf{Teams: []string{"Network"}}, match: false},
+		{name: "priority allowed", conf: config.WebhookConf{Priorities: []string{"3 normal"}}, match: true},
+		{name: "priority denied", conf: config.WebhookConf{Priorities: []string{"5 very high"}}, match: false},
+		{name: "all filters", conf: config.WebhookConf{Kinds: []string{"alert"}, Teams: []string{"Support"}, Priorities: []string{"3 normal"}}, match: true},
+		{name: "one filter denied", conf: config.WebhookConf{Kinds: []string{"alert"}, Teams: []string{"Network"}, Priorities: []string{"3 normal"}}, match: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			endpoint, err := newEndpoint(c.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match := endpoint.isMatch(testNotification); match != c.match {
+				t.Errorf("match %v, want %v", match, c.match)
+			}
+		})
+	}
+}
+
+func TestNotifyOnlyMatchingEndpoints(t *testing.T) {
+	matching, matchingRequests := newServer(t, http.StatusOK)
+	filtered, filteredRequests := newServer(t, http.StatusOK)
+	hw, _ := newWebhook(t,
+		config.WebhookConf{Name: "matching", URL: matching.URL, Teams: []string{"Support"}},
+		config.WebhookConf{Name: "filtered", URL: filtered.URL, Teams: []string{"Network"}},
+	)
+
+	hw.Notify(testNotification)
+
+	select {
+	case <-matchingRequests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("matching endpoint not notified")
+	}
+	select {
+	case <-filteredRequests:
+		t.Fatal("filtered endpoint notified")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
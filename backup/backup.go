@@ -0,0 +1,116 @@
+// Package backup makes scheduled online copies of bot database and keeps limited number of them.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	ModuleName      string        = "Backup Scheduler"
+	DefaultInterval time.Duration = 24 * time.Hour
+	DefaultKeep     int           = 7
+	filePrefix      string        = "sqlite3-"
+	fileSuffix      string        = ".bd"
+	fileTimeLayout  string        = "20060102-150405"
+)
+
+// Periodically back up database into directory and remove oldest backups above limit.
+type Scheduler struct {
+	DB        *DBProvider.DBProvider
+	Log       logger.Logger
+	Directory string        // Absolute path to directory for backup files.
+	Interval  time.Duration // Interval between backups.
+	Keep      int           // Number of newest backups kept. All kept if zero.
+}
+
+// Back up periodically until context done. First backup after one interval.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.Log.Info(fmt.Sprintf("Back up database into '%v' every '%v'", s.Directory, s.Interval))
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_, err := s.Backup(time.Now())
+			if err != nil {
+				s.Log.Error(fmt.Sprintf("Scheduled backup failed - '%v'", err))
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Make backup named by time and rotate old backups. Return backup file path.
+func (s *Scheduler) Backup(now time.Time) (string, error) {
+	err := os.MkdirAll(s.Directory, 0750)
+	if err != nil {
+		return "", err
+	}
+	fileName := FileName(s.Directory, now)
+	err = (*s.DB).Backup(fileName)
+	if err != nil {
+		return "", err
+	}
+	err = s.rotate()
+	if err != nil {
+		s.Log.Error(fmt.Sprintf("Can't remove old backups - '%v'", err))
+	}
+	return fileName, nil
+}
+
+// Remove oldest backups above limit. Files not created by scheduler kept.
+func (s *Scheduler) rotate() error {
+	if s.Keep <= 0 {
+		return nil
+	}
+	fileList, err := List(s.Directory)
+	if err != nil {
+		return err
+	}
+	for len(fileList) > s.Keep {
+		err = os.Remove(fileList[0])
+		if err != nil {
+			return err
+		}
+		s.Log.Info(fmt.Sprintf("Old backup '%v' removed", fileList[0]))
+		fileList = fileList[1:]
+	}
+	return nil
+}
+
+// Return path of backup file made at time in directory.
+func FileName(directory string, now time.Time) string {
+	return filepath.Join(directory, filePrefix+now.Format(fileTimeLayout)+fileSuffix)
+}
+
+// Return backup files in directory from oldest to newest.
+func List(directory string) ([]string, error) {
+	entryList, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+	fileList := make([]string, 0, len(entryList))
+	for _, entry := range entryList {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		_, err = time.Parse(fileTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+		fileList = append(fileList, filepath.Join(directory, name))
+	}
+	// Time layout sorted by name in chronological order.
+	sort.Strings(fileList)
+	return fileList, nil
+}
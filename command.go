@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider/SQLite3"
+	"github.com/Sarraksh/otrs-echo-bot/backup"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const commandUsage string = `Usage: otrs-echo-bot [command]
+Without command bot started.
+Commands:
+  backup [file]   Back up database while bot running. Into backup directory with rotation if file not set.
+  restore <file>  Replace database with backup. Current database backed up first. Fails if bot running.
+  export <file>   Write users, subscriptions and client bindings as JSON. "-" for standard output.
+  import <file>   Add or update users, subscriptions and client bindings from JSON export. "-" for standard input.`
+
+// Run maintenance command instead of bot.
+func runCommand(arguments []string, conf config.Config, programDirectory string, logModule logger.Logger) error {
+	logModule.Info(fmt.Sprintf("Run command '%v'", arguments))
+
+	var DBModule DBProvider.DBProvider = new(SQLite3.DB)
+	err := DBModule.Initialise(logModule, programDirectory)
+	if err != nil {
+		return err
+	}
+	scheduler := newBackupScheduler(conf.Backup, programDirectory, &DBModule, logModule)
+	if scheduler == nil {
+		scheduler = &backup.Scheduler{
+			DB:        &DBModule,
+			Log:       logModule.SetModuleName(backup.ModuleName),
+			Directory: filepath.Join(programDirectory, "backup"),
+		}
+	}
+
+	switch {
+	case arguments[0] == "backup" && len(arguments) <= 2:
+		fileName := ""
+		if len(arguments) == 2 {
+			fileName = arguments[1]
+			err = DBModule.Backup(fileName)
+		} else {
+			fileName, err = scheduler.Backup(time.Now())
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Database backed up into '%v'\n", fileName)
+
+	case arguments[0] == "restore" && len(arguments) == 2:
+		current, err := scheduler.Backup(time.Now())
+		if err != nil {
+			return fmt.Errorf("backup of current database failed - %w", err)
+		}
+		fmt.Printf("Current database backed up into '%v'\n", current)
+		err = DBModule.Restore(arguments[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Database restored from '%v'\n", arguments[1])
+
+	case arguments[0] == "export" && len(arguments) == 2:
+		dump, err := DBModule.Export()
+		if err != nil {
+			return err
+		}
+		err = writeDump(arguments[1], dump)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Exported '%v' users, '%v' subscriptions and '%v' clients\n",
+			len(dump.Users), len(dump.Subscriptions), len(dump.Clients))
+
+	case arguments[0] == "import" && len(arguments) == 2:
+		dump, err := readDump(arguments[1])
+		if err != nil {
+			return err
+		}
+		err = DBModule.Import(dump)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported '%v' users, '%v' subscriptions and '%v' clients\n",
+			len(dump.Users), len(dump.Subscriptions), len(dump.Clients))
+
+	default:
+		fmt.Fprintln(os.Stderr, commandUsage)
+		return fmt.Errorf("%w '%v'", myErrors.ErrInvalidArgument, arguments)
+	}
+	return nil
+}
+
+// Write dump as indented JSON into file or standard output for "-".
+func writeDump(fileName string, dump DBProvider.Dump) error {
+	var writer io.Writer = os.Stdout
+	if fileName != "-" {
+		file, err := os.OpenFile(fileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dump)
+}
+
+// Read dump from file or standard input for "-".
+func readDump(fileName string) (DBProvider.Dump, error) {
+	var reader io.Reader = os.Stdin
+	if fileName != "-" {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return DBProvider.Dump{}, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	var dump DBProvider.Dump
+	err := json.NewDecoder(reader).Decode(&dump)
+	return dump, err
+}
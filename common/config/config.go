@@ -10,40 +10,186 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // Combine all available options.
 type Config struct {
-	OTRS     OTRSConf     `yaml:"OTRS"`
-	Telegram TelegramConf `yaml:"Telegram"`
+	OTRS          OTRSConf   `yaml:"OTRS"`          // Default OTRS instance.
+	OTRSInstances []OTRSConf `yaml:"OTRSInstances"` // Additional OTRS instances. Credentials not encrypted.
+
+	Telegram  TelegramConf  `yaml:"Telegram"`
+	REST      RESTConf      `yaml:"REST"`
+	Webhooks  []WebhookConf `yaml:"Webhooks"`
+	Templates TemplatesConf `yaml:"Templates"`
+	Calendar  CalendarConf  `yaml:"Calendar"`
+
+	Notifications      NotificationsConf      `yaml:"Notifications"`
+	Digest             DigestConf             `yaml:"Digest"`
+	EscalationPolicies []EscalationPolicyConf `yaml:"EscalationPolicies"`
+	Reports            ReportsConf            `yaml:"Reports"`
+	Retention          RetentionConf          `yaml:"Retention"`
+	Backup             BackupConf             `yaml:"Backup"`
+	Cluster            ClusterConf            `yaml:"Cluster"`
 }
 
+// Route for ticket events from default OTRS instance if not configured.
+const DefaultOTRSWebhookPath string = "/newticket"
+
 // Options for OTRS module.
 type OTRSConf struct {
-	Host            string  `yaml:"Host"`            // Host on which OTRS is located.
-	TicketURLPrefix string  `yaml:"TicketURLPrefix"` // Prefix for ticket URL for browser (include port if not default).
-	API             OTRSAPI `yaml:"API"`
+	Name            string   `yaml:"Name"`            // Instance name shown in messages. Mandatory if additional instances configured.
+	WebhookPath     string   `yaml:"WebhookPath"`     // Route for ticket events from instance. "/newticket" for default instance if not set. Other instances without route only polled.
+	Host            string   `yaml:"Host"`            // Host on which OTRS is located.
+	TicketURLPrefix string   `yaml:"TicketURLPrefix"` // Prefix for ticket URL for browser (include port if not default).
+	Timezone        string   `yaml:"Timezone"`        // IANA name of OTRS server timezone, e.g. "Europe/Moscow". "Europe/Moscow" if not set.
+	CacheTTL        int      `yaml:"CacheTTL"`        // Ticket details cache lifetime in seconds. 60 if not set. Negative disables cache.
+	API             OTRSAPI  `yaml:"API"`
+	Poll            OTRSPoll `yaml:"Poll"`
+}
+
+// Polling for new tickets by TicketSearch. Works alongside ticket notifications from OTRS invoker.
+type OTRSPoll struct {
+	Interval int `yaml:"Interval"` // Seconds between polls. Polling disabled if not set.
+	Overlap  int `yaml:"Overlap"`  // Seconds searched before watermark to cover clock skew between bot and OTRS. 60 if not set.
+	Lookback int `yaml:"Lookback"` // Seconds searched back on first poll. Only tickets created after start found if not set.
 }
 
 // OTRS API configuration.
+// Migration from "query" auth mode: add POST to web service routes of TicketGet and TicketSearch
+// operations in OTRS, then set AuthMode "body". Unset AuthMode is deprecated and means "query".
 type OTRSAPI struct {
-	Login                   string `yaml:"Login"`                   // Login for API.
-	Password                string `yaml:"Password"`                // Password for API.
+	AuthMode                string `yaml:"AuthMode"`                // Credentials transfer - "query", "body", "basic", "session" or "token". "query" if not set (deprecated).
+	Login                   string `yaml:"Login"`                   // Login for API. Not used in "token" mode.
+	Password                string `yaml:"Password"`                // Password for API. Not used in "token" mode.
+	Token                   string `yaml:"Token"`                   // Access token for "token" mode (Znuny/OTRS 8 REST API).
+	SessionCreatePath       string `yaml:"SessionCreatePath"`       // Create session (SessionCreate operation). Mandatory for "session" mode.
 	Protocol                string `yaml:"Protocol"`                // Protocol over which the API is available. http or https.
 	Port                    string `yaml:"Port"`                    // Port over which the API is available.
 	InsecureConnection      bool   `yaml:"InsecureConnection"`      // If true allow insecure connections to API.
 	GetTicketDetailListPath string `yaml:"GetTicketDetailListPath"` // Get ticket details.
+	TicketSearchPath        string `yaml:"TicketSearchPath"`        // Search tickets (TicketSearch operation). Search disabled if not set.
+	Timeout                 int    `yaml:"Timeout"`                 // Request timeout in seconds. 10 if not set.
+	MaxRetries              int    `yaml:"MaxRetries"`              // Retries for timeouts and server errors. 2 if not set. Negative disables retries.
+	BreakerThreshold        int    `yaml:"BreakerThreshold"`        // Consecutive failures after which requests paused. 5 if not set.
+	BreakerCooldown         int    `yaml:"BreakerCooldown"`         // Pause in seconds before trial request to failed OTRS. 30 if not set.
 }
 
 // Options for Telegram module.
 type TelegramConf struct {
-	Token string `yaml:"Token"` // Token from @BotFather.
+	Token          string `yaml:"Token"`          // Token from @BotFather.
+	ParseMode      string `yaml:"ParseMode"`      // Alert markup - "HTML", "MarkdownV2" or "Plain". "HTML" if not set.
+	Mode           string `yaml:"Mode"`           // Update receiving mode - "polling" or "webhook". "polling" if not set.
+	WebhookURL     string `yaml:"WebhookURL"`     // Public HTTPS URL for Telegram updates. Proxied to WebhookPath on REST listener.
+	WebhookPath    string `yaml:"WebhookPath"`    // Route for Telegram updates on REST listener. "/telegram" if not set.
+	WebhookSecret  string `yaml:"WebhookSecret"`  // Secret token Telegram sends in every webhook request. Mandatory for webhook mode. Characters A-Z, a-z, 0-9, "_" and "-".
+	RateLimit      int    `yaml:"RateLimit"`      // Messages per second for all chats. 25 if not set.
+	ChatRateLimit  int    `yaml:"ChatRateLimit"`  // Messages per second for one private chat. 1 if not set.
+	GroupRateLimit int    `yaml:"GroupRateLimit"` // Messages per minute for one group or channel. 20 if not set.
+}
+
+// Options for REST listener.
+type RESTConf struct {
+	AdminToken string `yaml:"AdminToken"` // Bearer token for admin API: event timeline, reports, metrics and retention stats. Admin API disabled if not set.
+}
+
+// Options for notification delivery.
+type NotificationsConf struct {
+	CriticalPriorities []string `yaml:"CriticalPriorities"` // Ticket priorities delivered even in quiet hours and do-not-disturb mode.
+}
+
+// Options for periodic team digest of open tickets.
+type DigestConf struct {
+	Times    []string `yaml:"Times"`    // Daily digest times "15:04" in calendar timezone, e.g. "09:00".
+	Interval int      `yaml:"Interval"` // Digest interval in minutes during team working hours. Disabled if zero.
+}
+
+// Options for weekly response time summary. Summary covers seven days before sending.
+type ReportsConf struct {
+	Weekday   string             `yaml:"Weekday"`   // Day of weekly summary, e.g. "Mon". Summary disabled if not set.
+	Time      string             `yaml:"Time"`      // Time of weekly summary "15:04" in calendar timezone. "09:00" if not set.
+	TeamLeads map[string][]int64 `yaml:"TeamLeads"` // Telegram chats of team leads by team name. Each chat gets rows of own team.
+}
+
+// Options for removal of old data. Data kept forever if retention period not set.
+type RetentionConf struct {
+	Events           int    `yaml:"Events"`           // Days to keep ended events with history. Repeated notifications for removed events not alerted.
+	Messages         int    `yaml:"Messages"`         // Days to keep delivered messages.
+	Deliveries       int    `yaml:"Deliveries"`       // Days to keep idempotency keys of ticket notifications.
+	ArchiveDirectory string `yaml:"ArchiveDirectory"` // Removed rows appended to JSON lines files. Relative to program directory. Not archived if not set.
+	Interval         int    `yaml:"Interval"`         // Minutes between cleanups. 60 if not set.
+	BatchSize        int    `yaml:"BatchSize"`        // Rows removed in one transaction. 500 if not set.
+	OptimiseInterval int    `yaml:"OptimiseInterval"` // Hours between VACUUM and ANALYZE. 24 if not set. Negative disables.
+}
+
+// Options for scheduled online backup of database.
+type BackupConf struct {
+	Directory string `yaml:"Directory"` // Directory for backup files. Relative to program directory. Scheduled backup disabled if not set.
+	Interval  int    `yaml:"Interval"`  // Hours between backups. 24 if not set.
+	Keep      int    `yaml:"Keep"`      // Number of newest backups kept. 7 if not set. Negative keeps all.
+}
+
+// Options for several bot instances sharing one database. Events and deferred messages leased, digests and summaries sent by one instance.
+type ClusterConf struct {
+	Owner   string `yaml:"Owner"`   // Unique instance name for event and message leases. Host name and process ID if not set.
+	Lease   int    `yaml:"Lease"`   // Seconds event stays claimed by instance. 120 if not set. Must exceed OTRS request time.
+	Standby int    `yaml:"Standby"` // Passive instance processes only events overdue by this many seconds. Active instance if not set.
+}
+
+// Options for outbound webhook endpoint.
+type WebhookConf struct {
+	Name            string            `yaml:"Name"`            // Endpoint name for logs and delivery tracking.
+	URL             string            `yaml:"URL"`             // Full endpoint URL.
+	Method          string            `yaml:"Method"`          // HTTP method. POST if not set.
+	Headers         map[string]string `yaml:"Headers"`         // Additional request headers.
+	Template        string            `yaml:"Template"`        // Go text/template for request body. Default JSON payload if not set.
+	Secret          string            `yaml:"Secret"`          // If set, body signed by HMAC-SHA256 with this secret.
+	SignatureHeader string            `yaml:"SignatureHeader"` // Header for body signature. "X-Signature" if not set.
+	Retries         int               `yaml:"Retries"`         // Number of retries after failed delivery.
+	RetryInterval   int               `yaml:"RetryInterval"`   // First retry interval in seconds. Doubled after each retry.
+	Timeout         int               `yaml:"Timeout"`         // Request timeout in seconds.
+	Kinds           []string          `yaml:"Kinds"`           // Send only these notification kinds (alert, reminder, closure). All if empty.
+	Teams           []string          `yaml:"Teams"`           // Send only notifications for these teams. All if empty.
+	Priorities      []string          `yaml:"Priorities"`      // Send only notifications for these ticket priorities. All if empty.
+}
+
+// Options for message templates.
+type TemplatesConf struct {
+	Directory       string `yaml:"Directory"`       // Directory with "<kind>.<channel>.tmpl" files. "templates" in program directory if not set.
+	EscalationLevel int    `yaml:"EscalationLevel"` // Use escalation template for reminders from this level. Disabled if not set.
+	NotifyClosure   bool   `yaml:"NotifyClosure"`   // Send taken, closed and merged messages to Telegram subscribers.
+}
+
+// Options for business hours and holidays.
+type CalendarConf struct {
+	Timezone string                      `yaml:"Timezone"` // IANA name of timezone for working hours. OTRS timezone if not set.
+	Default  TeamCalendarConf            `yaml:"Default"`  // Calendar for teams without own calendar and tickets without team.
+	Teams    map[string]TeamCalendarConf `yaml:"Teams"`    // Calendar by team name.
+}
+
+// Working hours and holidays for one team.
+type TeamCalendarConf struct {
+	WorkingHours string   `yaml:"WorkingHours"` // Working hours, e.g. "09:00-18:00". Whole day if not set.
+	WorkingDays  []string `yaml:"WorkingDays"`  // Working days, e.g. [Mon, Tue, Wed, Thu, Fri]. Every day if empty.
+	Holidays     []string `yaml:"Holidays"`     // Non-working dates in "2006-01-02" format.
+	HolidayFiles []string `yaml:"HolidayFiles"` // iCalendar files with holidays. Relative to program directory.
+	OnDutyChats  []int64  `yaml:"OnDutyChats"`  // Telegram chats notified outside working hours by "onDuty" policy.
+}
+
+// Escalation policy. First policy matching ticket team and priority used.
+type EscalationPolicyConf struct {
+	Name             string   `yaml:"Name"`             // Policy name for logs.
+	Teams            []string `yaml:"Teams"`            // Apply only to these teams. All if empty.
+	Priorities       []string `yaml:"Priorities"`       // Apply only to these ticket priorities. All if empty.
+	OutsideHours     string   `yaml:"OutsideHours"`     // "notify", "postpone" till working hours or "onDuty". "notify" if not set.
+	ReminderInterval int      `yaml:"ReminderInterval"` // Minutes between reminders. 5 if not set.
 }
 
 // Used for encryption storage
 type SensitiveData struct {
 	OTRSLogin     string
 	OTRSPassword  string
+	OTRSToken     string
 	TelegramToken string
 }
 
@@ -109,6 +255,7 @@ func mergeWitEncryptedData(conf Config, encryptedFile, programDirectory string)
 		sensitiveData = SensitiveData{
 			OTRSLogin:     "",
 			OTRSPassword:  "",
+			OTRSToken:     "",
 			TelegramToken: "",
 		}
 	case err != nil:
@@ -126,6 +273,11 @@ func mergeWitEncryptedData(conf Config, encryptedFile, programDirectory string)
 	} else {
 		sensitiveData.OTRSPassword = conf.OTRS.API.Password
 	}
+	if conf.OTRS.API.Token == "" {
+		conf.OTRS.API.Token = sensitiveData.OTRSToken
+	} else {
+		sensitiveData.OTRSToken = conf.OTRS.API.Token
+	}
 	if conf.Telegram.Token == "" {
 		conf.Telegram.Token = sensitiveData.TelegramToken
 	} else {
@@ -133,7 +285,7 @@ func mergeWitEncryptedData(conf Config, encryptedFile, programDirectory string)
 	}
 
 	// Check all sensitive data existence.
-	err = checkSensitiveDataProvided(sensitiveData)
+	err = checkSensitiveDataProvided(sensitiveData, conf.OTRS.API.AuthMode)
 	if err != nil {
 		return Config{}, err
 	}
@@ -193,11 +345,14 @@ func writeEncryptedDataIntoFile(encryptionFileFullPath string, sensitiveData Sen
 	return nil
 }
 
-func checkSensitiveDataProvided(sensData SensitiveData) error {
+// Token mode don't need login and password, other modes don't need token.
+func checkSensitiveDataProvided(sensData SensitiveData, authMode string) error {
 	switch {
-	case sensData.OTRSLogin == "":
+	case authMode == "token" && sensData.OTRSToken == "":
+		return myErrors.ErrOTRSTokenNotProvided
+	case authMode != "token" && sensData.OTRSLogin == "":
 		return myErrors.ErrOTRSLoginNotProvided
-	case sensData.OTRSPassword == "":
+	case authMode != "token" && sensData.OTRSPassword == "":
 		return myErrors.ErrOTRSPasswordNotProvided
 	case sensData.TelegramToken == "":
 		return myErrors.ErrTelegramTokenNotProvided
@@ -208,34 +363,134 @@ func checkSensitiveDataProvided(sensData SensitiveData) error {
 // Check existence for all mandatory options.
 func isMandatoryFieldsPresent(config Config, logModule logger.Logger) bool {
 	var allFieldsPresent bool = true
-	if config.OTRS.Host == "" {
+	if !isOTRSFieldsPresent(config.OTRS, "OTRS", logModule) {
 		allFieldsPresent = false
-		logModule.Error("Option 'OTRS.Host' is mandatory but not present")
 	}
-	if config.OTRS.TicketURLPrefix == "" {
+	if len(config.OTRSInstances) > 0 && config.OTRS.Name == "" {
 		allFieldsPresent = false
-		logModule.Error("Option 'OTRS.TicketURLPrefix' is mandatory but not present")
+		logModule.Error("Option 'OTRS.Name' is mandatory if additional OTRS instances configured but not present")
+	}
+	names := map[string]bool{config.OTRS.Name: true}
+	defaultPath := config.OTRS.WebhookPath
+	if defaultPath == "" {
+		defaultPath = DefaultOTRSWebhookPath
+	}
+	paths := map[string]bool{defaultPath: true}
+	for i, instance := range config.OTRSInstances {
+		prefix := fmt.Sprintf("OTRSInstances[%v]", i)
+		if !isOTRSFieldsPresent(instance, prefix, logModule) {
+			allFieldsPresent = false
+		}
+		switch {
+		case instance.Name == "":
+			allFieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option '%v.Name' is mandatory but not present", prefix))
+		case names[instance.Name]:
+			allFieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option '%v.Name' has duplicate value '%v'", prefix, instance.Name))
+		}
+		names[instance.Name] = true
+		if instance.WebhookPath != "" && paths[instance.WebhookPath] {
+			allFieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option '%v.WebhookPath' has duplicate value '%v'", prefix, instance.WebhookPath))
+		}
+		paths[instance.WebhookPath] = true
 	}
-	if config.OTRS.API.Login == "" {
+	if config.Telegram.Token == "" {
 		allFieldsPresent = false
-		logModule.Error("Option 'OTRS.API.Login' is mandatory but not present")
+		logModule.Error("Option 'Telegram.Token' is mandatory but not present")
 	}
-	if config.OTRS.API.Password == "" {
+	if config.Telegram.Mode == "webhook" && config.Telegram.WebhookURL == "" {
 		allFieldsPresent = false
-		logModule.Error("Option 'OTRS.API.Password' is mandatory but not present")
+		logModule.Error("Option 'Telegram.WebhookURL' is mandatory for webhook mode but not present")
 	}
-	if config.OTRS.API.Protocol == "" {
+	if config.Telegram.Mode == "webhook" && config.Telegram.WebhookSecret == "" {
 		allFieldsPresent = false
-		logModule.Error("Option 'OTRS.API.Protocol' is mandatory but not present")
+		logModule.Error("Option 'Telegram.WebhookSecret' is mandatory for webhook mode but not present")
 	}
-	if config.OTRS.API.GetTicketDetailListPath == "" {
+	switch config.Telegram.ParseMode {
+	case "", "HTML", "MarkdownV2", "Plain":
+	default:
 		allFieldsPresent = false
-		logModule.Error("Option 'OTRS.API.GetTicketDetailListPath' is mandatory but not present")
+		logModule.Error(fmt.Sprintf("Option 'Telegram.ParseMode' has unsupported value '%v'", config.Telegram.ParseMode))
 	}
-	if config.Telegram.Token == "" {
+	if _, err := time.LoadLocation(config.Calendar.Timezone); config.Calendar.Timezone != "" && err != nil {
 		allFieldsPresent = false
-		logModule.Error("Option 'Telegram.Token' is mandatory but not present")
+		logModule.Error(fmt.Sprintf("Option 'Calendar.Timezone' has unknown timezone '%v' - '%v'", config.Calendar.Timezone, err))
+	}
+	for i, policy := range config.EscalationPolicies {
+		switch policy.OutsideHours {
+		case "", "notify", "postpone", "onDuty":
+		default:
+			allFieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option 'EscalationPolicies[%v].OutsideHours' has unsupported value '%v'", i, policy.OutsideHours))
+		}
+	}
+	for i, webhook := range config.Webhooks {
+		if webhook.Name == "" {
+			allFieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option 'Webhooks[%v].Name' is mandatory but not present", i))
+		}
+		if webhook.URL == "" {
+			allFieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option 'Webhooks[%v].URL' is mandatory but not present", i))
+		}
 	}
 
 	return allFieldsPresent
 }
+
+// Check mandatory options of one OTRS instance. Prefix is option path for log, e.g. "OTRS".
+func isOTRSFieldsPresent(conf OTRSConf, prefix string, logModule logger.Logger) bool {
+	var fieldsPresent bool = true
+	if conf.Host == "" {
+		fieldsPresent = false
+		logModule.Error(fmt.Sprintf("Option '%v.Host' is mandatory but not present", prefix))
+	}
+	if conf.TicketURLPrefix == "" {
+		fieldsPresent = false
+		logModule.Error(fmt.Sprintf("Option '%v.TicketURLPrefix' is mandatory but not present", prefix))
+	}
+	if _, err := time.LoadLocation(conf.Timezone); conf.Timezone != "" && err != nil {
+		fieldsPresent = false
+		logModule.Error(fmt.Sprintf("Option '%v.Timezone' has unknown timezone '%v' - '%v'", prefix, conf.Timezone, err))
+	}
+	switch conf.API.AuthMode {
+	case "", "query", "body", "basic", "session", "token":
+	default:
+		fieldsPresent = false
+		logModule.Error(fmt.Sprintf("Option '%v.API.AuthMode' has unsupported value '%v'", prefix, conf.API.AuthMode))
+	}
+	if conf.API.AuthMode == "token" {
+		if conf.API.Token == "" {
+			fieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option '%v.API.Token' is mandatory for token mode but not present", prefix))
+		}
+	} else {
+		if conf.API.Login == "" {
+			fieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option '%v.API.Login' is mandatory but not present", prefix))
+		}
+		if conf.API.Password == "" {
+			fieldsPresent = false
+			logModule.Error(fmt.Sprintf("Option '%v.API.Password' is mandatory but not present", prefix))
+		}
+	}
+	if conf.API.AuthMode == "session" && conf.API.SessionCreatePath == "" {
+		fieldsPresent = false
+		logModule.Error(fmt.Sprintf("Option '%v.API.SessionCreatePath' is mandatory for session mode but not present", prefix))
+	}
+	if conf.API.Protocol == "" {
+		fieldsPresent = false
+		logModule.Error(fmt.Sprintf("Option '%v.API.Protocol' is mandatory but not present", prefix))
+	}
+	if conf.API.GetTicketDetailListPath == "" {
+		fieldsPresent = false
+		logModule.Error(fmt.Sprintf("Option '%v.API.GetTicketDetailListPath' is mandatory but not present", prefix))
+	}
+	if conf.Poll.Interval > 0 && conf.API.TicketSearchPath == "" {
+		fieldsPresent = false
+		logModule.Error(fmt.Sprintf("Option '%v.API.TicketSearchPath' is mandatory for polling but not present", prefix))
+	}
+	return fieldsPresent
+}
@@ -0,0 +1,68 @@
+package locale
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"strings"
+	"time"
+)
+
+// Supported languages as ISO 639-1 codes.
+const (
+	Russian string = "ru"
+	English string = "en"
+	Default string = Russian // Used for users without language.
+)
+
+var List = []string{Russian, English}
+
+// Texts by language and key.
+type Catalog map[string]map[string]string
+
+// Return text for language. If text not translated use default language. If not found at all return key.
+func (c Catalog) Text(language, key string) string {
+	if text, ok := c[language][key]; ok {
+		return text
+	}
+	if text, ok := c[Default][key]; ok {
+		return text
+	}
+	return key
+}
+
+// Check that language supported.
+func IsSupported(language string) bool {
+	for _, supported := range List {
+		if supported == language {
+			return true
+		}
+	}
+	return false
+}
+
+// Choose language by Telegram user language code, e.g. "en" or "ru-RU".
+// Russian for russian-speaking regions, English for any other known code, default if code not provided.
+func FromTelegramCode(code string) string {
+	if code == "" {
+		return Default
+	}
+	language := strings.ToLower(strings.SplitN(code, "-", 2)[0])
+	switch language {
+	case Russian, "uk", "be", "kk":
+		return Russian
+	}
+	return English
+}
+
+// Parse IANA timezone name, e.g. "Europe/Moscow". Empty name and "Local" rejected,
+// because they mean bot server timezone instead of user one.
+func ParseTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("%w: timezone '%v'", myErrors.ErrInvalidArgument, name)
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: timezone '%v' - %v", myErrors.ErrInvalidArgument, name, err)
+	}
+	return location, nil
+}
@@ -0,0 +1,80 @@
+package locale
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+)
+
+func TestFromTelegramCode(t *testing.T) {
+	for code, want := range map[string]string{
+		"":      Default,
+		"ru":    Russian,
+		"ru-RU": Russian,
+		"RU":    Russian,
+		"uk":    Russian,
+		"be-BY": Russian,
+		"kk":    Russian,
+		"en":    English,
+		"en-GB": English,
+		"de":    English,
+		"zh-CN": English,
+	} {
+		if language := FromTelegramCode(code); language != want {
+			t.Errorf("FromTelegramCode(%q) = %q, want %q", code, language, want)
+		}
+	}
+}
+
+func TestIsSupported(t *testing.T) {
+	for language, want := range map[string]bool{"ru": true, "en": true, "de": false, "": false, "EN": false} {
+		if supported := IsSupported(language); supported != want {
+			t.Errorf("IsSupported(%q) = %v, want %v", language, supported, want)
+		}
+	}
+}
+
+func TestCatalogText(t *testing.T) {
+	catalog := Catalog{
+		Russian: {"hello": "Привет", "bye": "Пока"},
+		English: {"hello": "Hello"},
+	}
+	cases := []struct {
+		language string
+		key      string
+		want     string
+	}{
+		{English, "hello", "Hello"},
+		{Russian, "hello", "Привет"},
+		// Not translated text in default language.
+		{English, "bye", "Пока"},
+		{"de", "hello", "Привет"},
+		// Unknown key returned as is.
+		{English, "missing", "missing"},
+	}
+	for _, c := range cases {
+		if text := catalog.Text(c.language, c.key); text != c.want {
+			t.Errorf("Text(%q, %q) = %q, want %q", c.language, c.key, text, c.want)
+		}
+	}
+}
+
+func TestParseTimezone(t *testing.T) {
+	for _, name := range []string{"Europe/Moscow", "America/New_York", "Asia/Kolkata", "UTC"} {
+		location, err := ParseTimezone(name)
+		if err != nil {
+			t.Errorf("ParseTimezone(%q) - %v", name, err)
+			continue
+		}
+		if location.String() != name {
+			t.Errorf("ParseTimezone(%q) = %q", name, location)
+		}
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus", "Europe/Moskow", "+03:00", "MSK+3", "../../etc/passwd"} {
+		location, err := ParseTimezone(name)
+		if !errors.Is(err, myErrors.ErrInvalidArgument) {
+			t.Errorf("ParseTimezone(%q) = %v, error '%v', want '%v'", name, location, err, myErrors.ErrInvalidArgument)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package locale
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Word forms for numbers. English uses only One and Many.
+type PluralForms struct {
+	One  string // 1, 21, 31 ...
+	Few  string // 2-4, 22-24 ...
+	Many string // 0, 5-20, 25-30 ...
+}
+
+// Duration units by language.
+var durationUnitList = map[string][]PluralForms{
+	Russian: {
+		{One: "день", Few: "дня", Many: "дней"},
+		{One: "час", Few: "часа", Many: "часов"},
+		{One: "минута", Few: "минуты", Many: "минут"},
+	},
+	English: {
+		{One: "day", Many: "days"},
+		{One: "hour", Many: "hours"},
+		{One: "minute", Many: "minutes"},
+	},
+}
+
+// Short duration units by language. Not pluralised.
+var shortDurationUnitList = map[string][]string{
+	Russian: {"д", "ч", "мин"},
+	English: {"d", "h", "min"},
+}
+
+// Return word form for number by language plural rules.
+func Plural(language string, n int64, forms PluralForms) string {
+	if n < 0 {
+		n = -n
+	}
+	if language != Russian {
+		if n == 1 {
+			return forms.One
+		}
+		return forms.Many
+	}
+
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return forms.One
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return forms.Few
+	}
+	return forms.Many
+}
+
+// Format duration with minute precision, e.g. "1 час 25 минут" or "2 days 3 hours".
+// Zero parts skipped, duration less than a minute shown as zero minutes.
+func Duration(language string, d time.Duration) string {
+	units, ok := durationUnitList[language]
+	if !ok {
+		units = durationUnitList[Default]
+	}
+	values := durationParts(d)
+
+	parts := make([]string, 0, len(values))
+	for i, value := range values {
+		if value == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprint(value, " ", Plural(language, value, units[i])))
+	}
+	if len(parts) == 0 {
+		return fmt.Sprint(0, " ", Plural(language, 0, units[len(units)-1]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Format duration with minute precision in short form, e.g. "1 h 25 min" or "2 d 3 h".
+// Zero parts skipped, duration less than a minute shown as zero minutes.
+func ShortDuration(language string, d time.Duration) string {
+	units, ok := shortDurationUnitList[language]
+	if !ok {
+		units = shortDurationUnitList[Default]
+	}
+	values := durationParts(d)
+
+	parts := make([]string, 0, len(values))
+	for i, value := range values {
+		if value == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprint(value, " ", units[i]))
+	}
+	if len(parts) == 0 {
+		return fmt.Sprint(0, " ", units[len(units)-1])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Split duration into days, hours and minutes. Negative duration counted as zero.
+func durationParts(d time.Duration) []int64 {
+	if d < 0 {
+		d = 0
+	}
+	minutes := int64(d / time.Minute)
+	return []int64{minutes / (24 * 60), minutes / 60 % 24, minutes % 60}
+}
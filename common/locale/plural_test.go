@@ -0,0 +1,73 @@
+package locale
+
+import (
+	"testing"
+	"time"
+)
+
+var dayForms = PluralForms{One: "день", Few: "дня", Many: "дней"}
+
+func TestPluralRussian(t *testing.T) {
+	cases := map[int64]string{
+		0:   "дней",
+		1:   "день",
+		2:   "дня",
+		4:   "дня",
+		5:   "дней",
+		11:  "дней",
+		12:  "дней",
+		14:  "дней",
+		21:  "день",
+		22:  "дня",
+		25:  "дней",
+		101: "день",
+		111: "дней",
+		112: "дней",
+		122: "дня",
+		-1:  "день",
+		-11: "дней",
+	}
+	for n, want := range cases {
+		if form := Plural(Russian, n, dayForms); form != want {
+			t.Errorf("Plural(ru, %v) = %q, want %q", n, form, want)
+		}
+	}
+}
+
+func TestPluralEnglish(t *testing.T) {
+	forms := PluralForms{One: "day", Many: "days"}
+	for n, want := range map[int64]string{0: "days", 1: "day", 2: "days", 11: "days", 21: "days", -1: "day"} {
+		if form := Plural(English, n, forms); form != want {
+			t.Errorf("Plural(en, %v) = %q, want %q", n, form, want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	cases := []struct {
+		language string
+		duration time.Duration
+		want     string
+		short    string
+	}{
+		{Russian, 0, "0 минут", "0 мин"},
+		{Russian, 30 * time.Second, "0 минут", "0 мин"},
+		{Russian, -time.Hour, "0 минут", "0 мин"},
+		{Russian, time.Minute, "1 минута", "1 мин"},
+		{Russian, time.Hour + 25*time.Minute, "1 час 25 минут", "1 ч 25 мин"},
+		{Russian, 2*24*time.Hour + 3*time.Hour, "2 дня 3 часа", "2 д 3 ч"},
+		{Russian, 21*24*time.Hour + 22*time.Minute, "21 день 22 минуты", "21 д 22 мин"},
+		{English, time.Hour + 25*time.Minute, "1 hour 25 minutes", "1 h 25 min"},
+		{English, 24*time.Hour + time.Minute, "1 day 1 minute", "1 d 1 min"},
+		// Unknown language formatted in default language.
+		{"de", time.Hour, "1 час", "1 ч"},
+	}
+	for _, c := range cases {
+		if text := Duration(c.language, c.duration); text != c.want {
+			t.Errorf("Duration(%v, %v) = %q, want %q", c.language, c.duration, text, c.want)
+		}
+		if text := ShortDuration(c.language, c.duration); text != c.short {
+			t.Errorf("ShortDuration(%v, %v) = %q, want %q", c.language, c.duration, text, c.short)
+		}
+	}
+}
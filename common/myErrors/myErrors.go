@@ -16,13 +16,47 @@ var ErrClientNotExists = errors.New("client not exists")
 var ErrNoTeamBounded = errors.New("no team bounded")
 var ErrMoreThenOneTeamBounded = errors.New("more then one team bounded")
 var ErrUserAlreadyExists = errors.New("user already exists")
+var ErrEventNotFound = errors.New("event not found")
+var ErrLeaseLost = errors.New("event lease lost")
+var ErrUnknownRetentionKind = errors.New("unknown retention kind")
+var ErrBackupCorrupted = errors.New("backup integrity check failed")
+var ErrDatabaseInUse = errors.New("database used by other process")
+var ErrUnsupportedDumpVersion = errors.New("unsupported dump version")
 
 // TelegramProvider
 var ErrArgumentNotProvided = errors.New("argument not provided")
 var ErrInvalidArgument = errors.New("invalid argument")
+var ErrSendQueueStopped = errors.New("send queue stopped")
+var ErrInvalidDuration = errors.New("invalid duration")
+
+// OTRSProvider
+var ErrTicketNotFound = errors.New("ticket not found")
+var ErrTicketAccessDenied = errors.New("ticket access denied")
+var ErrSearchNotConfigured = errors.New("ticket search not configured")
+var ErrOTRSAuthFailed = errors.New("otrs authentication failed")
+var ErrOTRSServerError = errors.New("otrs server error")
+var ErrOTRSTimeout = errors.New("otrs request timeout")
+var ErrOTRSUnavailable = errors.New("otrs unavailable")
+var ErrUnknownOTRSInstance = errors.New("unknown otrs instance")
+
+// Formatter
+var ErrTemplateNotFound = errors.New("template not found")
+
+// WebhookProvider
+var ErrUnexpectedResponseStatus = errors.New("unexpected response status")
+
+// CalendarProvider
+var ErrInvalidWorkingHours = errors.New("invalid working hours")
+var ErrInvalidWorkingDay = errors.New("invalid working day")
+var ErrInvalidHoliday = errors.New("invalid holiday")
+var ErrInvalidTimeRange = errors.New("invalid time range")
+
+// Report
+var ErrInvalidReportCriteria = errors.New("invalid report criteria")
 
 // Config
 var ErrOTRSLoginNotProvided = errors.New("otrs login not provided")
 var ErrOTRSPasswordNotProvided = errors.New("otrs password not provided")
+var ErrOTRSTokenNotProvided = errors.New("otrs token not provided")
 var ErrTelegramTokenNotProvided = errors.New("telegram token not provided")
 var ErrMandatoryFieldMissing = errors.New("mandatory fields missing")
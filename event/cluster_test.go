@@ -0,0 +1,190 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+)
+
+// Record sent messages of all processors.
+type recordingTelegram struct {
+	TelegramProvider.TelegramProvider
+	mx   sync.Mutex
+	sent map[string]int // Number of sends by chat and text.
+}
+
+func (rt *recordingTelegram) SendEventMessage(chatID int64, message Formatter.Message, priority int) error {
+	rt.mx.Lock()
+	defer rt.mx.Unlock()
+	rt.sent[fmt.Sprint(chatID, " ", message.PlainText)]++
+	return nil
+}
+
+// Return copy of sent messages.
+func (rt *recordingTelegram) messages() map[string]int {
+	rt.mx.Lock()
+	defer rt.mx.Unlock()
+	sent := make(map[string]int, len(rt.sent))
+	for message, count := range rt.sent {
+		sent[message] = count
+	}
+	return sent
+}
+
+// Clients without team.
+type noTeamClient struct {
+	ClientProvider.ClientProvider
+}
+
+func (noTeamClient) GetTeamByClient(client string) (string, error) {
+	return "", errors.New("no team")
+}
+
+// Return two processors with own connections to one database, like two instances of bot.
+func newCluster(t *testing.T) ([]*Processor, *recordingTelegram) {
+	t.Helper()
+	directory := t.TempDir()
+	var telegram TelegramProvider.TelegramProvider
+	recorder := &recordingTelegram{sent: make(map[string]int)}
+	telegram = recorder
+	var client ClientProvider.ClientProvider = noTeamClient{}
+
+	processorList := make([]*Processor, 0, 2)
+	for _, owner := range []string{"first", "second"} {
+		processorList = append(processorList, &Processor{
+			DB:       openDB(t, directory),
+			Telegram: &telegram,
+			Client:   &client,
+			Log:      quietLogger{},
+			Owner:    owner,
+			Lease:    DefaultLease,
+		})
+	}
+	return processorList, recorder
+}
+
+func TestDeferredMessagesSentOnce(t *testing.T) {
+	processorList, telegram := newCluster(t)
+	const messages = 50
+	for n := 0; n < messages; n++ {
+		_, err := (*processorList[0].DB).MessageListNewDeferredMessage("Telegram", int64(n), fmt.Sprint("message ", n), "", time.Now().Add(-time.Minute).Unix())
+		if err != nil {
+			t.Fatalf("add deferred message - %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range processorList {
+		for n := 0; n < 3; n++ {
+			wg.Add(1)
+			go func(p *Processor) {
+				defer wg.Done()
+				p.deliverDeferred()
+			}(p)
+		}
+	}
+	wg.Wait()
+
+	if len(telegram.sent) != messages {
+		t.Errorf("%v of %v deferred messages sent", len(telegram.sent), messages)
+	}
+	for message, count := range telegram.sent {
+		if count != 1 {
+			t.Errorf("'%v' sent %v times", message, count)
+		}
+	}
+}
+
+func TestSummarySentOnce(t *testing.T) {
+	processorList, telegram := newCluster(t)
+	today := time.Now().UTC()
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	now := day.Add(12*time.Hour + 10*time.Second)
+	previousCheck := now.Add(-SchedulerInterval)
+	for _, p := range processorList {
+		p.Location = time.UTC
+		p.Summary = &WeeklySummary{
+			Weekday:   now.Weekday(),
+			Time:      12 * time.Hour,
+			TeamLeads: map[string][]int64{"Team1": {100}},
+		}
+		p.sendDueSummary(previousCheck)
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range processorList {
+		wg.Add(1)
+		go func(p *Processor) {
+			defer wg.Done()
+			p.sendDueSummary(now)
+		}(p)
+	}
+	wg.Wait()
+
+	if len(telegram.sent) != 1 {
+		t.Fatalf("%v different summaries sent, want 1", len(telegram.sent))
+	}
+	for _, count := range telegram.sent {
+		if count != 1 {
+			t.Errorf("summary sent %v times", count)
+		}
+	}
+}
+
+func TestDigestClaimedOnce(t *testing.T) {
+	processorList, _ := newCluster(t)
+	first, second := processorList[0], processorList[1]
+	first.DigestInterval = time.Hour
+	second.DigestInterval = time.Hour
+	now := time.Now()
+
+	// Scheduled digest: second instance checks a bit later, after first sent digest.
+	if !first.claimDigest("Team1", now, now.Add(-SchedulerInterval)) {
+		t.Fatal("first scheduled digest not claimed")
+	}
+	if second.claimDigest("Team1", now.Add(10*time.Second), now.Add(10*time.Second-SchedulerInterval)) {
+		t.Error("scheduled digest claimed twice")
+	}
+	if !second.claimDigest("Team2", now.Add(10*time.Second), now.Add(10*time.Second-SchedulerInterval)) {
+		t.Error("digest of other team not claimed")
+	}
+
+	// Interval digest due for instance which didn't send digest itself.
+	later := now.Add(30 * time.Minute)
+	if second.claimDigest("Team1", later, later.Add(-second.DigestInterval)) {
+		t.Error("interval digest claimed before interval passed since digest of other instance")
+	}
+	later = now.Add(time.Hour + time.Minute)
+	if !second.claimDigest("Team1", later, later.Add(-second.DigestInterval)) {
+		t.Error("interval digest not claimed after interval")
+	}
+	if first.claimDigest("Team1", later, later.Add(-first.DigestInterval)) {
+		t.Error("interval digest claimed twice")
+	}
+}
+
+// Message claimed by failed instance sent by other instance after lease expires.
+func TestDeferredMessageLeaseExpires(t *testing.T) {
+	processorList, telegram := newCluster(t)
+	db := *processorList[0].DB
+	_, err := db.MessageListNewDeferredMessage("Telegram", 1, "message", "", time.Now().Add(-time.Minute).Unix())
+	if err != nil {
+		t.Fatalf("add deferred message - %v", err)
+	}
+	claimed, err := db.MessageListClaimDeferred("Telegram", "failed", time.Now().Add(-time.Second).Unix(), time.Now().Unix())
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("claim for failed instance - %v, %v", claimed, err)
+	}
+
+	processorList[1].deliverDeferred()
+
+	if telegram.sent["1 message"] != 1 {
+		t.Errorf("message with expired lease not sent")
+	}
+}
@@ -0,0 +1,154 @@
+package event
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/CalendarProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+	"time"
+)
+
+// Teams available for subscription. Tickets without team included into digest of every team.
+var teamList = []string{"Team1", "Team2", "Team3"}
+
+const jobDigest string = "digest " // Scheduled job of team digest, followed by team name.
+
+// Send digest of open tickets to teams for which digest is due.
+// Digest due at configured daily times and every interval during team working hours.
+// Instances sharing database claim digest of team, so digest sent once.
+func (p *Processor) sendDueDigests(now time.Time) {
+	if len(p.DigestTimes) == 0 && p.DigestInterval <= 0 {
+		return
+	}
+
+	// First check only remember time. Digests sent on schedule, not on start.
+	previousCheck := p.digestCheck
+	p.digestCheck = now
+	if previousCheck.IsZero() {
+		p.lastDigest = make(map[string]time.Time)
+		for _, team := range teamList {
+			p.lastDigest[team] = now
+		}
+		return
+	}
+
+	// Digest not sent again if sent by any instance after previous check or during interval.
+	scheduled := p.isDigestTime(previousCheck, now)
+	dueTeams := make([]string, 0, len(teamList))
+	lastRunNotAfter := make(map[string]time.Time, len(teamList))
+	for _, team := range teamList {
+		intervalDue := p.DigestInterval > 0 &&
+			now.Sub(p.lastDigest[team]) >= p.DigestInterval &&
+			(*p.Calendar).IsWorkingTime(team, now)
+		if intervalDue {
+			lastRunNotAfter[team] = now.Add(-p.DigestInterval)
+		}
+		if scheduled && previousCheck.After(lastRunNotAfter[team]) {
+			lastRunNotAfter[team] = previousCheck
+		}
+		if scheduled || intervalDue {
+			dueTeams = append(dueTeams, team)
+		}
+	}
+	if len(dueTeams) == 0 {
+		return
+	}
+
+	ticketsByTeam, err := p.collectDigestTickets()
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Can't collect tickets for digest - '%v'", err))
+		return
+	}
+	for _, team := range dueTeams {
+		p.lastDigest[team] = now
+		if !p.claimDigest(team, now, lastRunNotAfter[team]) {
+			continue
+		}
+		tickets := append(ticketsByTeam[team], ticketsByTeam[""]...)
+		if len(tickets) == 0 {
+			p.Log.Debug(fmt.Sprintf("No open tickets for team '%v'. Digest skipped.", team))
+			continue
+		}
+		Formatter.SortByUrgency(tickets)
+
+		data := Formatter.TemplateData{Team: team, Tickets: tickets}
+		message, err := Formatter.RenderLocalised(Formatter.KindDigest, data)
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't render digest for team '%v' - '%v'", team, err))
+			continue
+		}
+		p.Log.Info(fmt.Sprintf("Send digest with '%v' tickets to team '%v'", len(tickets), team))
+		go p.sendMessageForByTeam(team, delivery{
+			Message:  message,
+			Priority: TelegramProvider.PriorityReminder,
+			Digest:   true,
+		})
+	}
+}
+
+// Claim digest of team if last digest sent by any instance not after provided time.
+// Return false if digest sent by other instance or claim failed.
+func (p *Processor) claimDigest(team string, now, lastRunNotAfter time.Time) bool {
+	claimed, err := (*p.DB).ScheduledJobClaim(jobDigest+team, p.Owner, now.Unix(), lastRunNotAfter.Unix())
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Can't claim digest for team '%v' - '%v'", team, err))
+		return false
+	}
+	if !claimed {
+		p.Log.Debug(fmt.Sprintf("Digest for team '%v' already sent by other instance", team))
+	}
+	return claimed
+}
+
+// Check if any daily digest time passed after previous check.
+func (p *Processor) isDigestTime(previousCheck, now time.Time) bool {
+	location := p.Location
+	if location == nil {
+		location = time.UTC
+	}
+	for _, t := range []time.Time{previousCheck.In(location), now.In(location)} {
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+		for _, clock := range p.DigestTimes {
+			at := CalendarProvider.AddClock(day, clock)
+			if at.After(previousCheck) && !at.After(now) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Return open tickets of all active events grouped by team. Tickets without team stored with empty team.
+func (p *Processor) collectDigestTickets() (map[string][]Formatter.TemplateData, error) {
+	eventList, err := (*p.DB).OTRSEventGetAllActive()
+	if err != nil {
+		return nil, err
+	}
+
+	ticketsByTeam := make(map[string][]Formatter.TemplateData)
+	refs := make([]OTRSProvider.TicketRef, 0, len(eventList))
+	for _, event := range eventList {
+		refs = append(refs, OTRSProvider.TicketRef{Instance: event.Instance, TicketID: fmt.Sprint(event.TicketID)})
+	}
+	for index, result := range p.OTRS.GetTicketDetailsList(refs) {
+		event := eventList[index]
+		ticketDetails, err := result.Ticket, result.Err
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't get ticket '%v' from instance '%v' for digest - '%v'", event.TicketID, event.Instance, err))
+			continue
+		}
+		// Finished tickets closed by event processing later.
+		if ticketDetails.StateType == "closed" || ticketDetails.StateType == "merged" {
+			continue
+		}
+
+		// Any lookup error means no team.
+		team, err := (*p.Client).GetTeamByClient(ticketDetails.CustomerID)
+		if err != nil {
+			team = ""
+		}
+		ticketsByTeam[team] = append(ticketsByTeam[team], Formatter.NewTemplateData(ticketDetails, p.Log))
+	}
+	return ticketsByTeam, nil
+}
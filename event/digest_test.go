@@ -0,0 +1,228 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider/fakeOTRS"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+)
+
+// Teams of clients. Unknown clients have no team.
+type teamClient struct {
+	ClientProvider.ClientProvider
+	teams map[string]string
+}
+
+func (tc teamClient) GetTeamByClient(client string) (string, error) {
+	team, ok := tc.teams[client]
+	if !ok {
+		return "", errors.New("unknown client")
+	}
+	return team, nil
+}
+
+// Return processor with events for tickets of fake OTRS and recording telegram.
+func newDigestProcessor(t *testing.T, server *fakeOTRS.Server, ticketIDs ...int64) (*Processor, *recordingTelegram) {
+	t.Helper()
+	poller, db := newTestPoller(t, server)
+	for _, ticketID := range ticketIDs {
+		err := (*db).OTRSEventCreateNew("test", "new", "", ticketID)
+		if err != nil {
+			t.Fatalf("create event - %v", err)
+		}
+	}
+
+	var client ClientProvider.ClientProvider = teamClient{teams: map[string]string{"first": "Team1", "second": "Team2"}}
+	var telegram TelegramProvider.TelegramProvider
+	recorder := &recordingTelegram{sent: make(map[string]int)}
+	telegram = recorder
+	p := poller.Processor
+	p.OTRS = poller.OTRS
+	p.Client = &client
+	p.Telegram = &telegram
+	p.Location = time.UTC
+	return p, recorder
+}
+
+// Add tickets of different teams, priorities, ages and states.
+func addDigestTickets(server *fakeOTRS.Server) {
+	now := time.Now().UTC()
+	for ticketID, ticket := range map[string]struct {
+		OTRSProvider.TicketOTRS
+		age time.Duration
+	}{
+		"1": {OTRSProvider.TicketOTRS{CustomerID: "first", Priority: "3 normal", StateType: "open"}, 3 * time.Hour},
+		"2": {OTRSProvider.TicketOTRS{CustomerID: "first", Priority: "5 very high", StateType: "new"}, time.Hour},
+		"3": {OTRSProvider.TicketOTRS{CustomerID: "second", Priority: "3 normal", StateType: "open"}, time.Hour},
+		"4": {OTRSProvider.TicketOTRS{CustomerID: "unknown", Priority: "3 normal", StateType: "open"}, 2 * time.Hour},
+		"5": {OTRSProvider.TicketOTRS{CustomerID: "first", Priority: "3 normal", StateType: "closed"}, time.Hour},
+		"6": {OTRSProvider.TicketOTRS{CustomerID: "second", Priority: "3 normal", StateType: "merged"}, time.Hour},
+	} {
+		ticket.TicketNumber = "100000" + ticketID
+		ticket.Created = now.Add(-ticket.age).Format(OTRSProvider.TimeLayout)
+		server.AddTicket(ticketID, ticket.TicketOTRS)
+	}
+}
+
+func ticketNumbers(tickets []Formatter.TemplateData) string {
+	numbers := make([]string, 0, len(tickets))
+	for _, ticket := range tickets {
+		numbers = append(numbers, ticket.TicketNumber)
+	}
+	return strings.Join(numbers, " ")
+}
+
+func TestCollectDigestTickets(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	addDigestTickets(server)
+	// Ticket 7 not found in OTRS.
+	p, _ := newDigestProcessor(t, server, 1, 2, 3, 4, 5, 6, 7)
+
+	ticketsByTeam, err := p.collectDigestTickets()
+	if err != nil {
+		t.Fatalf("collect tickets - %v", err)
+	}
+
+	want := map[string]string{
+		"Team1": "1000001 1000002",
+		"Team2": "1000003",
+		"":      "1000004", // Unknown client.
+	}
+	if len(ticketsByTeam) != len(want) {
+		t.Errorf("tickets of %v teams, want %v", len(ticketsByTeam), len(want))
+	}
+	for team, numbers := range want {
+		if got := ticketNumbers(ticketsByTeam[team]); got != numbers {
+			t.Errorf("tickets of team '%v' - '%v', want '%v'", team, got, numbers)
+		}
+	}
+}
+
+func TestIsDigestTime(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(day, hour, minute int) time.Time { return time.Date(2024, 6, day, hour, minute, 0, 0, time.UTC) }
+	times := []time.Duration{9 * time.Hour, 18 * time.Hour}
+
+	cases := []struct {
+		name          string
+		location      *time.Location
+		previousCheck time.Time
+		now           time.Time
+		want          bool
+	}{
+		{"before", time.UTC, utc(1, 8, 58), utc(1, 8, 59), false},
+		{"passed", time.UTC, utc(1, 8, 59), utc(1, 9, 0), true},
+		{"after", time.UTC, utc(1, 9, 0), utc(1, 9, 1), false},
+		{"second time", time.UTC, utc(1, 17, 59), utc(1, 18, 1), true},
+		{"across midnight", time.UTC, utc(1, 23, 59), utc(2, 0, 1), false},
+		{"long pause", time.UTC, utc(1, 18, 1), utc(2, 9, 1), true},
+		// Digest times in processor timezone. UTC if not provided.
+		{"timezone", moscow, utc(1, 5, 59), utc(1, 6, 0), true},
+		{"UTC time in timezone", moscow, utc(1, 8, 59), utc(1, 9, 0), false},
+		{"no location", nil, utc(1, 8, 59), utc(1, 9, 0), true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := &Processor{DigestTimes: times, Location: c.location}
+			if due := p.isDigestTime(c.previousCheck, c.now); due != c.want {
+				t.Errorf("digest due %v, want %v", due, c.want)
+			}
+		})
+	}
+}
+
+func TestSendDueDigests(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	addDigestTickets(server)
+	p, telegram := newDigestProcessor(t, server, 1, 2, 3, 4, 5, 6)
+	// Team1 has two subscribers, one of them receives individual reminders only. Team3 has no tickets of own.
+	db := *p.DB
+	for chatID, subscription := range map[int64]string{10: "Team1", 11: "Team1", 20: "Team2", 30: "Team3"} {
+		if err := db.BotUserAdd(chatID, "private", "en"); err != nil {
+			t.Fatalf("add user - %v", err)
+		}
+		if chatID != 11 {
+			if err := db.BotUserUpdateNotificationMode(chatID, DBProvider.NotificationModeDigest); err != nil {
+				t.Fatalf("update notification mode - %v", err)
+			}
+		}
+		userID, err := db.BotUserGetByTelegramID(chatID)
+		if err != nil {
+			t.Fatalf("get user - %v", err)
+		}
+		if err := db.SubscriptionListAdd(userID, subscription); err != nil {
+			t.Fatalf("add subscription - %v", err)
+		}
+	}
+
+	today := time.Now().UTC()
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	p.DigestTimes = []time.Duration{12 * time.Hour}
+
+	// First check only remembers time, even after digest time.
+	p.sendDueDigests(day.Add(12*time.Hour + time.Second))
+	p.sendDueDigests(day.Add(12*time.Hour + 2*time.Second))
+	time.Sleep(100 * time.Millisecond)
+	if sent := telegram.messages(); len(sent) != 0 {
+		t.Fatalf("digest sent without schedule - %v", sent)
+	}
+
+	p.sendDueDigests(day.Add(36*time.Hour + time.Second))
+	var sent map[string]int
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if sent = telegram.messages(); len(sent) >= 3 {
+			break
+		}
+	}
+
+	// Tickets without team included into digest of every team. Higher priority first, then older.
+	want := map[int64][]string{
+		10: {"Team1", "1000002", "1000001", "1000004"},
+		20: {"Team2", "1000004", "1000003"},
+		30: {"Team3", "1000004"},
+	}
+	if len(sent) != len(want) {
+		t.Errorf("%v digests sent, want %v - %v", len(sent), len(want), sent)
+	}
+	for chatID, parts := range want {
+		text := digestFor(sent, chatID)
+		position := 0
+		for _, part := range parts {
+			index := strings.Index(text[position:], part)
+			if index < 0 {
+				t.Errorf("digest for chat %v has no '%v' after position %v:\n%v", chatID, part, position, text)
+				break
+			}
+			position += index + len(part)
+		}
+		for _, excluded := range []string{"1000005", "1000006"} {
+			if strings.Contains(text, excluded) {
+				t.Errorf("digest for chat %v includes finished ticket %v", chatID, excluded)
+			}
+		}
+	}
+}
+
+// Return text of message sent to chat.
+func digestFor(sent map[string]int, chatID int64) string {
+	prefix := fmt.Sprint(chatID, " ")
+	for message := range sent {
+		if strings.HasPrefix(message, prefix) {
+			return strings.TrimPrefix(message, prefix)
+		}
+	}
+	return ""
+}
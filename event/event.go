@@ -1,35 +1,91 @@
 package event
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/CalendarProvider"
 	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
 	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
 	"github.com/Sarraksh/otrs-echo-bot/Formatter"
 	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
 	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+	"github.com/Sarraksh/otrs-echo-bot/WebhookProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
 	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const SchedulerInterval time.Duration = 30 * time.Second // Interval between checks for due events.
+const DefaultLease time.Duration = 2 * time.Minute       // Time event stays claimed by instance if not released.
+const AccessDeniedRetry time.Duration = 10 * time.Minute // Delay before retry of event with ticket not available to OTRS API user.
+
 // Processing events at all stages.
 type Processor struct {
 	DB       *DBProvider.DBProvider
-	OTRS     *OTRSProvider.OTRSProvider
+	OTRS     *OTRSProvider.Instances
 	Client   *ClientProvider.ClientProvider
 	Telegram *TelegramProvider.TelegramProvider
+	Webhook  *WebhookProvider.WebhookProvider
+	Calendar *CalendarProvider.CalendarProvider
 	Log      logger.Logger
 	mx       sync.Mutex
+
+	Owner   string        // Instance name in event leases. Unique for every instance sharing database.
+	Lease   time.Duration // Time event stays claimed by instance. Exceeds event processing time.
+	Standby time.Duration // Only events overdue by this time claimed, so instance takes over when active instance stops.
+
+	EscalationLevel int  // Escalation template used for reminders from this level. Disabled if zero.
+	NotifyClosure   bool // Send message to Telegram when ticket taken, closed or merged.
+
+	Policies []config.EscalationPolicyConf // Escalation policies in priority order.
+
+	CriticalPriorities []string       // Ticket priorities delivered in quiet hours and do-not-disturb mode.
+	Location           *time.Location // Default timezone for user quiet hours and digest times.
+
+	DigestTimes    []time.Duration      // Daily digest times since midnight.
+	DigestInterval time.Duration        // Digest interval during team working hours. Disabled if zero.
+	digestCheck    time.Time            // Time of previous check for due digests.
+	lastDigest     map[string]time.Time // Time of last digest by team.
+
+	pausedUntil map[string]time.Time // Events of OTRS instance postponed till this time while instance unavailable.
+
+	Summary      *WeeklySummary // Weekly response time summary. Disabled if nil.
+	summaryCheck time.Time      // Time of previous check for due summary.
+
+	accessDenied uint64 // Ticket requests denied by OTRS since start.
+}
+
+// Process due events periodically until context done.
+func (p *Processor) Scheduler(ctx context.Context) error {
+	ticker := time.NewTicker(SchedulerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.ProcessEvent()
+			p.deliverDeferred()
+			p.sendDueDigests(time.Now())
+			p.sendDueSummary(time.Now())
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
 func (p *Processor) ProcessEvent() {
-	// Measure that one event not processing by two routines simultaneously.
+	// Events of one instance processed one by one. Other instances excluded by event lease.
 	p.mx.Lock()
 	defer p.mx.Unlock()
 
-	// Check events for processing.
+	// Claim event for processing.
 	p.Log.Debug("Start search for active events.")
-	eventDBID, ticketID, err := (*p.DB).OTRSEventGetActive()
+	now := time.Now()
+	eventDBID, instance, ticketID, err := (*p.DB).OTRSEventClaim(p.Owner, now.Add(p.Lease).Unix(), now.Add(-p.Standby).Unix())
 	if err == myErrors.ErrNoActiveEvents {
 		p.Log.Debug("No active events.")
 		return
@@ -38,45 +94,97 @@ func (p *Processor) ProcessEvent() {
 		p.Log.Error(fmt.Sprintf("Can't search active events - '%v'", err))
 		return
 	}
+	defer p.release(eventDBID)
 
 	// Schedule another processing right after current.
-	go p.ProcessEvent() // TODO prevent infinite loop if error occurred
+	// Failed event suspended or processing paused so same event not processed again.
+	go p.ProcessEvent()
 
 	// Get additional info for event.
 	// Current status and detailed info for ticket from OTRS.
-	p.Log.Error(fmt.Sprintf("Get active event with eventDBID '%v' and tiketID '%v'", eventDBID, ticketID))
-	OTRS := *p.OTRS
+	p.Log.Error(fmt.Sprintf("Get active event with eventDBID '%v', instance '%v' and tiketID '%v'", eventDBID, instance, ticketID))
+	if until := p.pausedUntil[instance]; time.Now().Before(until) {
+		p.Log.Debug(fmt.Sprintf("OTRS instance '%v' paused. Postpone event with ID '%v' till '%v'", instance, eventDBID, until))
+		err = (*p.DB).OTRSEventPostpone(eventDBID, until.Unix())
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't postpone event with ID '%v' - '%v'", eventDBID, err))
+		}
+		return
+	}
+	OTRS, err := p.OTRS.Get(instance)
+	if err != nil {
+		p.handleOTRSError(eventDBID, instance, ticketID, err)
+		return
+	}
 	ticketDetails, err := OTRS.GetTicketDetails(ticketID)
 	if err != nil {
-		// TODO - add logic for close program
-		p.Log.Error(fmt.Sprintf("Can't search for active events - '%v'", err))
+		p.handleOTRSError(eventDBID, instance, ticketID, err)
 		return
 	}
+	// Event may be claimed by other instance if OTRS answered slower than lease.
+	err = (*p.DB).OTRSEventExtendLease(eventDBID, p.Owner, time.Now().Add(p.Lease).Unix())
+	if err != nil {
+		p.Log.Warning(fmt.Sprintf("Lease for event with eventDBID '%v' lost. Skip processing - '%v'", eventDBID, err))
+		return
+	}
+
+	// Remember ticket number for search of event history by number.
+	ticketIDint, _ := strconv.ParseInt(ticketID, 10, 64)
+	err = (*p.DB).OTRSEventSetTicketNumber(instance, ticketIDint, ticketDetails.TicketNumber)
+	if err != nil {
+		p.Log.Warning(fmt.Sprintf("Can't save ticket number for ticket ID '%v' - '%v'", ticketID, err))
+	}
+	p.recordChanges(eventDBID, ticketDetails)
 	status, err := (*p.DB).OTRSEventGetStatus(eventDBID)
 	p.Log.Debug(fmt.Sprintf("Processing event with eventDBID '%v' and status '%v'", eventDBID, status))
 
 	// Generate message for bot user.
-	var message string
+	data := Formatter.NewTemplateData(ticketDetails, p.Log)
+	var templateKind string
+	var kind string
 	switch status {
 	case "New":
-		message = Formatter.EventNewPlainText(ticketDetails)
-		p.Log.Debug(fmt.Sprintf("For event with eventDBID '%v' and status '%v' genereted message:\n'%v'", eventDBID, status, message))
+		templateKind = Formatter.KindNew
+		kind = WebhookProvider.KindAlert
 	case "Processing", "Suspended":
-		message = Formatter.EventReminderPlainText(ticketDetails, p.Log)
-		p.Log.Debug(fmt.Sprintf("For event with eventDBID '%v' and status '%v' genereted message:\n'%v'", eventDBID, status, message))
+		// Stop reminding if ticket already taken or finished in OTRS.
 		switch {
 		case ticketDetails.Lock == "lock":
-			finishEventProcessing("lock", eventDBID, p.DB, p.Log)
+			p.finishEventProcessing("lock", eventDBID, ticketDetails)
+			return
 		case ticketDetails.StateType == "closed":
-			finishEventProcessing("closed", eventDBID, p.DB, p.Log)
+			p.finishEventProcessing("closed", eventDBID, ticketDetails)
+			return
 		case ticketDetails.StateType == "merged":
-			finishEventProcessing("merged", eventDBID, p.DB, p.Log)
+			p.finishEventProcessing("merged", eventDBID, ticketDetails)
 			return
 		}
+		templateKind = p.reminderKind(data)
+		kind = WebhookProvider.KindReminder
 	default:
 		p.Log.Warning(fmt.Sprintf("For event with eventDBID '%v' recieved unknown status '%v'", eventDBID, status))
-		message = Formatter.EventReminderPlainText(ticketDetails, p.Log)
-		p.Log.Debug(fmt.Sprintf("For event with eventDBID '%v' and status '%v' genereted message:\n'%v'", eventDBID, status, message))
+		templateKind = p.reminderKind(data)
+		kind = WebhookProvider.KindReminder
+	}
+	message := p.renderMessage(templateKind, data)
+	p.Log.Debug(fmt.Sprintf("For event with eventDBID '%v' and status '%v' genereted message:\n'%v'", eventDBID, status, message.Default().Text))
+
+	// Get team for event by client.
+	team, ok := p.getTeam(ticketDetails.CustomerID)
+	if !ok {
+		// TODO - add logic for close program
+		return
+	}
+
+	// Evaluate escalation policy. Postponed event activated again at start of working hours.
+	plan := p.planActivation(team, ticketDetails.Priority, time.Now())
+	if !plan.Notify {
+		p.Log.Info(fmt.Sprintf("Event with eventDBID '%v' postponed till '%v' by escalation policy", eventDBID, plan.NextActivation))
+		err = (*p.DB).OTRSEventPostpone(eventDBID, plan.NextActivation.Unix())
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't postpone event with ID '%v' - '%v'", eventDBID, err))
+		}
+		return
 	}
 
 	// Set status "Processing" for current event.
@@ -88,129 +196,207 @@ func (p *Processor) ProcessEvent() {
 		return
 	}
 
-	// Get team for event by client.
-	p.Log.Debug(fmt.Sprintf("Get bounded team for client '%v'", ticketDetails.CustomerID))
-	clientModule := *p.Client
-	team, err := clientModule.GetTeamByClient(ticketDetails.CustomerID)
-	switch err {
-	case nil:
+	// New ticket alerts sent before reminders if send queue is full.
+	priority := TelegramProvider.PriorityReminder
+	if kind == WebhookProvider.KindAlert {
+		priority = TelegramProvider.PriorityAlert
+	}
+
+	// New ticket alerts for users in quiet hours delivered after quiet hours, reminders dropped.
+	d := delivery{
+		Message:    message,
+		Priority:   priority,
+		Critical:   p.isCritical(ticketDetails.Priority),
+		Deferrable: kind == WebhookProvider.KindAlert,
+		Reminder:   kind == WebhookProvider.KindReminder,
+		Instance:   ticketDetails.Instance,
+		EventID:    eventDBID,
+		Kind:       kind,
+	}
+
+	// Send message to on-duty chats, team subscribers or all users.
+	switch {
+	case len(plan.OnDutyChats) > 0:
+		p.Log.Debug(fmt.Sprintf("Outside working hours for team '%v'. Send message to on-duty chats.", team))
+		go p.sendMessageForChats(plan.OnDutyChats, d)
+	case team != "":
 		p.Log.Debug(fmt.Sprintf("Team '%v' bounded with client '%v'. Send message to all subscribed users.", team, ticketDetails.CustomerID))
-		go sendMessageForByTeam(team, message, p.DB, p.Log, p.Telegram)
-	case myErrors.ErrNoTeamBounded:
-		p.Log.Debug(fmt.Sprintf("No team bounded with client '%v'. Send message to all users.", ticketDetails.CustomerID))
-		go sendMessageForAllBotUsers(message, p.DB, p.Log, p.Telegram)
-	case myErrors.ErrClientNotExists:
-		p.Log.Debug(fmt.Sprintf("Client '%v' not found. Add into DB", ticketDetails.CustomerID))
-		err := clientModule.AddClient(ticketDetails.CustomerID)
-		if err != nil {
-			// TODO - add logic for close program
-			p.Log.Error(fmt.Sprintf("Can't add new client '%v'", ticketDetails.CustomerID))
-			return
-		}
-	case myErrors.ErrMoreThenOneTeamBounded:
-		p.Log.Error(fmt.Sprintf("With client '%v' bound more than one team. Send message to all users.", ticketDetails.CustomerID))
-		go sendMessageForAllBotUsers(message, p.DB, p.Log, p.Telegram)
+		go p.sendMessageForByTeam(team, d)
 	default:
-		p.Log.Error(fmt.Sprintf("While get bounded team for client '%v'", ticketDetails.CustomerID))
-		// TODO - add logic for close program
-		return
+		p.Log.Debug(fmt.Sprintf("No team for client '%v'. Send message to all users.", ticketDetails.CustomerID))
+		go p.sendMessageForAllBotUsers(d)
 	}
+	p.notifyWebhooks(eventDBID, kind, "", team, ticketDetails)
 
-	// Suspend event processing.
-	err = (*p.DB).OTRSEventSuspend(eventDBID)
+	// Suspend event processing till next activation.
+	err = (*p.DB).OTRSEventSuspend(eventDBID, plan.NextActivation.Unix())
 	if err != nil {
 		p.Log.Debug(fmt.Sprintf("Can't suspend event with ID '%v' - '%v'", eventDBID, err))
 		// TODO - add logic for close program
 	}
 }
 
-func sendMessageForAllBotUsers(message string, db *DBProvider.DBProvider, logger logger.Logger, tBot *TelegramProvider.TelegramProvider) {
-	logger.Debug(fmt.Sprintf("Start sending sequense for message:\n'%v'", message))
-
-	// Get all users by subscription (all subscriptions).
-	subscriptionList := make([]string, 0, 8)
-	subscriptionList = append(subscriptionList, "Team1", "Team2", "Team3")
-	userList, err := (*db).SubscriptionListGetActiveByMultipleSubscription(subscriptionList)
+// Release event lease so any instance can claim event on next activation.
+// Lease expires by itself if release failed.
+func (p *Processor) release(eventDBID int64) {
+	err := (*p.DB).OTRSEventRelease(eventDBID, p.Owner)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Whle get users by subscription - '%v'", err))
-		return
-	}
-	if len(userList) < 1 {
-		logger.Warning(fmt.Sprintf("No users for all subscriptions"))
-		return
-	}
-
-	// Generate send message tasks.
-	for _, user := range userList {
-		go sendMessage(user, &message, db, tBot, logger)
+		p.Log.Error(fmt.Sprintf("Can't release event with ID '%v' - '%v'", eventDBID, err))
 	}
 }
 
-func sendMessageForByTeam(team, message string, db *DBProvider.DBProvider, logger logger.Logger, tBot *TelegramProvider.TelegramProvider) {
-	logger.Debug(fmt.Sprintf("Start sending sequense for message:\n'%v'", message))
-
-	// Get all users by subscription.
-	userList, err := (*db).SubscriptionListGetActiveBySubscription(team)
-	if err != nil {
-		logger.Error(fmt.Sprintf("Whle get users by subscription - '%v'", err))
-		return
-	}
-	if len(userList) < 1 {
-		logger.Warning(fmt.Sprintf("No users for '%v' subscription", team))
-		return
+// Pause instance events while OTRS unavailable, end events of deleted tickets and removed instances
+// and retry event later on other errors. Denied access usually means wrong permissions of API user,
+// so event kept and failure counted.
+func (p *Processor) handleOTRSError(eventDBID int64, instance, ticketID string, err error) {
+	switch {
+	case errors.Is(err, myErrors.ErrOTRSUnavailable):
+		until := time.Now().Add(SchedulerInterval)
+		if p.pausedUntil == nil {
+			p.pausedUntil = make(map[string]time.Time)
+		}
+		p.pausedUntil[instance] = until
+		p.Log.Error(fmt.Sprintf("OTRS instance '%v' unavailable - '%v'. Pause event processing till '%v'", instance, err, until))
+		err = (*p.DB).OTRSEventPostpone(eventDBID, until.Unix())
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't postpone event with ID '%v' - '%v'", eventDBID, err))
+		}
+	case errors.Is(err, myErrors.ErrUnknownOTRSInstance):
+		p.Log.Error(fmt.Sprintf("OTRS instance '%v' not configured. End event with ID '%v'", instance, eventDBID))
+		err = (*p.DB).OTRSEventEnded(eventDBID)
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't end event with ID '%v' - '%v'", eventDBID, err))
+		}
+	case errors.Is(err, myErrors.ErrTicketAccessDenied):
+		atomic.AddUint64(&p.accessDenied, 1)
+		nextActivation := time.Now().Add(AccessDeniedRetry)
+		p.Log.Error(fmt.Sprintf("Access to ticket '%v' of OTRS instance '%v' denied - '%v'. Check API user permissions. Retry event with ID '%v' at '%v'", ticketID, instance, err, eventDBID, nextActivation))
+		err = (*p.DB).OTRSEventPostpone(eventDBID, nextActivation.Unix())
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't postpone event with ID '%v' - '%v'", eventDBID, err))
+		}
+	case errors.Is(err, myErrors.ErrTicketNotFound):
+		p.Log.Warning(fmt.Sprintf("Ticket '%v' not found in OTRS. End event with ID '%v'", ticketID, eventDBID))
+		err = (*p.DB).OTRSEventEnded(eventDBID)
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't end event with ID '%v' - '%v'", eventDBID, err))
+		}
+	default:
+		nextActivation := time.Now().Add(SchedulerInterval)
+		p.Log.Error(fmt.Sprintf("Can't get ticket '%v' - '%v'. Retry event with ID '%v' at '%v'", ticketID, err, eventDBID, nextActivation))
+		err = (*p.DB).OTRSEventPostpone(eventDBID, nextActivation.Unix())
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't postpone event with ID '%v' - '%v'", eventDBID, err))
+		}
 	}
+}
 
-	// Generate send message tasks.
-	for _, user := range userList {
-		go sendMessage(user, &message, db, tBot, logger)
+// Return team bounded with client. Empty team means message for all users.
+// Return false if team can't be found because of error.
+func (p *Processor) getTeam(client string) (string, bool) {
+	p.Log.Debug(fmt.Sprintf("Get bounded team for client '%v'", client))
+	clientModule := *p.Client
+	team, err := clientModule.GetTeamByClient(client)
+	switch err {
+	case nil:
+		return team, true
+	case myErrors.ErrNoTeamBounded:
+		return "", true
+	case myErrors.ErrClientNotExists:
+		p.Log.Debug(fmt.Sprintf("Client '%v' not found. Add into DB", client))
+		err := clientModule.AddClient(client)
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't add new client '%v'", client))
+			return "", false
+		}
+		return "", true
+	case myErrors.ErrMoreThenOneTeamBounded:
+		p.Log.Error(fmt.Sprintf("With client '%v' bound more than one team. Send message to all users.", client))
+		return "", true
+	default:
+		p.Log.Error(fmt.Sprintf("While get bounded team for client '%v' - '%v'", client, err))
+		return "", false
 	}
 }
 
-func sendMessage(userID int64, message *string, db *DBProvider.DBProvider, tBot *TelegramProvider.TelegramProvider, logger logger.Logger) {
-	logger.Debug(fmt.Sprintf("Start sending message to telegram user '%v'", userID))
+func (p *Processor) finishEventProcessing(reason string, eventID int64, ticketDetails OTRSProvider.TicketOTRS) {
+	p.Log.Debug(fmt.Sprintf("Event with ID '%v' finished. Reason '%v'", eventID, reason))
 
-	// Get user telegram ID
-	telegramID, err := (*db).BotUserGetTelegramIDByID(userID)
+	// Set event as finished.
+	err := (*p.DB).OTRSEventEnded(eventID)
 	if err != nil {
-		logger.Error(fmt.Sprintf("While get user's telegram ID - '%v'. Message not sent or scheduled.", err))
+		p.Log.Debug(fmt.Sprintf("Can't finish event with ID '%v' and reason '%v' - '%v'", eventID, reason, err))
+		// TODO - add logic for close program
 		return
 	}
+	p.recordHistory(eventID, DBProvider.HistoryAction, ticketDetails.Owner, reason)
 
-	// Schedule message.
-	messageID, err := (*db).MessageListNewMessage("Telegram", telegramID, *message)
+	// Any lookup error means no team. Client added into DB by previous event processing.
+	team, err := (*p.Client).GetTeamByClient(ticketDetails.CustomerID)
 	if err != nil {
-		logger.Error(fmt.Sprintf("While scheduling message - '%v'. Message not sent or scheduled.", err))
+		team = ""
+	}
+	p.notifyWebhooks(eventID, WebhookProvider.KindClosure, reason, team, ticketDetails)
+
+	if !p.NotifyClosure {
 		return
 	}
+	templateKind := Formatter.KindClosed
+	switch reason {
+	case "lock":
+		templateKind = Formatter.KindTaken
+	case "merged":
+		templateKind = Formatter.KindMerged
+	}
+	data := Formatter.NewTemplateData(ticketDetails, p.Log)
+	data.Team = team
+	data.Reason = reason
+	d := delivery{
+		Message:  p.renderMessage(templateKind, data),
+		Priority: TelegramProvider.PriorityReminder,
+		Critical: p.isCritical(ticketDetails.Priority),
+		Instance: ticketDetails.Instance,
+		EventID:  eventID,
+		Kind:     WebhookProvider.KindClosure,
+	}
+	if team != "" {
+		go p.sendMessageForByTeam(team, d)
+	} else {
+		go p.sendMessageForAllBotUsers(d)
+	}
+}
 
-	// Send message into social media.
-	err = (*tBot).SendEventMessage(userID, *message)
-	if err != nil {
-		logger.Error(fmt.Sprintf("While send message - '%v'. Try again in 1 minute.", err))
-		err = (*tBot).SendEventMessage(userID, *message)
-		if err != nil {
-			logger.Error(fmt.Sprintf("While retry send message - '%v'. Message not sent. Retry in next retry for all failed messages", err))
-			err = (*tBot).SendEventMessage(userID, *message)
-			return
-		}
+// Use escalation template for long-lived tickets if enabled.
+func (p *Processor) reminderKind(data Formatter.TemplateData) string {
+	if p.EscalationLevel > 0 && data.EscalationLevel >= p.EscalationLevel {
+		return Formatter.KindEscalation
 	}
+	return Formatter.KindReminder
+}
 
-	// Finish message processing.
-	logger.Debug(fmt.Sprintf("Message to telegram user '%v' sucessfully sent", userID))
-	err = (*db).MessageListMarkDelivered(messageID)
+// Render message by template in every language. Templates validated on load so error is unexpected,
+// but alert must not be lost, so send minimal plain text instead.
+func (p *Processor) renderMessage(templateKind string, data Formatter.TemplateData) *Formatter.LocalisedMessage {
+	message, err := Formatter.RenderLocalised(templateKind, data)
 	if err != nil {
-		logger.Error(fmt.Sprintf("While mark message as delivered - '%v'. Message can be sent twice.", err))
-		return
+		p.Log.Error(fmt.Sprintf("Can't render '%v' template for ticket '%v' - '%v'", templateKind, data.TicketNumber, err))
+		plainText := fmt.Sprint(templateKind, " ", data.TicketNumber, "\n", data.Title, "\n", data.URL)
+		return Formatter.FixedMessage(Formatter.PlainMessage(plainText))
 	}
+	return message
 }
 
-func finishEventProcessing(reason string, eventID int64, db *DBProvider.DBProvider, logger logger.Logger) {
-	logger.Debug(fmt.Sprintf("Event with ID '%v' finished. Reason '%v'", eventID, reason))
-
-	// Set event as finished.
-	err := (*db).OTRSEventEnded(eventID)
-	if err != nil {
-		logger.Debug(fmt.Sprintf("Can't finish event with ID '%v' and reason '%v' - '%v'", eventID, reason, err))
-		// TODO - add logic for close program
+// Pass notification to outbound webhooks if module configured.
+func (p *Processor) notifyWebhooks(eventID int64, kind, reason, team string, ticketDetails OTRSProvider.TicketOTRS) {
+	if p.Webhook == nil {
+		return
 	}
+	p.recordHistory(eventID, DBProvider.HistoryNotification, "webhook", kind)
+	(*p.Webhook).Notify(WebhookProvider.Notification{
+		Kind:   kind,
+		Reason: reason,
+		Team:   team,
+		Ticket: ticketDetails,
+		Time:   time.Now(),
+	})
 }
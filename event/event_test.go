@@ -0,0 +1,74 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider/fakeOTRS"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+)
+
+// Create event for ticket, request ticket from fake OTRS and handle error like event processing.
+func handleTicketError(t *testing.T, server *fakeOTRS.Server, ticketID int64) (*Processor, *DBProvider.DBProvider) {
+	t.Helper()
+	poller, db := newTestPoller(t, server)
+	err := (*db).OTRSEventCreateNew("test", "new", "", ticketID)
+	if err != nil {
+		t.Fatalf("create event - %v", err)
+	}
+	eventList, err := (*db).OTRSEventGetAllActive()
+	if err != nil || len(eventList) != 1 {
+		t.Fatalf("get created event - %v, %v", eventList, err)
+	}
+
+	_, err = poller.OTRS.GetTicketDetails("", fmt.Sprint(ticketID))
+	if err == nil {
+		t.Fatal("ticket request succeeded")
+	}
+	poller.Processor.handleOTRSError(eventList[0].ID, "", fmt.Sprint(ticketID), err)
+	return poller.Processor, db
+}
+
+func TestAccessDeniedPostponesEvent(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	// Ticket found by search, but not available to API user.
+	server.AddTicket("1", OTRSProvider.TicketOTRS{TicketNumber: "1000001"})
+	server.Fail(fakeOTRS.Failure{ErrorCode: "TicketGet.AccessDenied"})
+
+	before := time.Now()
+	processor, db := handleTicketError(t, server, 1)
+
+	if count := countEvents(t, db); count != 1 {
+		t.Fatalf("%v active events, want 1", count)
+	}
+	// Event due only after retry delay.
+	_, _, _, err := (*db).OTRSEventClaim("test", before.Add(time.Hour).Unix(), before.Add(AccessDeniedRetry-time.Minute).Unix())
+	if !errors.Is(err, myErrors.ErrNoActiveEvents) {
+		t.Errorf("event claimed before retry delay - %v", err)
+	}
+	_, _, _, err = (*db).OTRSEventClaim("test", before.Add(time.Hour).Unix(), before.Add(AccessDeniedRetry+time.Minute).Unix())
+	if err != nil {
+		t.Errorf("event not claimed after retry delay - %v", err)
+	}
+	if stats := processor.Stats(); stats.AccessDenied != 1 {
+		t.Errorf("access denied counter %v, want 1", stats.AccessDenied)
+	}
+}
+
+func TestMissingTicketEndsEvent(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	processor, db := handleTicketError(t, server, 404)
+
+	if count := countEvents(t, db); count != 0 {
+		t.Errorf("%v active events, want 0", count)
+	}
+	if stats := processor.Stats(); stats.AccessDenied != 0 {
+		t.Errorf("access denied counter %v, want 0", stats.AccessDenied)
+	}
+}
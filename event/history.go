@@ -0,0 +1,58 @@
+package event
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+)
+
+// Append entry into event history. History is informational, so error only logged.
+func (p *Processor) recordHistory(eventID int64, kind, actor, details string) {
+	if eventID == 0 {
+		return
+	}
+	err := (*p.DB).EventHistoryAdd(eventID, kind, actor, details)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Can't add '%v' history entry for event '%v' - '%v'", kind, eventID, err))
+	}
+}
+
+// Save ticket snapshot of event and record fields changed since previous processing.
+// Changes before first processing not recorded.
+func (p *Processor) recordChanges(eventID int64, ticketDetails OTRSProvider.TicketOTRS) {
+	current := DBProvider.TicketSnapshot{
+		Lock:       ticketDetails.Lock,
+		State:      ticketDetails.State,
+		Owner:      ticketDetails.Owner,
+		Priority:   ticketDetails.Priority,
+		CustomerID: ticketDetails.CustomerID,
+	}
+	previous, ok, err := (*p.DB).OTRSEventSwapSnapshot(eventID, current)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Can't save ticket snapshot for event '%v' - '%v'", eventID, err))
+		return
+	}
+	if !ok {
+		return
+	}
+	for _, field := range snapshotDiff(previous, current) {
+		p.recordHistory(eventID, DBProvider.HistoryChange, ticketDetails.Owner, field)
+	}
+}
+
+// Return changed fields in "field: old -> new" form.
+func snapshotDiff(previous, current DBProvider.TicketSnapshot) []string {
+	fields := []struct{ name, previous, current string }{
+		{"lock", previous.Lock, current.Lock},
+		{"state", previous.State, current.State},
+		{"owner", previous.Owner, current.Owner},
+		{"priority", previous.Priority, current.Priority},
+	}
+	diff := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field.previous != field.current {
+			diff = append(diff, fmt.Sprintf("%v: %v -> %v", field.name, field.previous, field.current))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,71 @@
+package event
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/config"
+	"time"
+)
+
+// Escalation policy behavior outside working hours.
+const (
+	OutsideHoursNotify   string = "notify"   // Notify as usual.
+	OutsideHoursPostpone string = "postpone" // Postpone notification till start of working hours.
+	OutsideHoursOnDuty   string = "onDuty"   // Notify only on-duty chats.
+)
+
+const DefaultReminderInterval time.Duration = 5 * time.Minute
+
+// Decision made by escalation policy for current activation.
+type activationPlan struct {
+	Notify         bool      // Send notification now.
+	OnDutyChats    []int64   // If not empty notification sent only to these chats.
+	NextActivation time.Time // Next event activation.
+}
+
+// Evaluate escalation policy for ticket team and priority at provided time.
+func (p *Processor) planActivation(team, priority string, now time.Time) activationPlan {
+	policy := p.findPolicy(team, priority)
+	interval := DefaultReminderInterval
+	if policy.ReminderInterval > 0 {
+		interval = time.Duration(policy.ReminderInterval) * time.Minute
+	}
+	plan := activationPlan{Notify: true, NextActivation: now.Add(interval)}
+
+	if p.Calendar == nil || (*p.Calendar).IsWorkingTime(team, now) {
+		return plan
+	}
+	switch policy.OutsideHours {
+	case OutsideHoursPostpone:
+		plan.Notify = false
+		plan.NextActivation = (*p.Calendar).NextWorkingTime(team, now)
+	case OutsideHoursOnDuty:
+		plan.OnDutyChats = (*p.Calendar).OnDutyChats(team)
+		if len(plan.OnDutyChats) == 0 {
+			p.Log.Warning(fmt.Sprintf("Policy '%v' require on-duty chats but no chats for team '%v'. Notify as usual.", policy.Name, team))
+		}
+	}
+	return plan
+}
+
+// Return first policy matching team and priority. Empty policy notify as usual.
+func (p *Processor) findPolicy(team, priority string) config.EscalationPolicyConf {
+	for _, policy := range p.Policies {
+		if isAllowed(policy.Teams, team) && isAllowed(policy.Priorities, priority) {
+			return policy
+		}
+	}
+	return config.EscalationPolicyConf{}
+}
+
+// Empty filter allow any value.
+func isAllowed(filter []string, value string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, allowed := range filter {
+		if allowed == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPollOverlap time.Duration = time.Minute
+	PollLimit          int           = 500 // Maximum tickets found by one search. More tickets between polls searched by pages.
+
+	TypeNewTicket string = "newticket" // Type of events for new tickets. Same for invoker and poller so ticket not duplicated.
+	channelPoller string = "poller"
+)
+
+// Periodically search OTRS instance for new tickets and create events for tickets not known yet.
+// Watermark saved after each successful poll, so tickets created while bot stopped found after start.
+type Poller struct {
+	Instance  string // OTRS instance name.
+	OTRS      *OTRSProvider.Instances
+	DB        *DBProvider.DBProvider
+	Processor *Processor
+	Log       logger.Logger
+
+	Interval time.Duration // Interval between polls.
+	Overlap  time.Duration // Searched before watermark to cover clock skew between bot and OTRS.
+	Lookback time.Duration // Searched back on first poll without saved watermark.
+}
+
+// Poll OTRS periodically until context done.
+func (pl *Poller) Run(ctx context.Context) error {
+	pl.Log.Info(fmt.Sprintf("Poll OTRS instance '%v' for new tickets every '%v'", pl.Instance, pl.Interval))
+	pl.Poll()
+	ticker := time.NewTicker(pl.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pl.Poll()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Search tickets created since watermark, create events for unknown tickets and move watermark to poll start.
+// Return false if poll failed. Watermark kept on failure so next poll searches same period again.
+func (pl *Poller) Poll() bool {
+	started := time.Now()
+	watermarkUnix, err := (*pl.DB).PollerStateGetWatermark(pl.Instance)
+	if err != nil {
+		pl.Log.Error(fmt.Sprintf("Can't get watermark of OTRS instance '%v' - '%v'", pl.Instance, err))
+		return false
+	}
+	watermark := time.Unix(watermarkUnix, 0)
+	if watermarkUnix == 0 {
+		watermark = started.Add(-pl.Lookback)
+	}
+	since := watermark.Add(-pl.Overlap)
+
+	otrs, err := pl.OTRS.Get(pl.Instance)
+	if err != nil {
+		pl.Log.Error(fmt.Sprintf("Can't poll - '%v'", err))
+		return false
+	}
+	ticketIDList, err := pl.searchCreated(otrs, since)
+	if err != nil {
+		pl.Log.Error(fmt.Sprintf("Can't search tickets created in OTRS instance '%v' since '%v' - '%v'", pl.Instance, since, err))
+		return false
+	}
+
+	created := 0
+	for _, ticketID := range ticketIDList {
+		ticketIDint, err := strconv.ParseInt(ticketID, 10, 64)
+		if err != nil {
+			pl.Log.Warning(fmt.Sprintf("Skip invalid ticket ID '%v' found in OTRS instance '%v'", ticketID, pl.Instance))
+			continue
+		}
+		_, isCreated, err := (*pl.DB).OTRSEventCreateOnce(channelPoller, TypeNewTicket, pl.Instance, ticketIDint, "")
+		if err != nil {
+			pl.Log.Error(fmt.Sprintf("Can't create event for ticket '%v' - '%v'", ticketID, err))
+			return false
+		}
+		if !isCreated {
+			continue
+		}
+		created++
+		pl.Log.Info(fmt.Sprintf("Ticket '%v' of OTRS instance '%v' found by poll", ticketID, pl.Instance))
+	}
+
+	// Details of changed tickets can be cached before change.
+	if cache, ok := otrs.(OTRSProvider.TicketCache); ok {
+		changedList, err := otrs.SearchTicketIDs(OTRSProvider.SearchCriteria{ChangedAfter: since, Limit: PollLimit})
+		if err != nil {
+			pl.Log.Warning(fmt.Sprintf("Can't search tickets changed in OTRS instance '%v' since '%v' - '%v'", pl.Instance, since, err))
+		}
+		for _, ticketID := range changedList {
+			cache.Invalidate(ticketID, "")
+		}
+	}
+
+	err = (*pl.DB).PollerStateSetWatermark(pl.Instance, started.Unix())
+	if err != nil {
+		pl.Log.Error(fmt.Sprintf("Can't save watermark of OTRS instance '%v' - '%v'", pl.Instance, err))
+		return false
+	}
+	pl.Log.Debug(fmt.Sprintf("Poll of OTRS instance '%v' found '%v' tickets, '%v' new", pl.Instance, len(ticketIDList), created))
+
+	if created > 0 {
+		pl.Processor.ProcessEvent()
+	}
+	return true
+}
+
+// Return IDs of tickets created after provided time. Search result limited by PollLimit, so full result
+// searched by pages: next page contains tickets created before oldest ticket of previous page.
+// Tickets of page boundary second found twice, so result contains unique IDs.
+func (pl *Poller) searchCreated(otrs OTRSProvider.OTRSProvider, since time.Time) ([]string, error) {
+	criteria := OTRSProvider.SearchCriteria{CreatedAfter: since, Limit: PollLimit}
+	ticketIDList := make([]string, 0, PollLimit)
+	found := make(map[string]bool, PollLimit)
+	for {
+		page, err := otrs.SearchTicketIDs(criteria)
+		if err != nil {
+			return nil, err
+		}
+		for _, ticketID := range page {
+			if !found[ticketID] {
+				found[ticketID] = true
+				ticketIDList = append(ticketIDList, ticketID)
+			}
+		}
+		if len(page) < PollLimit {
+			return ticketIDList, nil
+		}
+
+		// Search result sorted by age, so oldest ticket of page is first or last.
+		oldest, err := pl.oldestCreated(otrs, page[0], page[len(page)-1])
+		if err != nil {
+			return nil, err
+		}
+		if !criteria.CreatedBefore.IsZero() && !oldest.Before(criteria.CreatedBefore) {
+			// Whole page created in one second. Rest of tickets of this second can't be found.
+			oldest = criteria.CreatedBefore.Add(-time.Second)
+			pl.Log.Warning(fmt.Sprintf("More than '%v' tickets created in OTRS instance '%v' at '%v'. Some of them missed", PollLimit, pl.Instance, criteria.CreatedBefore))
+		}
+		if !oldest.After(since) {
+			return ticketIDList, nil
+		}
+		pl.Log.Debug(fmt.Sprintf("Search next page of tickets created in OTRS instance '%v' before '%v'", pl.Instance, oldest))
+		criteria.CreatedBefore = oldest
+	}
+}
+
+// Return earliest creation time of two tickets.
+func (pl *Poller) oldestCreated(otrs OTRSProvider.OTRSProvider, firstID, lastID string) (time.Time, error) {
+	var oldest time.Time
+	for _, ticketID := range []string{firstID, lastID} {
+		ticket, err := otrs.GetTicketDetails(ticketID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ticket.CreatedTime.IsZero() {
+			return time.Time{}, fmt.Errorf("%w: unknown creation time '%v' of ticket '%v'", myErrors.ErrOTRSServerError, ticket.Created, ticketID)
+		}
+		if oldest.IsZero() || ticket.CreatedTime.Before(oldest) {
+			oldest = ticket.CreatedTime
+		}
+	}
+	return oldest, nil
+}
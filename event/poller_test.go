@@ -0,0 +1,134 @@
+package event
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider/SQLite3"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider/basicOTRS"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider/fakeOTRS"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+)
+
+// Drop all messages.
+type quietLogger struct{}
+
+func (ql quietLogger) SetModuleName(name string) logger.Logger { return ql }
+func (quietLogger) Error(message string)                       {}
+func (quietLogger) Warning(message string)                     {}
+func (quietLogger) Info(message string)                        {}
+func (quietLogger) Debug(message string)                       {}
+
+// Open database in temporary directory. Several calls with same directory share database.
+func openDB(t *testing.T, directory string) *DBProvider.DBProvider {
+	t.Helper()
+	var db DBProvider.DBProvider = &SQLite3.DB{}
+	err := db.Initialise(quietLogger{}, directory)
+	if err != nil {
+		t.Fatalf("initialise database - %v", err)
+	}
+	return &db
+}
+
+// Return poller of fake OTRS default instance.
+func newTestPoller(t *testing.T, server *fakeOTRS.Server) (*Poller, *DBProvider.DBProvider) {
+	t.Helper()
+	conf := server.Conf()
+	conf.API.AuthMode = basicOTRS.AuthModeBody
+	provider := &basicOTRS.BasicOTRS{}
+	provider.Initialise(quietLogger{}, conf)
+	provider.RetryInterval = time.Millisecond
+	instances := OTRSProvider.NewInstances()
+	instances.Add("", provider)
+
+	db := openDB(t, t.TempDir())
+	return &Poller{
+		OTRS:      instances,
+		DB:        db,
+		Processor: &Processor{DB: db, Log: quietLogger{}, Owner: "test", Lease: DefaultLease},
+		Log:       quietLogger{},
+		Overlap:   DefaultPollOverlap,
+		Lookback:  24 * time.Hour,
+	}, db
+}
+
+// Add tickets created one by one with interval before now.
+func addTickets(server *fakeOTRS.Server, count int, interval time.Duration) {
+	now := time.Now().UTC()
+	for n := 1; n <= count; n++ {
+		created := now.Add(-time.Duration(count-n+1) * interval)
+		server.AddTicket(fmt.Sprint(n), OTRSProvider.TicketOTRS{
+			TicketNumber: fmt.Sprint(1000000 + n),
+			Created:      created.Format(OTRSProvider.TimeLayout),
+		})
+	}
+}
+
+func countEvents(t *testing.T, db *DBProvider.DBProvider) int {
+	t.Helper()
+	eventList, err := (*db).OTRSEventGetAllActive()
+	if err != nil {
+		t.Fatalf("get events - %v", err)
+	}
+	return len(eventList)
+}
+
+func TestPollFindsAllTicketsBeyondLimit(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	addTickets(server, 2*PollLimit+37, 2*time.Second)
+	poller, db := newTestPoller(t, server)
+
+	if !poller.Poll() {
+		t.Fatal("poll failed")
+	}
+
+	if events := countEvents(t, db); events != 2*PollLimit+37 {
+		t.Errorf("%v events created, want %v", events, 2*PollLimit+37)
+	}
+	watermark, err := (*db).PollerStateGetWatermark("")
+	if err != nil || watermark == 0 {
+		t.Errorf("watermark %v not saved - %v", watermark, err)
+	}
+}
+
+func TestPollPageBoundaryInOneSecond(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	// Several tickets in every second, page boundary falls inside second.
+	addTickets(server, PollLimit+100, 250*time.Millisecond)
+	poller, db := newTestPoller(t, server)
+
+	if !poller.Poll() {
+		t.Fatal("poll failed")
+	}
+
+	if events := countEvents(t, db); events != PollLimit+100 {
+		t.Errorf("%v events created, want %v", events, PollLimit+100)
+	}
+}
+
+func TestPollKeepsWatermarkOnFailure(t *testing.T) {
+	server := fakeOTRS.NewServer()
+	defer server.Close()
+	addTickets(server, PollLimit+1, time.Second)
+	poller, db := newTestPoller(t, server)
+	// First page and its oldest ticket found, search of second page fails after all retries.
+	failures := []fakeOTRS.Failure{{}, {}, {}}
+	for n := 0; n < 10; n++ {
+		failures = append(failures, fakeOTRS.Failure{Status: 500})
+	}
+	server.Fail(failures...)
+
+	if poller.Poll() {
+		t.Fatal("poll succeeded with failed page")
+	}
+
+	watermark, err := (*db).PollerStateGetWatermark("")
+	if err != nil || watermark != 0 {
+		t.Errorf("watermark %v moved after failed poll - %v", watermark, err)
+	}
+}
@@ -0,0 +1,226 @@
+package event
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/CalendarProvider"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+	"time"
+)
+
+// Message with delivery options same for all recipients.
+type delivery struct {
+	Message    *Formatter.LocalisedMessage
+	Priority   int    // Send queue priority.
+	Critical   bool   // Delivered even in quiet hours and do-not-disturb mode.
+	Deferrable bool   // Delivered after quiet hours instead of drop.
+	Reminder   bool   // Individual reminder. Not delivered to users who chose digest only.
+	Digest     bool   // Team digest. Delivered only to users who chose digest.
+	Instance   string // OTRS instance of ticket. Empty for digest.
+	EventID    int64  // Event recorded in history for delivered message. Zero for digest.
+	Kind       string // Notification kind recorded in history.
+}
+
+func (p *Processor) sendMessageForAllBotUsers(d delivery) {
+	p.Log.Debug(fmt.Sprintf("Start sending sequense for message:\n'%v'", d.Message.Default().PlainText))
+
+	// Get all users by subscription (all subscriptions).
+	userList, err := (*p.DB).SubscriptionListGetActiveByMultipleSubscription(teamList)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Whle get users by subscription - '%v'", err))
+		return
+	}
+	if len(userList) < 1 {
+		p.Log.Warning(fmt.Sprintf("No users for all subscriptions"))
+		return
+	}
+
+	// Generate send message tasks.
+	for _, user := range userList {
+		go p.sendMessage(user, d)
+	}
+}
+
+func (p *Processor) sendMessageForByTeam(team string, d delivery) {
+	p.Log.Debug(fmt.Sprintf("Start sending sequense for message:\n'%v'", d.Message.Default().PlainText))
+
+	// Get all users by subscription.
+	userList, err := (*p.DB).SubscriptionListGetActiveBySubscription(team)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Whle get users by subscription - '%v'", err))
+		return
+	}
+	if len(userList) < 1 {
+		p.Log.Warning(fmt.Sprintf("No users for '%v' subscription", team))
+		return
+	}
+
+	// Generate send message tasks.
+	for _, user := range userList {
+		go p.sendMessage(user, d)
+	}
+}
+
+func (p *Processor) sendMessageForChats(chatList []int64, d delivery) {
+	p.Log.Debug(fmt.Sprintf("Start sending sequense to chats %v for message:\n'%v'", chatList, d.Message.Default().PlainText))
+
+	// Generate send message tasks.
+	for _, telegramID := range chatList {
+		go p.sendMessageToChat(telegramID, d)
+	}
+}
+
+func (p *Processor) sendMessage(userID int64, d delivery) {
+	p.Log.Debug(fmt.Sprintf("Start sending message to telegram user '%v'", userID))
+
+	// Get user telegram ID
+	telegramID, err := (*p.DB).BotUserGetTelegramIDByID(userID)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("While get user's telegram ID - '%v'. Message not sent or scheduled.", err))
+		return
+	}
+
+	p.sendMessageToChat(telegramID, d)
+}
+
+func (p *Processor) sendMessageToChat(telegramID int64, d delivery) {
+	// Choose message in user language and timezone. Defaults used if not chosen.
+	settings, err := (*p.DB).BotUserGetSettings(telegramID)
+	if err != nil {
+		p.Log.Warning(fmt.Sprintf("While get user's settings - '%v'. Send in default language.", err))
+	}
+	var location *time.Location
+	if settings.Timezone != "" {
+		location, err = time.LoadLocation(settings.Timezone)
+		if err != nil {
+			p.Log.Warning(fmt.Sprintf("Can't load user's timezone '%v' - '%v'. Use OTRS timezone.", settings.Timezone, err))
+			location = nil
+		}
+	}
+	message, err := d.Message.For(settings.Language, location)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("While render message for user - '%v'. Send in default language.", err))
+	}
+
+	// Skip reminders or digests by user notification mode.
+	switch settings.NotificationMode {
+	case DBProvider.NotificationModeDigest:
+		if d.Reminder {
+			p.Log.Debug(fmt.Sprintf("Reminder to telegram chat '%v' skipped. Chat receives digest only.", telegramID))
+			return
+		}
+	case DBProvider.NotificationModeBoth:
+	default:
+		if d.Digest {
+			p.Log.Debug(fmt.Sprintf("Digest to telegram chat '%v' skipped. Chat receives individual reminders only.", telegramID))
+			return
+		}
+	}
+
+	// Defer or drop non-critical message in quiet hours.
+	if !d.Critical {
+		quietLocation := p.Location
+		if location != nil {
+			quietLocation = location
+		}
+		until, quiet := quietUntil(settings, time.Now(), quietLocation)
+		switch {
+		case quiet && d.Deferrable:
+			_, err = (*p.DB).MessageListNewDeferredMessage("Telegram", telegramID, message.PlainText, d.Instance, until.Unix())
+			if err != nil {
+				p.Log.Error(fmt.Sprintf("While defer message for chat '%v' - '%v'. Message not sent or scheduled.", telegramID, err))
+				return
+			}
+			p.Log.Debug(fmt.Sprintf("Message to telegram chat '%v' deferred till '%v'", telegramID, until))
+			p.recordHistory(d.EventID, DBProvider.HistoryNotification, fmt.Sprint(telegramID), fmt.Sprint(d.Kind, " deferred"))
+			return
+		case quiet:
+			p.Log.Debug(fmt.Sprintf("Message to telegram chat '%v' dropped. Quiet till '%v'", telegramID, until))
+			return
+		}
+	}
+
+	// Schedule message.
+	messageID, err := (*p.DB).MessageListNewMessage("Telegram", telegramID, message.PlainText, d.Instance)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("While scheduling message - '%v'. Message not sent or scheduled.", err))
+		return
+	}
+
+	// Send message into social media.
+	err = (*p.Telegram).SendEventMessage(telegramID, message, d.Priority)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("While send message - '%v'. Try again in 1 minute.", err))
+		err = (*p.Telegram).SendEventMessage(telegramID, message, d.Priority)
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("While retry send message - '%v'. Message not sent. Retry in next retry for all failed messages", err))
+			err = (*p.Telegram).SendEventMessage(telegramID, message, d.Priority)
+			return
+		}
+	}
+
+	// Finish message processing.
+	p.Log.Debug(fmt.Sprintf("Message to telegram chat '%v' sucessfully sent", telegramID))
+	p.recordHistory(d.EventID, DBProvider.HistoryNotification, fmt.Sprint(telegramID), d.Kind)
+	err = (*p.DB).MessageListMarkDelivered(messageID)
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("While mark message as delivered - '%v'. Message can be sent twice.", err))
+		return
+	}
+}
+
+// Send deferred messages which quiet hours passed. Deferred messages sent without markup.
+// Messages claimed like events, so every message sent by one instance.
+func (p *Processor) deliverDeferred() {
+	now := time.Now()
+	messageList, err := (*p.DB).MessageListClaimDeferred("Telegram", p.Owner, now.Add(p.Lease).Unix(), now.Add(-p.Standby).Unix())
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Can't get deferred messages - '%v'", err))
+		return
+	}
+
+	for _, message := range messageList {
+		err = (*p.Telegram).SendEventMessage(message.ChatID, Formatter.PlainMessage(message.Text), TelegramProvider.PriorityAlert)
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("While send deferred message '%v' - '%v'. Retry after lease expires.", message.ID, err))
+			continue
+		}
+		err = (*p.DB).MessageListMarkDelivered(message.ID)
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("While mark deferred message '%v' as delivered - '%v'. Message can be sent twice.", message.ID, err))
+		}
+	}
+}
+
+// Check if ticket priority ignores quiet hours and do-not-disturb mode.
+func (p *Processor) isCritical(priority string) bool {
+	for _, critical := range p.CriticalPriorities {
+		if critical == priority {
+			return true
+		}
+	}
+	return false
+}
+
+// Return end of do-not-disturb mode or quiet hours for user at provided time.
+// Quiet hours evaluated in provided location. Return false if user can be notified now.
+func quietUntil(settings DBProvider.BotUserSettings, now time.Time, location *time.Location) (time.Time, bool) {
+	var until time.Time
+	if settings.DNDUntil > now.Unix() {
+		until = time.Unix(settings.DNDUntil, 0)
+	}
+	if settings.QuietHours != "" {
+		start, end, err := CalendarProvider.ParseDailyRange(settings.QuietHours)
+		if err == nil {
+			if location == nil {
+				location = time.UTC
+			}
+			quietEnd, ok := CalendarProvider.DailyRangeEnd(now.In(location), start, end)
+			if ok && quietEnd.After(until) {
+				until = quietEnd
+			}
+		}
+	}
+	return until, !until.IsZero()
+}
@@ -0,0 +1,95 @@
+package event
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+)
+
+func TestQuietUntil(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(day, hour, minute int) time.Time { return time.Date(2024, 6, day, hour, minute, 0, 0, time.UTC) }
+	night := DBProvider.BotUserSettings{QuietHours: "23:00-07:00"}
+
+	cases := []struct {
+		name     string
+		settings DBProvider.BotUserSettings
+		now      time.Time
+		location *time.Location
+		want     time.Time // Zero if user can be notified.
+	}{
+		// Range crossing midnight.
+		{"before midnight", night, utc(1, 23, 30), time.UTC, utc(2, 7, 0)},
+		{"after midnight", night, utc(2, 2, 0), time.UTC, utc(2, 7, 0)},
+		{"start inclusive", night, utc(1, 23, 0), time.UTC, utc(2, 7, 0)},
+		{"end exclusive", night, utc(2, 7, 0), time.UTC, time.Time{}},
+		{"daytime", night, utc(2, 12, 0), time.UTC, time.Time{}},
+		{"range within day", DBProvider.BotUserSettings{QuietHours: "13:00-14:00"}, utc(2, 13, 30), time.UTC, utc(2, 14, 0)},
+
+		// Invalid ranges ignored.
+		{"equal start and end", DBProvider.BotUserSettings{QuietHours: "07:00-07:00"}, utc(2, 7, 0), time.UTC, time.Time{}},
+		{"no end", DBProvider.BotUserSettings{QuietHours: "23:00"}, utc(1, 23, 30), time.UTC, time.Time{}},
+		{"invalid hour", DBProvider.BotUserSettings{QuietHours: "25:00-07:00"}, utc(2, 2, 0), time.UTC, time.Time{}},
+		{"three parts", DBProvider.BotUserSettings{QuietHours: "23:00-07:00-08:00"}, utc(2, 2, 0), time.UTC, time.Time{}},
+		{"text", DBProvider.BotUserSettings{QuietHours: "night"}, utc(2, 2, 0), time.UTC, time.Time{}},
+
+		// Quiet hours evaluated in user location. UTC if not provided.
+		{"user timezone", DBProvider.BotUserSettings{QuietHours: "09:00-18:00"}, utc(1, 6, 0), moscow, time.Date(2024, 6, 1, 18, 0, 0, 0, moscow)},
+		{"same instant in UTC", DBProvider.BotUserSettings{QuietHours: "09:00-18:00"}, utc(1, 6, 0), time.UTC, time.Time{}},
+		{"no location", night, utc(1, 23, 30), nil, utc(2, 7, 0)},
+
+		// Wall clock end kept on daylight saving time change.
+		{"spring forward", night, time.Date(2024, 3, 30, 23, 30, 0, 0, berlin), berlin, time.Date(2024, 3, 31, 7, 0, 0, 0, berlin)},
+		{"fall back", night, time.Date(2024, 10, 26, 23, 30, 0, 0, berlin), berlin, time.Date(2024, 10, 27, 7, 0, 0, 0, berlin)},
+
+		// Do-not-disturb mode.
+		{"do not disturb", DBProvider.BotUserSettings{DNDUntil: utc(2, 15, 0).Unix()}, utc(2, 12, 0), time.UTC, utc(2, 15, 0)},
+		{"do not disturb expired", DBProvider.BotUserSettings{DNDUntil: utc(2, 11, 0).Unix()}, utc(2, 12, 0), time.UTC, time.Time{}},
+		{"do not disturb ends now", DBProvider.BotUserSettings{DNDUntil: utc(2, 12, 0).Unix()}, utc(2, 12, 0), time.UTC, time.Time{}},
+		{"do not disturb after quiet hours", DBProvider.BotUserSettings{QuietHours: "23:00-07:00", DNDUntil: utc(2, 9, 0).Unix()}, utc(2, 2, 0), time.UTC, utc(2, 9, 0)},
+		{"do not disturb within quiet hours", DBProvider.BotUserSettings{QuietHours: "23:00-07:00", DNDUntil: utc(2, 3, 0).Unix()}, utc(2, 2, 0), time.UTC, utc(2, 7, 0)},
+		{"expired do not disturb in quiet hours", DBProvider.BotUserSettings{QuietHours: "23:00-07:00", DNDUntil: utc(2, 1, 0).Unix()}, utc(2, 2, 0), time.UTC, utc(2, 7, 0)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			until, quiet := quietUntil(c.settings, c.now, c.location)
+			if quiet != !c.want.IsZero() {
+				t.Fatalf("quiet %v until '%v', want until '%v'", quiet, until, c.want)
+			}
+			if !until.Equal(c.want) {
+				t.Errorf("quiet until '%v', want '%v'", until, c.want)
+			}
+		})
+	}
+}
+
+func TestQuietUntilDSTDuration(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := DBProvider.BotUserSettings{QuietHours: "23:00-07:00"}
+	for name, c := range map[string]struct {
+		now  time.Time
+		want time.Duration
+	}{
+		"spring forward": {time.Date(2024, 3, 30, 23, 0, 0, 0, berlin), 7 * time.Hour},
+		"fall back":      {time.Date(2024, 10, 26, 23, 0, 0, 0, berlin), 9 * time.Hour},
+		"regular night":  {time.Date(2024, 6, 1, 23, 0, 0, 0, berlin), 8 * time.Hour},
+	} {
+		t.Run(name, func(t *testing.T) {
+			until, _ := quietUntil(settings, c.now, berlin)
+			if duration := until.Sub(c.now); duration != c.want {
+				t.Errorf("quiet for '%v', want '%v'", duration, c.want)
+			}
+		})
+	}
+}
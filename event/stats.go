@@ -0,0 +1,33 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+)
+
+const StatsHandlerPath string = "/api/v1/metrics/events" // Route of event processing statistics on REST listener.
+
+// Event processing counters since start.
+type Stats struct {
+	AccessDenied uint64 `json:"accessDenied"` // Ticket requests denied by OTRS. Events of such tickets retried.
+}
+
+// Return event processing counters.
+func (p *Processor) Stats() Stats {
+	return Stats{
+		AccessDenied: atomic.LoadUint64(&p.accessDenied),
+	}
+}
+
+// Return HTTP handler with event processing statistics in JSON.
+func (p *Processor) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(p.Stats())
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("Can't write statistics - '%v'", err))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package event
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/CalendarProvider"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
+	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
+	"github.com/Sarraksh/otrs-echo-bot/report"
+	"time"
+)
+
+const DefaultSummaryTime time.Duration = 9 * time.Hour // Weekly summary time if not configured.
+
+const jobSummary string = "summary" // Scheduled job of weekly summary.
+
+// Weekly response time summary for team leads.
+type WeeklySummary struct {
+	Weekday   time.Weekday
+	Time      time.Duration      // Offset since midnight in processor location.
+	TeamLeads map[string][]int64 // Telegram chats by team.
+}
+
+// Send weekly response time summary to team leads if summary time passed after previous check.
+// First check only remember time, so summary not repeated on restart.
+// Instances sharing database claim summary, so summary sent once.
+func (p *Processor) sendDueSummary(now time.Time) {
+	if p.Summary == nil || len(p.Summary.TeamLeads) == 0 {
+		return
+	}
+	previousCheck := p.summaryCheck
+	p.summaryCheck = now
+	if previousCheck.IsZero() || !p.isSummaryTime(previousCheck, now) {
+		return
+	}
+	claimed, err := (*p.DB).ScheduledJobClaim(jobSummary, p.Owner, now.Unix(), previousCheck.Unix())
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Can't claim weekly summary - '%v'", err))
+		return
+	}
+	if !claimed {
+		p.Log.Debug("Weekly summary already sent by other instance")
+		return
+	}
+
+	location := p.Location
+	if location == nil {
+		location = time.UTC
+	}
+	today := now.In(location)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, location)
+	weekReport, err := report.Build(*p.DB, *p.Client, report.Criteria{
+		From:     to.AddDate(0, 0, -7),
+		To:       to,
+		GroupBy:  report.GroupTeam,
+		Location: location,
+	})
+	if err != nil {
+		p.Log.Error(fmt.Sprintf("Can't build weekly summary - '%v'", err))
+		return
+	}
+
+	for team, chatList := range p.Summary.TeamLeads {
+		for _, chatID := range chatList {
+			settings, err := (*p.DB).BotUserGetSettings(chatID)
+			if err != nil {
+				p.Log.Debug(fmt.Sprintf("Can't get settings of chat '%v' - '%v'. Send summary in default language.", chatID, err))
+			}
+			text := weekReport.Summary(settings.Language, location, team)
+			err = (*p.Telegram).SendEventMessage(chatID, Formatter.PlainMessage(text), TelegramProvider.PriorityReminder)
+			if err != nil {
+				p.Log.Error(fmt.Sprintf("Can't send weekly summary of team '%v' to chat '%v' - '%v'", team, chatID, err))
+				continue
+			}
+			p.Log.Info(fmt.Sprintf("Weekly summary of team '%v' sent to chat '%v'", team, chatID))
+		}
+	}
+}
+
+// Check if weekly summary time passed after previous check.
+func (p *Processor) isSummaryTime(previousCheck, now time.Time) bool {
+	location := p.Location
+	if location == nil {
+		location = time.UTC
+	}
+	for _, t := range []time.Time{previousCheck.In(location), now.In(location)} {
+		if t.Weekday() != p.Summary.Weekday {
+			continue
+		}
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+		at := CalendarProvider.AddClock(day, p.Summary.Time)
+		if at.After(previousCheck) && !at.After(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,27 +3,39 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/CalendarProvider"
+	"github.com/Sarraksh/otrs-echo-bot/CalendarProvider/basicCalendar"
 	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
 	"github.com/Sarraksh/otrs-echo-bot/ClientProvider/basicCilent"
 	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
 	"github.com/Sarraksh/otrs-echo-bot/DBProvider/SQLite3"
+	"github.com/Sarraksh/otrs-echo-bot/Formatter"
 	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider"
 	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider/basicOTRS"
+	"github.com/Sarraksh/otrs-echo-bot/OTRSProvider/cachedOTRS"
 	"github.com/Sarraksh/otrs-echo-bot/RESTProvider"
 	"github.com/Sarraksh/otrs-echo-bot/RESTProvider/echoREST"
 	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider"
 	"github.com/Sarraksh/otrs-echo-bot/TelegramProvider/tgbotapiProvider"
+	"github.com/Sarraksh/otrs-echo-bot/WebhookProvider"
+	"github.com/Sarraksh/otrs-echo-bot/WebhookProvider/httpWebhook"
+	"github.com/Sarraksh/otrs-echo-bot/backup"
 	"github.com/Sarraksh/otrs-echo-bot/common/config"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger"
 	"github.com/Sarraksh/otrs-echo-bot/common/logger/zapLogger"
 	"github.com/Sarraksh/otrs-echo-bot/event"
+	"github.com/Sarraksh/otrs-echo-bot/report"
+	"github.com/Sarraksh/otrs-echo-bot/retention"
 	"golang.org/x/sync/errgroup"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
 	"syscall"
+	"time"
+	_ "time/tzdata" // Timezones for OTRS server and users on hosts without tzdata.
 )
 
 const version string = `0.1.0.0`
@@ -53,21 +65,38 @@ func main() {
 		return
 	}
 
+	// Run maintenance command if provided instead of bot.
+	if len(os.Args) > 1 {
+		err = runCommand(os.Args[1:], conf, programDirectory, logModule)
+		if err != nil {
+			logModule.Error(fmt.Sprintf("Command '%v' failed - '%v'", os.Args[1:], err))
+			log.Fatalf("Command failed - %v", err)
+		}
+		return
+	}
+
 	// Declare module variables.
 	var (
 		DBModule       DBProvider.DBProvider
-		OTRSModule     OTRSProvider.OTRSProvider
+		OTRSModule     *OTRSProvider.Instances
 		TelegramModule TelegramProvider.TelegramProvider
 		ClientModule   ClientProvider.ClientProvider
+		WebhookModule  WebhookProvider.WebhookProvider
+		CalendarModule CalendarProvider.CalendarProvider
 		EventProcessor event.Processor
+		Pollers        []*event.Poller
+		Janitor        *retention.Janitor
+		Backup         *backup.Scheduler
 		RESTModule     RESTProvider.RESTProvider
 	)
 
 	// Define types for module variables.
 	DBModule = new(SQLite3.DB)
-	OTRSModule = new(basicOTRS.BasicOTRS)
+	OTRSModule = OTRSProvider.NewInstances()
 	TelegramModule = new(tgbotapiProvider.TelegramModule)
 	ClientModule = new(basicCilent.BasicClient)
+	WebhookModule = new(httpWebhook.HTTPWebhook)
+	CalendarModule = new(basicCalendar.BasicCalendar)
 	RESTModule = new(echoREST.EchoREST)
 
 	// Initialise modules.
@@ -76,10 +105,15 @@ func main() {
 		logModule,
 		programDirectory,
 		&DBModule,
-		&OTRSModule,
+		OTRSModule,
 		&TelegramModule,
 		&ClientModule,
+		&WebhookModule,
+		&CalendarModule,
 		&EventProcessor,
+		&Pollers,
+		&Janitor,
+		&Backup,
 		&RESTModule,
 	)
 	if err != nil {
@@ -109,6 +143,45 @@ func main() {
 		return err
 	})
 
+	// Start scheduler for due events.
+	group.Go(func() error {
+		logModule.Debug(fmt.Sprintf("Start event scheduler."))
+		err := EventProcessor.Scheduler(ctxGroup)
+		logModule.Debug(fmt.Sprintf("Stop event scheduler with error '%v'.", err))
+		return err
+	})
+
+	// Start pollers for new tickets.
+	for _, poller := range Pollers {
+		poller := poller
+		group.Go(func() error {
+			logModule.Debug(fmt.Sprintf("Start poller for OTRS instance '%v'.", poller.Instance))
+			err := poller.Run(ctxGroup)
+			logModule.Debug(fmt.Sprintf("Stop poller for OTRS instance '%v' with error '%v'.", poller.Instance, err))
+			return err
+		})
+	}
+
+	// Start cleanup of expired data.
+	if Janitor != nil {
+		group.Go(func() error {
+			logModule.Debug(fmt.Sprintf("Start retention janitor."))
+			err := Janitor.Run(ctxGroup)
+			logModule.Debug(fmt.Sprintf("Stop retention janitor with error '%v'.", err))
+			return err
+		})
+	}
+
+	// Start scheduled database backup.
+	if Backup != nil {
+		group.Go(func() error {
+			logModule.Debug(fmt.Sprintf("Start backup scheduler."))
+			err := Backup.Run(ctxGroup)
+			logModule.Debug(fmt.Sprintf("Stop backup scheduler with error '%v'.", err))
+			return err
+		})
+	}
+
 	// Start HTTP listener.
 	group.Go(func() error {
 		logModule.Debug(fmt.Sprintf("Start HTTP listener."))
@@ -163,10 +236,15 @@ func initialiseModules(
 	logModule logger.Logger,
 	programDirectory string,
 	DBModule *DBProvider.DBProvider,
-	OTRSModule *OTRSProvider.OTRSProvider,
+	OTRSModule *OTRSProvider.Instances,
 	TelegramModule *TelegramProvider.TelegramProvider,
 	ClientModule *ClientProvider.ClientProvider,
+	WebhookModule *WebhookProvider.WebhookProvider,
+	CalendarModule *CalendarProvider.CalendarProvider,
 	EventProcessor *event.Processor,
+	Pollers *[]*event.Poller,
+	Janitor **retention.Janitor,
+	Backup **backup.Scheduler,
 	RESTModule *RESTProvider.RESTProvider,
 ) error {
 
@@ -179,11 +257,32 @@ func initialiseModules(
 		return err
 	}
 
-	logModule.Debug("Initialise OTRS module")
-	(*OTRSModule).Initialise(logModule, conf.OTRS)
+	logModule.Debug("Load message templates")
+	templatesDirectory := conf.Templates.Directory
+	if templatesDirectory == "" {
+		templatesDirectory = filepath.Join(programDirectory, "templates")
+	}
+	err = Formatter.LoadTemplates(templatesDirectory, DBModule, parseMode(conf.Telegram.ParseMode), logModule)
+	if err != nil {
+		logModule.Error(fmt.Sprintf("Load message templates failed - '%v'", err))
+		return err
+	}
+
+	logModule.Debug("Initialise OTRS modules")
+	otrsConfList := append([]config.OTRSConf{conf.OTRS}, conf.OTRSInstances...)
+	for _, otrsConf := range otrsConfList {
+		var instance OTRSProvider.OTRSProvider = &cachedOTRS.CachedOTRS{Next: new(basicOTRS.BasicOTRS)}
+		instance.Initialise(logModule, otrsConf)
+		OTRSModule.Add(otrsConf.Name, instance)
+	}
+	err = (*DBModule).OTRSEventSetDefaultInstance(conf.OTRS.Name)
+	if err != nil {
+		logModule.Error(fmt.Sprintf("Assign events to default OTRS instance failed - '%v'", err))
+		return err
+	}
 
 	logModule.Debug("Initialise Telegram module")
-	err = (*TelegramModule).Initialise(conf.Telegram.Token, logModule, DBModule)
+	err = (*TelegramModule).Initialise(conf.Telegram, logModule, DBModule, OTRSModule, ClientModule)
 	if err != nil {
 		logModule.Error(fmt.Sprintf("Initialise Telegram module failed - '%v'", err))
 		return err
@@ -192,18 +291,227 @@ func initialiseModules(
 	logModule.Debug("Initialise Client module")
 	(*ClientModule).Initialise(DBModule, logModule)
 
+	logModule.Debug("Initialise Webhook module")
+	err = (*WebhookModule).Initialise(logModule, DBModule, conf.Webhooks)
+	if err != nil {
+		logModule.Error(fmt.Sprintf("Initialise Webhook module failed - '%v'", err))
+		return err
+	}
+
+	logModule.Debug("Initialise Calendar module")
+	calendarConf := conf.Calendar
+	if calendarConf.Timezone == "" {
+		calendarConf.Timezone = conf.OTRS.Timezone
+	}
+	if calendarConf.Timezone == "" {
+		calendarConf.Timezone = OTRSProvider.DefaultTimezone
+	}
+	err = (*CalendarModule).Initialise(logModule, calendarConf, programDirectory)
+	if err != nil {
+		logModule.Error(fmt.Sprintf("Initialise Calendar module failed - '%v'", err))
+		return err
+	}
+
 	logModule.Debug("Initialise Event processor")
-	EventProcessor = &event.Processor{
+	location, err := time.LoadLocation(calendarConf.Timezone)
+	if err != nil {
+		logModule.Error(fmt.Sprintf("Load timezone '%v' failed - '%v'", calendarConf.Timezone, err))
+		return err
+	}
+	digestTimes := make([]time.Duration, 0, len(conf.Digest.Times))
+	for _, value := range conf.Digest.Times {
+		clock, err := CalendarProvider.ParseClock(value)
+		if err != nil {
+			logModule.Error(fmt.Sprintf("Invalid digest time '%v' - '%v'", value, err))
+			return err
+		}
+		digestTimes = append(digestTimes, clock)
+	}
+	owner := conf.Cluster.Owner
+	if owner == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			logModule.Error(fmt.Sprintf("Can't get host name for event leases - '%v'", err))
+			return err
+		}
+		owner = fmt.Sprintf("%v:%v", hostname, os.Getpid())
+	}
+	lease := event.DefaultLease
+	if conf.Cluster.Lease > 0 {
+		lease = time.Duration(conf.Cluster.Lease) * time.Second
+	}
+	logModule.Info(fmt.Sprintf("Claim events as '%v' with lease '%v'", owner, lease))
+	var summary *event.WeeklySummary
+	if conf.Reports.Weekday != "" {
+		weekday, err := CalendarProvider.ParseWeekday(conf.Reports.Weekday)
+		if err != nil {
+			logModule.Error(fmt.Sprintf("Invalid weekly summary day - '%v'", err))
+			return err
+		}
+		summaryTime := event.DefaultSummaryTime
+		if conf.Reports.Time != "" {
+			summaryTime, err = CalendarProvider.ParseClock(conf.Reports.Time)
+			if err != nil {
+				logModule.Error(fmt.Sprintf("Invalid weekly summary time '%v' - '%v'", conf.Reports.Time, err))
+				return err
+			}
+		}
+		summary = &event.WeeklySummary{Weekday: weekday, Time: summaryTime, TeamLeads: conf.Reports.TeamLeads}
+	}
+	*EventProcessor = event.Processor{
 		DB:       DBModule,
 		OTRS:     OTRSModule,
 		Client:   ClientModule,
 		Telegram: TelegramModule,
+		Webhook:  WebhookModule,
+		Calendar: CalendarModule,
 		Log:      logModule.SetModuleName("Event Processor"),
+
+		Owner:   owner,
+		Lease:   lease,
+		Standby: time.Duration(conf.Cluster.Standby) * time.Second,
+
+		EscalationLevel: conf.Templates.EscalationLevel,
+		NotifyClosure:   conf.Templates.NotifyClosure,
+		Policies:        conf.EscalationPolicies,
+
+		CriticalPriorities: conf.Notifications.CriticalPriorities,
+		Location:           location,
+
+		DigestTimes:    digestTimes,
+		DigestInterval: time.Duration(conf.Digest.Interval) * time.Minute,
+
+		Summary: summary,
 	}
 
-	(*RESTModule).Initialise(logModule, DBModule)
+	logModule.Debug("Initialise pollers")
+	for _, otrsConf := range otrsConfList {
+		if otrsConf.Poll.Interval <= 0 {
+			continue
+		}
+		overlap := event.DefaultPollOverlap
+		if otrsConf.Poll.Overlap > 0 {
+			overlap = time.Duration(otrsConf.Poll.Overlap) * time.Second
+		}
+		*Pollers = append(*Pollers, &event.Poller{
+			Instance:  otrsConf.Name,
+			OTRS:      OTRSModule,
+			DB:        DBModule,
+			Processor: EventProcessor,
+			Log:       logModule.SetModuleName(OTRSProvider.ModuleName("Poller", otrsConf.Name)),
+			Interval:  time.Duration(otrsConf.Poll.Interval) * time.Second,
+			Overlap:   overlap,
+			Lookback:  time.Duration(otrsConf.Poll.Lookback) * time.Second,
+		})
+	}
+
+	*Janitor = newJanitor(conf.Retention, programDirectory, DBModule, logModule)
+	*Backup = newBackupScheduler(conf.Backup, programDirectory, DBModule, logModule)
+
+	(*RESTModule).Initialise(logModule, DBModule, OTRSModule, otrsConfList, conf.REST)
 	(*RESTModule).PrepareListener(EventProcessor)
+	if conf.Telegram.Mode == tgbotapiProvider.ModeWebhook {
+		path, handler := (*TelegramModule).WebhookHandler()
+		(*RESTModule).AddHandler(http.MethodPost, path, handler)
+	}
+	(*RESTModule).AddAdminHandler(http.MethodGet, event.StatsHandlerPath, EventProcessor.StatsHandler())
+	(*RESTModule).AddAdminHandler(http.MethodGet, report.HandlerPath, report.NewHandler(DBModule, ClientModule, location, logModule.SetModuleName("Report")))
+	if *Janitor != nil {
+		(*RESTModule).AddAdminHandler(http.MethodGet, retention.HandlerPath, (*Janitor).Handler())
+	}
 
 	logModule.Debug("Module initialisation sequence complete")
 	return nil
 }
+
+// Prepare cleanup of expired data. Return nil if no retention period configured.
+func newJanitor(conf config.RetentionConf, programDirectory string, DBModule *DBProvider.DBProvider, logModule logger.Logger) *retention.Janitor {
+	day := 24 * time.Hour
+	periods := map[string]time.Duration{
+		DBProvider.RetentionEvents:     time.Duration(conf.Events) * day,
+		DBProvider.RetentionMessages:   time.Duration(conf.Messages) * day,
+		DBProvider.RetentionDeliveries: time.Duration(conf.Deliveries) * day,
+	}
+	enabled := false
+	for kind, period := range periods {
+		if period <= 0 {
+			delete(periods, kind)
+			continue
+		}
+		enabled = true
+	}
+	if !enabled {
+		logModule.Debug("No retention period configured. Data kept forever")
+		return nil
+	}
+
+	janitor := &retention.Janitor{
+		DB:        DBModule,
+		Log:       logModule.SetModuleName(retention.ModuleName),
+		Retention: periods,
+		Interval:  retention.DefaultInterval,
+		BatchSize: retention.DefaultBatchSize,
+		Optimise:  retention.DefaultOptimise,
+	}
+	if conf.Interval > 0 {
+		janitor.Interval = time.Duration(conf.Interval) * time.Minute
+	}
+	if conf.BatchSize > 0 {
+		janitor.BatchSize = conf.BatchSize
+	}
+	switch {
+	case conf.OptimiseInterval > 0:
+		janitor.Optimise = time.Duration(conf.OptimiseInterval) * time.Hour
+	case conf.OptimiseInterval < 0:
+		janitor.Optimise = 0
+	}
+	if conf.ArchiveDirectory != "" {
+		directory := conf.ArchiveDirectory
+		if !filepath.IsAbs(directory) {
+			directory = filepath.Join(programDirectory, directory)
+		}
+		janitor.Archive = &retention.FileArchive{Directory: directory}
+	}
+	return janitor
+}
+
+// Prepare scheduled database backup. Return nil if backup directory not configured.
+func newBackupScheduler(conf config.BackupConf, programDirectory string, DBModule *DBProvider.DBProvider, logModule logger.Logger) *backup.Scheduler {
+	if conf.Directory == "" {
+		logModule.Debug("No backup directory configured. Scheduled backup disabled")
+		return nil
+	}
+	directory := conf.Directory
+	if !filepath.IsAbs(directory) {
+		directory = filepath.Join(programDirectory, directory)
+	}
+	scheduler := &backup.Scheduler{
+		DB:        DBModule,
+		Log:       logModule.SetModuleName(backup.ModuleName),
+		Directory: directory,
+		Interval:  backup.DefaultInterval,
+		Keep:      backup.DefaultKeep,
+	}
+	if conf.Interval > 0 {
+		scheduler.Interval = time.Duration(conf.Interval) * time.Hour
+	}
+	switch {
+	case conf.Keep > 0:
+		scheduler.Keep = conf.Keep
+	case conf.Keep < 0:
+		scheduler.Keep = 0
+	}
+	return scheduler
+}
+
+// Convert parse mode option into Telegram parse mode. Rich HTML alerts by default.
+func parseMode(option string) string {
+	switch option {
+	case "Plain":
+		return Formatter.ParseModePlain
+	case "MarkdownV2":
+		return Formatter.ParseModeMarkdownV2
+	default:
+		return Formatter.ParseModeHTML
+	}
+}
@@ -0,0 +1,107 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/common/locale"
+	"io"
+	"strings"
+	"time"
+)
+
+// Summary texts by language.
+var messageList = locale.Catalog{
+	locale.Russian: {
+		"header":      "Время реакции (медиана / 90%)",
+		"tickets":     "заявок",
+		"acknowledge": "принятие",
+		"close":       "закрытие",
+		"noTeam":      "без команды",
+		"total":       "Всего",
+	},
+	locale.English: {
+		"header":      "Response time (median / 90th percentile)",
+		"tickets":     "tickets",
+		"acknowledge": "acknowledge",
+		"close":       "close",
+		"noTeam":      "no team",
+		"total":       "Total",
+	},
+}
+
+// Write report as CSV with header. Times in seconds.
+func (r Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := []string{r.GroupBy, "tickets",
+		"ack_count", "ack_avg", "ack_p50", "ack_p90", "ack_p95", "ack_max",
+		"close_count", "close_avg", "close_p50", "close_p90", "close_p95", "close_max",
+	}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, row := range append(r.Rows, r.Total) {
+		err = writer.Write(append([]string{row.Group, fmt.Sprint(row.Tickets)},
+			append(row.Acknowledge.fields(), row.Close.fields()...)...))
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (d Durations) fields() []string {
+	return []string{fmt.Sprint(d.Count), fmt.Sprint(d.Average), fmt.Sprint(d.P50), fmt.Sprint(d.P90), fmt.Sprint(d.P95), fmt.Sprint(d.Max)}
+}
+
+// Return plain text summary with median and 90th percentile of reaction times.
+// Rows limited to provided groups if any, total always included.
+func (r Report) Summary(language string, location *time.Location, groups ...string) string {
+	if location == nil {
+		location = time.UTC
+	}
+	lines := []string{fmt.Sprintf("%v\n%v - %v\n", messageList.Text(language, "header"),
+		r.From.In(location).Format("2006-01-02"), r.To.Add(-time.Second).In(location).Format("2006-01-02"))}
+	for _, row := range r.Rows {
+		if len(groups) > 0 && !contains(groups, row.Group) {
+			continue
+		}
+		name := row.Group
+		if name == "" {
+			name = messageList.Text(language, "noTeam")
+		}
+		lines = append(lines, summaryLine(name, row, language))
+	}
+	if len(lines) > 1 {
+		lines = append(lines, "")
+	}
+	lines = append(lines, summaryLine(messageList.Text(language, "total"), r.Total, language))
+	return strings.Join(lines, "\n")
+}
+
+// Format row as "name: N tickets, acknowledge p50 / p90, close p50 / p90".
+func summaryLine(name string, row Row, language string) string {
+	line := fmt.Sprintf("%v: %v %v", name, row.Tickets, messageList.Text(language, "tickets"))
+	for _, part := range []struct {
+		key    string
+		values Durations
+	}{{"acknowledge", row.Acknowledge}, {"close", row.Close}} {
+		if part.values.Count == 0 {
+			continue
+		}
+		line = fmt.Sprintf("%v, %v %v / %v", line, messageList.Text(language, part.key),
+			locale.ShortDuration(language, time.Duration(part.values.P50)*time.Second),
+			locale.ShortDuration(language, time.Duration(part.values.P90)*time.Second))
+	}
+	return line
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/common/locale"
+)
+
+func TestWriteCSV(t *testing.T) {
+	report, _ := build(t, Criteria{GroupBy: GroupClient})
+	var buffer bytes.Buffer
+	if err := report.WriteCSV(&buffer); err != nil {
+		t.Fatalf("write CSV - %v", err)
+	}
+
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV - %v", err)
+	}
+	want := [][]string{
+		{"client", "tickets", "ack_count", "ack_avg", "ack_p50", "ack_p90", "ack_p95", "ack_max",
+			"close_count", "close_avg", "close_p50", "close_p90", "close_p95", "close_max"},
+		{"first", "2", "2", "90", "60", "120", "120", "120", "1", "600", "600", "600", "600", "600"},
+		{"second", "2", "1", "30", "30", "30", "30", "30", "1", "300", "300", "300", "300", "300"},
+		{"unknown", "1", "0", "0", "0", "0", "0", "0", "0", "0", "0", "0", "0", "0"},
+		{"total", "5", "3", "70", "60", "120", "120", "120", "2", "450", "300", "600", "600", "600"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("%v records, want %v", len(records), len(want))
+	}
+	for index, record := range records {
+		if strings.Join(record, ",") != strings.Join(want[index], ",") {
+			t.Errorf("record %v '%v', want '%v'", index, record, want[index])
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	report, _ := build(t, Criteria{From: weekStart.Add(-10 * time.Hour), To: weekStart.AddDate(0, 0, 7).Add(-10 * time.Hour)})
+	minutes := func(language string, n int) string {
+		return locale.ShortDuration(language, time.Duration(n)*time.Minute)
+	}
+
+	summary := report.Summary(locale.English, time.UTC, "Team1", "")
+	lines := strings.Split(summary, "\n")
+	want := []string{
+		"Response time (median / 90th percentile)",
+		"2026-10-12 - 2026-10-18", // Range end excluded.
+		"",
+		"no team: 1 tickets", // Rows sorted by group, no team first.
+		"Team1: 2 tickets, acknowledge " + minutes(locale.English, 1) + " / " + minutes(locale.English, 2) +
+			", close " + minutes(locale.English, 10) + " / " + minutes(locale.English, 10),
+		"",
+		"Total: 5 tickets, acknowledge " + minutes(locale.English, 1) + " / " + minutes(locale.English, 2) +
+			", close " + minutes(locale.English, 5) + " / " + minutes(locale.English, 10),
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("summary:\n%v\nwant:\n%v", summary, strings.Join(want, "\n"))
+	}
+
+	// Russian summary without group filter lists every group.
+	summary = report.Summary(locale.Russian, nil)
+	for _, part := range []string{"Время реакции", "Team2: 2 заявок", "без команды", "Всего: 5"} {
+		if !strings.Contains(summary, part) {
+			t.Errorf("Russian summary has no '%v':\n%v", part, summary)
+		}
+	}
+}
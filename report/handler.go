@@ -0,0 +1,89 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"net/http"
+	"time"
+)
+
+const (
+	HandlerPath string = "/api/v1/reports/response-time" // Route of report export on REST listener.
+	DefaultDays int    = 7                               // Report range in days if "from" not provided.
+	dateLayout  string = "2006-01-02"
+)
+
+// Return HTTP handler exporting report as JSON or CSV.
+// Query parameters: "from" and "to" dates in "2006-01-02" format ("to" excluded, tomorrow and week before if not set),
+// "group" - team, client, priority or period, "period" - day, week or month, "format" - json or csv.
+func NewHandler(db *DBProvider.DBProvider, client *ClientProvider.ClientProvider, location *time.Location, log logger.Logger) http.HandlerFunc {
+	if location == nil {
+		location = time.UTC
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		criteria, err := parseCriteria(query.Get("from"), query.Get("to"), location)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		criteria.GroupBy = query.Get("group")
+		criteria.Period = query.Get("period")
+		err = criteria.Validate()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := Build(*db, *client, criteria)
+		if err != nil {
+			log.Error(fmt.Sprintf("Can't build report - '%v'", err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		switch query.Get("format") {
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"response-time-%v.csv\"", criteria.From.Format(dateLayout)))
+			err = report.WriteCSV(w)
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			err = json.NewEncoder(w).Encode(report)
+		default:
+			http.Error(w, fmt.Sprintf("unknown format '%v'", query.Get("format")), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Error(fmt.Sprintf("Can't write report - '%v'", err))
+		}
+	}
+}
+
+// Parse report dates in location. Default range is week till end of today.
+func parseCriteria(from, to string, location *time.Location) (Criteria, error) {
+	now := time.Now().In(location)
+	criteria := Criteria{
+		To:       time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, location),
+		Location: location,
+	}
+	var err error
+	if to != "" {
+		criteria.To, err = time.ParseInLocation(dateLayout, to, location)
+		if err != nil {
+			return Criteria{}, fmt.Errorf("%w: invalid date '%v'", myErrors.ErrInvalidReportCriteria, to)
+		}
+	}
+	criteria.From = criteria.To.AddDate(0, 0, -DefaultDays)
+	if from != "" {
+		criteria.From, err = time.ParseInLocation(dateLayout, from, location)
+		if err != nil {
+			return Criteria{}, fmt.Errorf("%w: invalid date '%v'", myErrors.ErrInvalidReportCriteria, from)
+		}
+	}
+	return criteria, nil
+}
@@ -0,0 +1,220 @@
+// Package report builds response time reports from stored events and their history.
+package report
+
+import (
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+	"sort"
+	"time"
+)
+
+const EventType string = "newticket" // Events of new tickets measured by reports.
+
+// Report grouping.
+const (
+	GroupTeam     string = "team"
+	GroupClient   string = "client"
+	GroupPriority string = "priority"
+	GroupPeriod   string = "period"
+)
+
+// Periods for grouping by period.
+const (
+	PeriodDay   string = "day"
+	PeriodWeek  string = "week"
+	PeriodMonth string = "month"
+)
+
+// Report options. Events created in [From, To) included.
+type Criteria struct {
+	From     time.Time
+	To       time.Time
+	GroupBy  string         // Team, client, priority or period. Team if not set.
+	Period   string         // Day, week or month for grouping by period. Week if not set.
+	Location *time.Location // Timezone of period boundaries. UTC if not set.
+}
+
+// Distribution of reaction times. Times in seconds.
+type Durations struct {
+	Count   int   `json:"count"`
+	Average int64 `json:"average"`
+	P50     int64 `json:"p50"`
+	P90     int64 `json:"p90"`
+	P95     int64 `json:"p95"`
+	Max     int64 `json:"max"`
+}
+
+// Report row for one group.
+type Row struct {
+	Group       string    `json:"group"`
+	Tickets     int       `json:"tickets"`
+	Acknowledge Durations `json:"timeToAcknowledge"`
+	Close       Durations `json:"timeToClose"`
+}
+
+// Response time report. Rows sorted by group.
+type Report struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	GroupBy string    `json:"groupBy"`
+	Rows    []Row     `json:"rows"`
+	Total   Row       `json:"total"`
+}
+
+// Build report of time to acknowledge and time to close for new ticket events.
+// Ticket acknowledged when taken or closed in OTRS, reaction observed by event processing.
+// Tickets closed after being taken not counted in time to close, event ends when ticket taken.
+func Build(db DBProvider.DBProvider, client ClientProvider.ClientProvider, criteria Criteria) (Report, error) {
+	if criteria.GroupBy == "" {
+		criteria.GroupBy = GroupTeam
+	}
+	if criteria.Period == "" {
+		criteria.Period = PeriodWeek
+	}
+	if criteria.Location == nil {
+		criteria.Location = time.UTC
+	}
+
+	reactionList, err := db.EventHistoryGetReactions(EventType, criteria.From.Unix(), criteria.To.Unix())
+	if err != nil {
+		return Report{}, err
+	}
+
+	// Team lookup error means no team.
+	teamByClient := make(map[string]string)
+	groupKey := func(reaction DBProvider.EventReaction) string {
+		switch criteria.GroupBy {
+		case GroupClient:
+			return reaction.CustomerID
+		case GroupPriority:
+			return reaction.Priority
+		case GroupPeriod:
+			return periodStart(time.Unix(reaction.Created, 0).In(criteria.Location), criteria.Period)
+		default:
+			team, ok := teamByClient[reaction.CustomerID]
+			if !ok {
+				team, err = client.GetTeamByClient(reaction.CustomerID)
+				if err != nil {
+					team = ""
+				}
+				teamByClient[reaction.CustomerID] = team
+			}
+			return team
+		}
+	}
+
+	groups := make(map[string]*samples)
+	total := &samples{}
+	for _, reaction := range reactionList {
+		key := groupKey(reaction)
+		group, ok := groups[key]
+		if !ok {
+			group = &samples{}
+			groups[key] = group
+		}
+		group.add(reaction)
+		total.add(reaction)
+	}
+
+	report := Report{
+		From:    criteria.From,
+		To:      criteria.To,
+		GroupBy: criteria.GroupBy,
+		Rows:    make([]Row, 0, len(groups)),
+		Total:   total.row("total"),
+	}
+	for key, group := range groups {
+		report.Rows = append(report.Rows, group.row(key))
+	}
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Group < report.Rows[j].Group })
+	return report, nil
+}
+
+// Check grouping and period names.
+func (c Criteria) Validate() error {
+	switch c.GroupBy {
+	case "", GroupTeam, GroupClient, GroupPriority, GroupPeriod:
+	default:
+		return fmt.Errorf("%w: unknown grouping '%v'", myErrors.ErrInvalidReportCriteria, c.GroupBy)
+	}
+	switch c.Period {
+	case "", PeriodDay, PeriodWeek, PeriodMonth:
+	default:
+		return fmt.Errorf("%w: unknown period '%v'", myErrors.ErrInvalidReportCriteria, c.Period)
+	}
+	if !c.From.Before(c.To) {
+		return fmt.Errorf("%w: empty time range '%v' - '%v'", myErrors.ErrInvalidReportCriteria, c.From, c.To)
+	}
+	return nil
+}
+
+// Reaction times of one group.
+type samples struct {
+	tickets     int
+	acknowledge []time.Duration
+	close       []time.Duration
+}
+
+func (s *samples) add(reaction DBProvider.EventReaction) {
+	s.tickets++
+	if reaction.Acknowledged != 0 {
+		s.acknowledge = append(s.acknowledge, time.Duration(reaction.Acknowledged-reaction.Created)*time.Second)
+	}
+	if reaction.Closed != 0 {
+		s.close = append(s.close, time.Duration(reaction.Closed-reaction.Created)*time.Second)
+	}
+}
+
+func (s *samples) row(group string) Row {
+	return Row{
+		Group:       group,
+		Tickets:     s.tickets,
+		Acknowledge: distribution(s.acknowledge),
+		Close:       distribution(s.close),
+	}
+}
+
+// Return count, average, percentiles and maximum of durations.
+func distribution(values []time.Duration) Durations {
+	if len(values) == 0 {
+		return Durations{}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	var sum time.Duration
+	for _, value := range values {
+		sum += value
+	}
+	return Durations{
+		Count:   len(values),
+		Average: int64(sum / time.Duration(len(values)) / time.Second),
+		P50:     int64(percentile(values, 50) / time.Second),
+		P90:     int64(percentile(values, 90) / time.Second),
+		P95:     int64(percentile(values, 95) / time.Second),
+		Max:     int64(values[len(values)-1] / time.Second),
+	}
+}
+
+// Return percentile of sorted values by nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// Return start of period containing t as date, e.g. "2026-10-12" for week or "2026-10" for month.
+// Weeks start on Monday.
+func periodStart(t time.Time, period string) string {
+	switch period {
+	case PeriodDay:
+		return t.Format("2006-01-02")
+	case PeriodMonth:
+		return t.Format("2006-01")
+	default:
+		offset := (int(t.Weekday()) + 6) % 7
+		return t.AddDate(0, 0, -offset).Format("2006-01-02")
+	}
+}
@@ -0,0 +1,246 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sarraksh/otrs-echo-bot/ClientProvider"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/myErrors"
+)
+
+// Database with stored reactions only.
+type reactionDB struct {
+	DBProvider.DBProvider
+	reactions []DBProvider.EventReaction
+	err       error
+	from, to  int64 // Range of last request.
+}
+
+func (rdb *reactionDB) EventHistoryGetReactions(eventType string, from, to int64) ([]DBProvider.EventReaction, error) {
+	rdb.from, rdb.to = from, to
+	return rdb.reactions, rdb.err
+}
+
+// Teams of clients counting lookups. Unknown clients have no team.
+type teamClient struct {
+	ClientProvider.ClientProvider
+	teams   map[string]string
+	lookups int
+}
+
+func (tc *teamClient) GetTeamByClient(client string) (string, error) {
+	tc.lookups++
+	team, ok := tc.teams[client]
+	if !ok {
+		return "", errors.New("unknown client")
+	}
+	return team, nil
+}
+
+// Monday.
+var weekStart = time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+
+// Return reaction to ticket created after week start. Zero acknowledge or close time means not observed.
+func reaction(customerID, priority string, created, acknowledge, close time.Duration) DBProvider.EventReaction {
+	r := DBProvider.EventReaction{CustomerID: customerID, Priority: priority}
+	r.Created = weekStart.Add(created).Unix()
+	if acknowledge != 0 {
+		r.Acknowledged = r.Created + int64(acknowledge/time.Second)
+	}
+	if close != 0 {
+		r.Closed = r.Created + int64(close/time.Second)
+	}
+	return r
+}
+
+func testReactions() []DBProvider.EventReaction {
+	day := 24 * time.Hour
+	return []DBProvider.EventReaction{
+		reaction("first", "3 normal", 0, time.Minute, 0),
+		reaction("first", "5 very high", time.Hour, 2*time.Minute, 10*time.Minute),
+		reaction("second", "3 normal", day, 0, 5*time.Minute),
+		reaction("unknown", "3 normal", 6*day, 0, 0),
+		reaction("second", "3 normal", 7*day, 30*time.Second, 0),
+	}
+}
+
+func build(t *testing.T, criteria Criteria) (Report, *teamClient) {
+	t.Helper()
+	client := &teamClient{teams: map[string]string{"first": "Team1", "second": "Team2"}}
+	report, err := Build(&reactionDB{reactions: testReactions()}, client, criteria)
+	if err != nil {
+		t.Fatalf("build report - %v", err)
+	}
+	return report, client
+}
+
+// Return groups and ticket counts of rows, e.g. "Team1:2 Team2:1".
+func groupTickets(rows []Row) string {
+	parts := make([]string, 0, len(rows))
+	for _, row := range rows {
+		parts = append(parts, fmt.Sprint(row.Group, ":", row.Tickets))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestBuildGrouping(t *testing.T) {
+	cases := []struct {
+		groupBy string
+		period  string
+		want    string
+	}{
+		{"", "", ":1 Team1:2 Team2:2"},
+		{GroupTeam, "", ":1 Team1:2 Team2:2"},
+		{GroupClient, "", "first:2 second:2 unknown:1"},
+		{GroupPriority, "", "3 normal:4 5 very high:1"},
+		{GroupPeriod, "", "2026-10-12:4 2026-10-19:1"},
+		{GroupPeriod, PeriodWeek, "2026-10-12:4 2026-10-19:1"},
+		{GroupPeriod, PeriodDay, "2026-10-12:2 2026-10-13:1 2026-10-18:1 2026-10-19:1"},
+		{GroupPeriod, PeriodMonth, "2026-10:5"},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.groupBy, " ", c.period), func(t *testing.T) {
+			report, _ := build(t, Criteria{GroupBy: c.groupBy, Period: c.period})
+			if rows := groupTickets(report.Rows); rows != c.want {
+				t.Errorf("rows '%v', want '%v'", rows, c.want)
+			}
+			if report.Total.Tickets != 5 {
+				t.Errorf("total %v tickets, want 5", report.Total.Tickets)
+			}
+		})
+	}
+}
+
+func TestBuildDurations(t *testing.T) {
+	report, client := build(t, Criteria{})
+
+	// Team of every client requested once.
+	if client.lookups != 3 {
+		t.Errorf("%v team lookups, want 3", client.lookups)
+	}
+
+	cases := []struct {
+		name string
+		got  Durations
+		want Durations
+	}{
+		{"Team1 acknowledge", report.Rows[1].Acknowledge, Durations{Count: 2, Average: 90, P50: 60, P90: 120, P95: 120, Max: 120}},
+		{"Team1 close", report.Rows[1].Close, Durations{Count: 1, Average: 600, P50: 600, P90: 600, P95: 600, Max: 600}},
+		{"no team acknowledge", report.Rows[0].Acknowledge, Durations{}},
+		{"total acknowledge", report.Total.Acknowledge, Durations{Count: 3, Average: 70, P50: 60, P90: 120, P95: 120, Max: 120}},
+		{"total close", report.Total.Close, Durations{Count: 2, Average: 450, P50: 300, P90: 600, P95: 600, Max: 600}},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%v %+v, want %+v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestBuildRange(t *testing.T) {
+	db := &reactionDB{}
+	criteria := Criteria{From: weekStart, To: weekStart.AddDate(0, 0, 7)}
+	report, err := Build(db, &teamClient{}, criteria)
+	if err != nil {
+		t.Fatalf("build report - %v", err)
+	}
+	if db.from != criteria.From.Unix() || db.to != criteria.To.Unix() {
+		t.Errorf("reactions requested for '%v' - '%v'", db.from, db.to)
+	}
+	if len(report.Rows) != 0 || report.Total.Tickets != 0 || report.GroupBy != GroupTeam {
+		t.Errorf("report without reactions %+v", report)
+	}
+
+	db.err = errors.New("database is locked")
+	if _, err = Build(db, &teamClient{}, criteria); err == nil {
+		t.Error("report built without database")
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	seconds := func(values ...int) []time.Duration {
+		durations := make([]time.Duration, 0, len(values))
+		for _, value := range values {
+			durations = append(durations, time.Duration(value)*time.Second)
+		}
+		return durations
+	}
+	cases := []struct {
+		name   string
+		values []time.Duration
+		want   Durations
+	}{
+		{"empty", nil, Durations{}},
+		{"single", seconds(42), Durations{Count: 1, Average: 42, P50: 42, P90: 42, P95: 42, Max: 42}},
+		{"unsorted", seconds(10, 1, 9, 2, 8, 3, 7, 4, 6, 5), Durations{Count: 10, Average: 5, P50: 5, P90: 9, P95: 10, Max: 10}},
+		{"twenty", seconds(20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1),
+			Durations{Count: 20, Average: 10, P50: 10, P90: 18, P95: 19, Max: 20}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := distribution(c.values); got != c.want {
+				t.Errorf("distribution %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sundayNight := time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		t      time.Time
+		period string
+		want   string
+	}{
+		{"monday", weekStart, PeriodWeek, "2026-10-12"},
+		{"sunday", sundayNight, PeriodWeek, "2026-10-12"},
+		{"sunday in UTC is monday in timezone", sundayNight.In(moscow), PeriodWeek, "2026-10-19"},
+		{"week across month", time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC), PeriodWeek, "2026-10-26"},
+		{"day", sundayNight.In(moscow), PeriodDay, "2026-10-19"},
+		{"month", time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC), PeriodMonth, "2026-10"},
+		{"unknown period as week", sundayNight, "year", "2026-10-12"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if start := periodStart(c.t, c.period); start != c.want {
+				t.Errorf("period start '%v', want '%v'", start, c.want)
+			}
+		})
+	}
+}
+
+func TestCriteriaValidate(t *testing.T) {
+	week := Criteria{From: weekStart, To: weekStart.AddDate(0, 0, 7)}
+	cases := []struct {
+		name     string
+		criteria Criteria
+		valid    bool
+	}{
+		{"defaults", week, true},
+		{"grouping", Criteria{From: week.From, To: week.To, GroupBy: GroupPeriod, Period: PeriodMonth}, true},
+		{"unknown grouping", Criteria{From: week.From, To: week.To, GroupBy: "queue"}, false},
+		{"unknown period", Criteria{From: week.From, To: week.To, GroupBy: GroupPeriod, Period: "year"}, false},
+		{"empty range", Criteria{From: week.From, To: week.From}, false},
+		{"reversed range", Criteria{From: week.To, To: week.From}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.criteria.Validate()
+			if c.valid && err != nil {
+				t.Errorf("valid criteria rejected - %v", err)
+			}
+			if !c.valid && !errors.Is(err, myErrors.ErrInvalidReportCriteria) {
+				t.Errorf("error '%v', want '%v'", err, myErrors.ErrInvalidReportCriteria)
+			}
+		})
+	}
+}
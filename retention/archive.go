@@ -0,0 +1,47 @@
+package retention
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Archive rows as JSON lines into monthly files "<table>-2006-01.jsonl" in directory.
+// Row archived again if removal failed after archive, so archive may contain duplicates.
+type FileArchive struct {
+	Directory string
+	mx        sync.Mutex
+}
+
+// Append rows to archive file of table and sync file before return.
+func (fa *FileArchive) Archive(table string, rows []map[string]interface{}) error {
+	fa.mx.Lock()
+	defer fa.mx.Unlock()
+
+	err := os.MkdirAll(fa.Directory, 0750)
+	if err != nil {
+		return err
+	}
+	fileName := filepath.Join(fa.Directory, fmt.Sprintf("%v-%v.jsonl", table, time.Now().Format("2006-01")))
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, row := range rows {
+		err = encoder.Encode(row)
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,161 @@
+// Package retention removes expired events, messages and notification keys in background.
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Sarraksh/otrs-echo-bot/DBProvider"
+	"github.com/Sarraksh/otrs-echo-bot/common/logger"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	ModuleName       string        = "Retention Janitor"
+	HandlerPath      string        = "/api/v1/metrics/retention" // Route of cleanup statistics on REST listener.
+	DefaultInterval  time.Duration = time.Hour
+	DefaultBatchSize int           = 500
+	DefaultOptimise  time.Duration = 24 * time.Hour
+	batchPause       time.Duration = 100 * time.Millisecond // Pause between batches so other writers not blocked.
+)
+
+// Periodically remove rows older than retention period. Rows archived before removal if archive set.
+type Janitor struct {
+	DB        *DBProvider.DBProvider
+	Log       logger.Logger
+	Retention map[string]time.Duration // Retention period by kind. Kinds without period kept forever.
+	Archive   DBProvider.Archiver      // Nil if rows deleted without archive.
+	Interval  time.Duration            // Interval between cleanups.
+	BatchSize int                      // Rows removed in one transaction.
+	Optimise  time.Duration            // Interval between VACUUM and ANALYZE. Disabled if zero.
+
+	mx           sync.Mutex
+	stats        Stats
+	lastOptimise time.Time
+}
+
+// Cleanup statistics since start.
+type Stats struct {
+	Runs         int              `json:"runs"`
+	LastRun      time.Time        `json:"lastRun"`
+	LastRemoved  map[string]int64 `json:"lastRemoved"`  // Rows removed by last cleanup by kind.
+	TotalRemoved map[string]int64 `json:"totalRemoved"` // Rows removed since start by kind.
+	LastOptimise time.Time        `json:"lastOptimise"`
+	SizeBefore   int64            `json:"sizeBefore"` // Database size in bytes before last VACUUM.
+	SizeAfter    int64            `json:"sizeAfter"`  // Database size in bytes after last VACUUM.
+	Errors       int              `json:"errors"`
+}
+
+// Clean up periodically until context done. First cleanup after one interval, so start not slowed down.
+func (j *Janitor) Run(ctx context.Context) error {
+	j.Log.Info(fmt.Sprintf("Clean up expired data every '%v'", j.Interval))
+	j.lastOptimise = time.Now()
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			j.Cleanup(ctx, time.Now())
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Remove expired rows of every kind in batches, then optimise database if due.
+// Return number of removed rows by kind.
+func (j *Janitor) Cleanup(ctx context.Context, now time.Time) map[string]int64 {
+	removed := make(map[string]int64, len(j.Retention))
+	failed := 0
+	for kind, period := range j.Retention {
+		if period <= 0 {
+			continue
+		}
+		before := now.Add(-period).Unix()
+		for ctx.Err() == nil {
+			count, err := (*j.DB).RetentionPurge(kind, before, j.BatchSize, j.Archive)
+			if err != nil {
+				j.Log.Error(fmt.Sprintf("Can't remove expired '%v' - '%v'", kind, err))
+				failed++
+				break
+			}
+			removed[kind] += count
+			if count < int64(j.BatchSize) {
+				break
+			}
+			time.Sleep(batchPause)
+		}
+		if removed[kind] > 0 {
+			j.Log.Info(fmt.Sprintf("Removed '%v' expired '%v' older than '%v'", removed[kind], kind, time.Unix(before, 0)))
+		}
+	}
+
+	j.mx.Lock()
+	j.stats.Runs++
+	j.stats.LastRun = now
+	j.stats.LastRemoved = removed
+	if j.stats.TotalRemoved == nil {
+		j.stats.TotalRemoved = make(map[string]int64)
+	}
+	for kind, count := range removed {
+		j.stats.TotalRemoved[kind] += count
+	}
+	j.stats.Errors += failed
+	j.mx.Unlock()
+
+	if j.Optimise > 0 && now.Sub(j.lastOptimise) >= j.Optimise && ctx.Err() == nil {
+		j.optimise(now)
+	}
+	return removed
+}
+
+// Run VACUUM and ANALYZE and remember database size change.
+func (j *Janitor) optimise(now time.Time) {
+	j.lastOptimise = now
+	sizeBefore, sizeAfter, err := (*j.DB).Optimise()
+	if err != nil {
+		j.Log.Error(fmt.Sprintf("Can't optimise database - '%v'", err))
+		j.mx.Lock()
+		j.stats.Errors++
+		j.mx.Unlock()
+		return
+	}
+	j.Log.Info(fmt.Sprintf("Database optimised. Size '%v' bytes before, '%v' bytes after", sizeBefore, sizeAfter))
+
+	j.mx.Lock()
+	j.stats.LastOptimise = now
+	j.stats.SizeBefore = sizeBefore
+	j.stats.SizeAfter = sizeAfter
+	j.mx.Unlock()
+}
+
+// Return copy of cleanup statistics.
+func (j *Janitor) Stats() Stats {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	stats := j.stats
+	stats.LastRemoved = copyCounters(j.stats.LastRemoved)
+	stats.TotalRemoved = copyCounters(j.stats.TotalRemoved)
+	return stats
+}
+
+// Return HTTP handler with cleanup statistics in JSON.
+func (j *Janitor) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(j.Stats())
+		if err != nil {
+			j.Log.Error(fmt.Sprintf("Can't write statistics - '%v'", err))
+		}
+	}
+}
+
+func copyCounters(counters map[string]int64) map[string]int64 {
+	result := make(map[string]int64, len(counters))
+	for key, value := range counters {
+		result[key] = value
+	}
+	return result
+}